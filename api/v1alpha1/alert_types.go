@@ -0,0 +1,99 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//AlertEventInstalled the release installed by the helm operation
+	AlertEventInstalled = "Installed"
+	//AlertEventUpgraded the release upgraded by the change of the helm operation
+	AlertEventUpgraded = "Upgraded"
+	//AlertEventAutoUpdated the release upgraded to a new chart version in the repo
+	AlertEventAutoUpdated = "AutoUpdated"
+	//AlertEventRolledBack the release rolled back by the rollback annotation
+	AlertEventRolledBack = "RolledBack"
+	//AlertEventFailed the install, upgrade or the manual operation of the release failed
+	AlertEventFailed = "Failed"
+	//AlertEventNewVersion a chart version greater than the release found in the repo
+	AlertEventNewVersion = "NewVersion"
+	//AlertEventDigestChanged the chart version of the release re-pushed to the repo with another digest
+	AlertEventDigestChanged = "DigestChanged"
+)
+
+// AlertEventTypes all the event types of the alerts
+var AlertEventTypes = []string{
+	AlertEventInstalled, AlertEventUpgraded, AlertEventAutoUpdated,
+	AlertEventRolledBack, AlertEventFailed, AlertEventNewVersion, AlertEventDigestChanged,
+}
+
+// AlertSpec defines the desired state of Alert
+type AlertSpec struct {
+	//ProviderRef the notification provider in the namespace of the alert
+	ProviderRef LocalObjectReference `json:"providerRef"`
+	//Selector select the helm operations in the namespace of the alert by labels, all the helm operations if not set
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	//EventTypes the event types to post, one of Installed, Upgraded, AutoUpdated, RolledBack, Failed, NewVersion
+	// and DigestChanged, all the event types if not set
+	EventTypes []string `json:"eventTypes,omitempty"`
+	//Template the go template of the message, the fields of the event like {{ .Type }}, {{ .Namespace }}, {{ .Name }},
+	// {{ .ChartName }}, {{ .ChartVersion }}, {{ .Revision }} and {{ .Message }} can be used
+	Template string `json:"template,omitempty"`
+	//Suspend stop to post the messages of the alert
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Provider",type="string",JSONPath=".spec.providerRef.name"
+//+kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Alert is the Schema for the alerts API, it post the events of the selected helm operations to the provider
+type Alert struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AlertSpec `json:"spec,omitempty"`
+}
+
+// HasEventType check the alert post the event type
+func (r *Alert) HasEventType(eventType string) bool {
+	if len(r.Spec.EventTypes) == 0 {
+		return true
+	}
+	for _, item := range r.Spec.EventTypes {
+		if item == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+//+kubebuilder:object:root=true
+
+// AlertList contains a list of Alert
+type AlertList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Alert `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Alert{}, &AlertList{})
+}
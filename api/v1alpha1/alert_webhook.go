@@ -0,0 +1,87 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"text/template"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var alertlog = logf.Log.WithName("alert-resource")
+
+func (r *Alert) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-helmops-shijunlee-net-v1alpha1-alert,mutating=false,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=alerts,verbs=create;update,versions=v1alpha1,name=valert.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &Alert{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Alert) ValidateCreate() error {
+	alertlog.Info("validate create", "name", r.Name)
+	return r.commonValidate()
+}
+
+func (r *Alert) commonValidate() error {
+	if r.Spec.ProviderRef.Name == "" {
+		return errors.New("provider name can not empty")
+	}
+	if r.Spec.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.Selector); err != nil {
+			return errors.Wrap(err, "selector is invalid")
+		}
+	}
+	for _, eventType := range r.Spec.EventTypes {
+		var supported bool
+		for _, item := range AlertEventTypes {
+			if item == eventType {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return errors.Errorf("event type %s is not supported", eventType)
+		}
+	}
+	if r.Spec.Template != "" {
+		if _, err := template.New(r.Name).Parse(r.Spec.Template); err != nil {
+			return errors.Wrap(err, "template is invalid")
+		}
+	}
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Alert) ValidateUpdate(old runtime.Object) error {
+	alertlog.Info("validate update", "name", r.Name)
+	return r.commonValidate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Alert) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const appliedByWebhookPath = "/mutate-helmops-shijunlee-net-v1alpha1-appliedby"
+
+// triggerAnnotations the annotations which trigger an apply of the helm operation when changed
+var triggerAnnotations = []string{ForceUpgradeAnnotation, RollbackToRevisionAnnotation, ReinstallAnnotation}
+
+// SetupAppliedByWebhookWithManager register the webhook which record who last changed the helm operation
+func SetupAppliedByWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(appliedByWebhookPath, &webhook.Admission{Handler: &appliedByRecorder{}})
+}
+
+//+kubebuilder:webhook:path=/mutate-helmops-shijunlee-net-v1alpha1-appliedby,mutating=true,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=helmoperations,verbs=create;update,versions=v1alpha1,name=mappliedby.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// appliedByRecorder set the last applied by annotation when the spec or the trigger annotations changed
+type appliedByRecorder struct{}
+
+var _ admission.Handler = &appliedByRecorder{}
+
+// Handle record the request user when the helm operation will be applied, otherwise keep the old value
+func (a *appliedByRecorder) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	var changed = true
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		oldObj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldObj.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		oldAnnotations := oldObj.GetAnnotations()
+		changed = !reflect.DeepEqual(obj.Object["spec"], oldObj.Object["spec"])
+		for _, key := range triggerAnnotations {
+			if annotations[key] != oldAnnotations[key] {
+				changed = true
+			}
+		}
+		if !changed {
+			// the annotation can only be changed by the webhook
+			if value, ok := oldAnnotations[LastAppliedByAnnotation]; ok {
+				annotations[LastAppliedByAnnotation] = value
+			} else {
+				delete(annotations, LastAppliedByAnnotation)
+			}
+		}
+	}
+	if changed {
+		annotations[LastAppliedByAnnotation] = req.UserInfo.Username
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	marshaled, err := json.Marshal(obj.Object)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//MaxPublishedVersions the max count of the published versions kept in the status
+	MaxPublishedVersions = 20
+)
+
+// ChartPublisherSpec defines the desired state of ChartPublisher
+type ChartPublisherSpec struct {
+	//SourceRef the Git HelmRepo or ClusterHelmRepo which hold the chart source
+	SourceRef ChartRepoReference `json:"sourceRef"`
+	//Path the path of the chart dir in the git repo
+	Path string `json:"path"`
+	//TargetRef the ChartMuseum HelmRepo or ClusterHelmRepo which the packaged charts pushed to,
+	// the auth and tls of the repo are used to push the charts
+	TargetRef ChartRepoReference `json:"targetRef"`
+	//Lint the lint of the packaged chart, the chart is not pushed if the lint failed
+	Lint PublisherLint `json:"lint,omitempty"`
+	//Interval the interval to check the chart version in git, default is 1m
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	//Suspend stop to publish the charts
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// PublisherLint the lint options of the publisher
+type PublisherLint struct {
+	//Disabled skip the lint of the chart
+	Disabled bool `json:"disabled,omitempty"`
+	//Strict fail the lint on the warnings
+	Strict bool `json:"strict,omitempty"`
+}
+
+// PublishedChartVersion the chart version pushed by the publisher
+type PublishedChartVersion struct {
+	//Version the chart version
+	Version string `json:"version"`
+	//Digest the sha256 digest of the pushed chart archive
+	Digest string `json:"digest,omitempty"`
+	//PublishedTime the time the chart version pushed
+	PublishedTime metav1.Time `json:"publishedTime"`
+}
+
+// ChartPublisherStatus defines the observed state of ChartPublisher
+type ChartPublisherStatus struct {
+	//ObservedGeneration the generation of the spec last checked
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	//ChartName the name of the chart in the git path
+	ChartName string `json:"chartName,omitempty"`
+	//LastCheckedVersion the chart version in the git path at the last check
+	LastCheckedVersion string `json:"lastCheckedVersion,omitempty"`
+	//LastCheckTime the time of the last check
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	//PublishedVersions the chart versions pushed by the publisher, the latest first
+	PublishedVersions []PublishedChartVersion `json:"publishedVersions,omitempty"`
+	//Suspension who and when suspend the publisher
+	Suspension *Suspension `json:"suspension,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Chart",type="string",JSONPath=".status.chartName"
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.lastCheckedVersion"
+//+kubebuilder:printcolumn:name="Published",type="string",JSONPath=".status.publishedVersions[0].version"
+//+kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.targetRef.name"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ChartPublisher is the Schema for the chartpublishers API, it lint and package the chart in a git repo
+// and push the new chart versions to a chartMuseum repo
+type ChartPublisher struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ChartPublisherSpec   `json:"spec,omitempty"`
+	Status ChartPublisherStatus `json:"status,omitempty"`
+}
+
+// GetSourceRef get the resolved reference of the source repo
+func (r *ChartPublisher) GetSourceRef() ChartRepoReference {
+	return r.resolveRepoRef(r.Spec.SourceRef)
+}
+
+// GetTargetRef get the resolved reference of the target repo
+func (r *ChartPublisher) GetTargetRef() ChartRepoReference {
+	return r.resolveRepoRef(r.Spec.TargetRef)
+}
+
+// resolveRepoRef the HelmRepo is default to the namespace of the publisher
+func (r *ChartPublisher) resolveRepoRef(ref ChartRepoReference) ChartRepoReference {
+	if ref.Kind == "" {
+		ref.Kind = HelmRepoKind
+	}
+	if ref.Kind == HelmRepoKind && ref.Namespace == "" {
+		ref.Namespace = r.Namespace
+	}
+	return ref
+}
+
+// AddPublishedVersion record the pushed chart version, only the latest MaxPublishedVersions kept
+func (s *ChartPublisherStatus) AddPublishedVersion(version PublishedChartVersion) {
+	s.PublishedVersions = append([]PublishedChartVersion{version}, s.PublishedVersions...)
+	if len(s.PublishedVersions) > MaxPublishedVersions {
+		s.PublishedVersions = s.PublishedVersions[:MaxPublishedVersions]
+	}
+}
+
+//+kubebuilder:object:root=true
+
+// ChartPublisherList contains a list of ChartPublisher
+type ChartPublisherList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChartPublisher `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ChartPublisher{}, &ChartPublisherList{})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var chartpublisherlog = logf.Log.WithName("chartpublisher-resource")
+
+func (r *ChartPublisher) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-helmops-shijunlee-net-v1alpha1-chartpublisher,mutating=false,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=chartpublishers,verbs=create;update,versions=v1alpha1,name=vchartpublisher.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &ChartPublisher{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ChartPublisher) ValidateCreate() error {
+	chartpublisherlog.Info("validate create", "name", r.Name)
+	return r.commonValidate()
+}
+
+func (r *ChartPublisher) commonValidate() error {
+	if r.Spec.Path == "" || path.IsAbs(r.Spec.Path) || strings.HasPrefix(path.Clean(r.Spec.Path), "..") {
+		return errors.New("the chart path must be a relative path in the git repo")
+	}
+	if err := r.validateRepoRef("source", r.Spec.SourceRef); err != nil {
+		return err
+	}
+	if err := r.validateRepoRef("target", r.Spec.TargetRef); err != nil {
+		return err
+	}
+	if r.Spec.Interval != nil && r.Spec.Interval.Duration <= 0 {
+		return errors.New("the interval must be positive")
+	}
+	return nil
+}
+
+// validateRepoRef check the HelmRepo is in the namespace of the publisher,
+// the access of the ClusterHelmRepo and the repo types are checked by the controller
+func (r *ChartPublisher) validateRepoRef(name string, ref ChartRepoReference) error {
+	if ref.Name == "" {
+		return errors.Errorf("the %s repo name can not empty", name)
+	}
+	switch ref.Kind {
+	case "", HelmRepoKind:
+		if ref.Namespace != "" && ref.Namespace != r.Namespace {
+			return errors.Errorf("the %s HelmRepo must be in the namespace of the publisher, use a ClusterHelmRepo instead", name)
+		}
+	case ClusterHelmRepoKind:
+		if ref.Namespace != "" {
+			return errors.Errorf("the namespace can not set for the %s ClusterHelmRepo", name)
+		}
+	default:
+		return errors.Errorf("the %s repo kind %s not support", name, ref.Kind)
+	}
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ChartPublisher) ValidateUpdate(old runtime.Object) error {
+	chartpublisherlog.Info("validate update", "name", r.Name)
+	return r.commonValidate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ChartPublisher) ValidateDelete() error {
+	return nil
+}
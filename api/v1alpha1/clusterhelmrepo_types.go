@@ -0,0 +1,70 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//HelmRepoKind the kind of the namespaced helm repo
+	HelmRepoKind = "HelmRepo"
+	//ClusterHelmRepoKind the kind of the cluster scoped helm repo
+	ClusterHelmRepoKind = "ClusterHelmRepo"
+)
+
+// ClusterHelmRepoSpec defines the desired state of ClusterHelmRepo
+type ClusterHelmRepoSpec struct {
+	HelmRepoSpec `json:",inline"`
+
+	//AllowedNamespaces the selector of the namespaces which the helm operations can use the repo,
+	// the repo is visible to all the namespaces if not set
+	AllowedNamespaces *metav1.LabelSelector `json:"allowedNamespaces,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:path=clusterhelmrepos,scope=Cluster
+//+kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.repoURL"
+//+kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.repoType"
+//+kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend"
+//+kubebuilder:printcolumn:name="Breaker",type="string",JSONPath=".status.circuitBreaker.state",priority=1
+//+kubebuilder:printcolumn:name="Charts",type="integer",JSONPath=".status.catalog.chartCount"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterHelmRepo is the Schema for the clusterhelmrepos API,
+// the repo is shared by the helm operations in the allowed namespaces
+type ClusterHelmRepo struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterHelmRepoSpec `json:"spec,omitempty"`
+	Status HelmRepoStatus      `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterHelmRepoList contains a list of ClusterHelmRepo
+type ClusterHelmRepoList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterHelmRepo `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterHelmRepo{}, &ClusterHelmRepoList{})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var clusterhelmrepolog = logf.Log.WithName("clusterhelmrepo-resource")
+
+func (r *ClusterHelmRepo) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-helmops-shijunlee-net-v1alpha1-clusterhelmrepo,mutating=true,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=clusterhelmrepos,verbs=create;update,versions=v1alpha1,name=mclusterhelmrepo.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &ClusterHelmRepo{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ClusterHelmRepo) Default() {
+	clusterhelmrepolog.Info("default", "name", r.Name)
+	r.Spec.setDefaults()
+}
+
+//+kubebuilder:webhook:path=/validate-helmops-shijunlee-net-v1alpha1-clusterhelmrepo,mutating=false,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=clusterhelmrepos,verbs=create;update,versions=v1alpha1,name=vclusterhelmrepo.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &ClusterHelmRepo{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterHelmRepo) ValidateCreate() error {
+	clusterhelmrepolog.Info("validate create", "name", r.Name)
+	return r.commonValidate()
+}
+
+func (r *ClusterHelmRepo) commonValidate() error {
+	if r.Spec.AllowedNamespaces != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.AllowedNamespaces); err != nil {
+			return errors.Wrap(err, "allowed namespaces selector is invalid")
+		}
+	}
+	if s3 := r.Spec.S3; s3 != nil && s3.SecretRef != nil && s3.SecretRef.Namespace == "" {
+		return errors.New("the namespace of the s3 secret must be set for the cluster helm repo")
+	}
+	if auth := r.Spec.Auth; auth != nil && auth.SecretRef != nil && auth.SecretRef.Namespace == "" {
+		return errors.New("the namespace of the auth secret must be set for the cluster helm repo")
+	}
+	if ref := r.Spec.GetTLSSecretRef(); ref != nil && ref.Namespace == "" {
+		return errors.New("the namespace of the tls secret must be set for the cluster helm repo, use tlsSecretRef")
+	}
+	if c := r.Spec.ConfigMap; c != nil && c.Namespace == "" {
+		return errors.New("the namespace of the chart configmaps must be set for the cluster helm repo")
+	}
+	if e := r.Spec.Embedded; e != nil && e.Namespace == "" {
+		return errors.New("the namespace of the embedded chart configmap must be set for the cluster helm repo")
+	}
+	return r.Spec.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterHelmRepo) ValidateUpdate(old runtime.Object) error {
+	clusterhelmrepolog.Info("validate update", "name", r.Name)
+	return r.commonValidate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterHelmRepo) ValidateDelete() error {
+	return nil
+}
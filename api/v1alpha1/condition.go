@@ -0,0 +1,80 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConditionStatusTrue    = "True"
+	ConditionStatusFalse   = "False"
+	ConditionStatusUnknown = "Unknown"
+)
+
+const (
+	//ConditionTypeClusterReady the target cluster for the helm release is reachable
+	ConditionTypeClusterReady = "ClusterReady"
+	//ConditionTypeReleaseOwned the helm release is installed or adopted by the helm operation
+	ConditionTypeReleaseOwned = "ReleaseOwned"
+	//ConditionTypeReady the helm release is deployed at the chart version and values of the helm operation
+	ConditionTypeReady = "Ready"
+	//ConditionTypeHealthy the resources of the helm release are healthy, the reason is the health status
+	ConditionTypeHealthy = "Healthy"
+	//ConditionTypeDependenciesReady all the helm operations in spec.dependsOn are ready
+	ConditionTypeDependenciesReady = "DependenciesReady"
+	//ConditionTypeSuspended the reconciliation is suspended by spec.suspend
+	ConditionTypeSuspended = "Suspended"
+	//ConditionTypeManualOperation the result of the last manual operation triggered by the annotations
+	ConditionTypeManualOperation = "ManualOperation"
+	//ConditionTypeGenerated all the elements of the helm operation set generated and synced
+	ConditionTypeGenerated = "Generated"
+	//ConditionTypePublished the chart version in git is linted and pushed to the target repo
+	ConditionTypePublished = "Published"
+	//ConditionTypeCanary the result of the last canary upgrade, the reason is the canary phase
+	ConditionTypeCanary = "Canary"
+)
+
+// GetCondition find the condition with the condition type, return nil if not found
+func GetCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition add the condition or replace the condition with same type,
+// the last transition time only change when the condition status changed
+func SetCondition(conditions []Condition, condition Condition) []Condition {
+	now := metav1.Now()
+	existing := GetCondition(conditions, condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime == nil {
+			condition.LastTransitionTime = &now
+		}
+		return append(conditions, condition)
+	}
+	if existing.Status != condition.Status || existing.LastTransitionTime == nil {
+		existing.LastTransitionTime = &now
+	}
+	existing.Status = condition.Status
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	return conditions
+}
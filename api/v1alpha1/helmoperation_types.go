@@ -17,9 +17,36 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	//ReconcileAtAnnotation trigger a reconcile when the value changed, the value is usually a timestamp
+	ReconcileAtAnnotation = "helmops.shijunlee.net/reconcile-at"
+	//ForceUpgradeAnnotation upgrade the release with force once when the value changed
+	ForceUpgradeAnnotation = "helmops.shijunlee.net/force-upgrade"
+	//RollbackToRevisionAnnotation rollback the release to the revision in the value once, the value is
+	// <revision>@<requested time> so the same revision can be requested again
+	RollbackToRevisionAnnotation = "helmops.shijunlee.net/rollback-to-revision"
+	//ReinstallAnnotation uninstall and install the release once when the value changed
+	ReinstallAnnotation = "helmops.shijunlee.net/reinstall"
+	//LastAppliedByAnnotation the user who last changed the spec or triggered a one-shot operation,
+	// it is recorded by the webhook and copied to the controller revisions
+	LastAppliedByAnnotation = "helmops.shijunlee.net/last-applied-by"
+	//ServiceAccountUsernamePrefix the prefix of the user name of the service accounts
+	ServiceAccountUsernamePrefix = "system:serviceaccount:"
+	//CanaryAnalysisLabel the label on the canary analysis job for the release name
+	CanaryAnalysisLabel = "helmops.shijunlee.net/canary-analysis"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -36,10 +63,19 @@ type HelmOperationSpec struct {
 	//AutoUpdate is auto update for release
 	AutoUpdate bool `json:"autoUpdate,omitempty"`
 
-	//ChartRepoName the helmops repo name
+	//ChartRepoName the name of the HelmRepo in the namespace of the helm operation,
+	// use ChartRepoRef to reference a ClusterHelmRepo
 	ChartRepoName string `json:"chartRepoName,omitempty"`
+	//ChartRepoRef the reference of the HelmRepo or ClusterHelmRepo, take precedence over ChartRepoName
+	ChartRepoRef *ChartRepoReference `json:"chartRepoRef,omitempty"`
 	//ChartVersion the version for the chart will install
 	ChartVersion string `json:"chartVersion,omitempty"`
+	//ChartDigest the sha256 digest of the chart archive of the chart version, like `sha256:<hex>` or `<hex>`,
+	// the release is not installed or upgraded if the chart archive in the repo has another digest
+	ChartDigest string `json:"chartDigest,omitempty"`
+	//PinChartDigest record the digest of the chart archive when the chart version first installed or upgraded to,
+	// the same chart version with another digest is not deployed again
+	PinChartDigest bool `json:"pinChartDigest,omitempty"`
 	//ChartName the chart name which will install
 	ChartName string `json:"chartName,omitempty"`
 	// Create the chart create options
@@ -48,8 +84,72 @@ type HelmOperationSpec struct {
 	//Upgrade the chart upgrade options
 	Upgrade Upgrade `json:"upgrade,omitempty"`
 
+	//UpgradeStrategy the strategy to upgrade the release, the release is upgraded with the values at once if not set
+	UpgradeStrategy *UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+
 	//Uninstall the chart uninstall options
 	Uninstall Uninstall `json:"uninstall,omitempty"`
+
+	//KubeConfigSecretRef the secret which hold the kubeconfig for a remote cluster,
+	// if not set the release will install to the cluster which helmops running.
+	// The remote cluster is identified by the secret reference, use the same secret for the releases of a cluster
+	KubeConfigSecretRef *KubeConfigSecretRef `json:"kubeConfigSecretRef,omitempty"`
+
+	//ServiceAccountName the service account in the namespace of the helm operation, the helm actions impersonate it
+	// in the target cluster, so the release can only create the resources the service account allowed.
+	// if not set the helm actions run with the permissions of helmops
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	//ReleaseName the helm release name, default is the helm operation name
+	ReleaseName string `json:"releaseName,omitempty"`
+	//TargetNamespace the namespace which the helm release install, default is the helm operation namespace
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	//Adopt take over the release if it already exist and not installed by this helm operation
+	Adopt bool `json:"adopt,omitempty"`
+
+	//DependsOn the helm operations which must be ready before the release install or upgrade
+	DependsOn []DependencyReference `json:"dependsOn,omitempty"`
+
+	//Suspend stop the reconciliation of the helm operation, the release will not be changed until it unset
+	Suspend bool `json:"suspend,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	//RevisionHistoryLimit the number of the helm operation controller revisions to keep, default is 10
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+}
+
+//ChartRepoReference the reference of the chart repo which the chart install from
+type ChartRepoReference struct {
+	//+kubebuilder:validation:Enum=HelmRepo;ClusterHelmRepo
+	//Kind the kind of the chart repo, default is HelmRepo
+	Kind string `json:"kind,omitempty"`
+	//Name the name of the chart repo
+	Name string `json:"name"`
+	//Namespace the namespace of the HelmRepo, must be the namespace of the helm operation,
+	// it is empty for the ClusterHelmRepo
+	Namespace string `json:"namespace,omitempty"`
+}
+
+//DependencyReference the reference of a helm operation which this helm operation depends on
+type DependencyReference struct {
+	//Name the helm operation name
+	Name string `json:"name"`
+	//Namespace the helm operation namespace, default is the namespace of this helm operation
+	Namespace string `json:"namespace,omitempty"`
+	//ChartVersion the semver constraint for the chart version of the dependency release,
+	// default is the dependency release installed at the chart version of its spec
+	ChartVersion string `json:"chartVersion,omitempty"`
+}
+
+//KubeConfigSecretRef the reference of a secret which hold the kubeconfig for the target cluster,
+// the target cluster is identified by the namespace and the name of the secret, not the server in the kubeconfig.
+// The helm operations reference different secrets of the same cluster are different clusters, the release unique
+// check can not find the conflict releases of them, reference the same secret for the releases of a cluster
+type KubeConfigSecretRef struct {
+	//Name the secret name, the secret must in the same namespace with the helm operation
+	Name string `json:"name"`
+	//Key the key in the secret data for the kubeconfig, default is `kubeconfig`
+	Key string `json:"key,omitempty"`
 }
 
 type Upgrade struct {
@@ -106,6 +206,79 @@ type Upgrade struct {
 	UpgradeCRDs bool `json:"UpgradeCRDs,omitempty"`
 }
 
+//UpgradeStrategy the strategy to upgrade the release
+type UpgradeStrategy struct {
+	//Canary upgrade the release in steps, the new chart version is applied with the canary values first,
+	// and the values are applied only after the analysis of the canary step succeeded
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+}
+
+//CanaryStrategy the canary step of the upgrade
+type CanaryStrategy struct {
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//Values the values override merged over spec.values for the canary step,
+	// e.g. the partition of the StatefulSet or the replica count of the canary
+	Values CreateParam `json:"values,omitempty"`
+	//Pause the time to wait after the canary step applied before the analysis, default is 1m
+	Pause *metav1.Duration `json:"pause,omitempty"`
+	//Analysis the analysis of the canary step, the values are applied after the pause if not set
+	Analysis *CanaryAnalysis `json:"analysis,omitempty"`
+}
+
+//CanaryAnalysis the analysis of the canary step, one of prometheus or job must be set
+type CanaryAnalysis struct {
+	//Prometheus evaluate a prometheus query against the threshold
+	Prometheus *PrometheusAnalysis `json:"prometheus,omitempty"`
+	//Job run a job to completion in the release namespace, the analysis succeeded if the job succeeded
+	Job *JobAnalysis `json:"job,omitempty"`
+	//Timeout the time to wait for the analysis result, default is 10m
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+//PrometheusAnalysisOperator compare the query result with the threshold
+type PrometheusAnalysisOperator string
+
+const (
+	PrometheusAnalysisLessThan           PrometheusAnalysisOperator = "LessThan"
+	PrometheusAnalysisLessThanOrEqual    PrometheusAnalysisOperator = "LessThanOrEqual"
+	PrometheusAnalysisGreaterThan        PrometheusAnalysisOperator = "GreaterThan"
+	PrometheusAnalysisGreaterThanOrEqual PrometheusAnalysisOperator = "GreaterThanOrEqual"
+)
+
+//PrometheusAnalysis the analysis with a prometheus instant query
+type PrometheusAnalysis struct {
+	//Address the address of the prometheus server which helmops can reach, e.g. http://prometheus.monitoring:9090
+	Address string `json:"address"`
+	//Query the promql instant query, every sample of the result must satisfy the threshold,
+	// the analysis failed if the result is empty
+	Query string `json:"query"`
+	//+kubebuilder:validation:Enum=LessThan;LessThanOrEqual;GreaterThan;GreaterThanOrEqual
+	//Operator compare the sample value with the threshold, default is LessThan
+	Operator PrometheusAnalysisOperator `json:"operator,omitempty"`
+	//Threshold the float number which the sample values compared with
+	Threshold string `json:"threshold"`
+}
+
+//JobAnalysis the analysis with a job run to completion
+type JobAnalysis struct {
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//Spec the batch/v1 JobSpec of the job which created in the release namespace of the target cluster,
+	// the schema is not inlined to keep the crd small, it is validated by the webhook
+	Spec runtime.RawExtension `json:"spec"`
+}
+
+// GetJobSpec decode the job spec of the analysis
+func (j *JobAnalysis) GetJobSpec() (*batchv1.JobSpec, error) {
+	var spec = &batchv1.JobSpec{}
+	if len(j.Spec.Raw) == 0 {
+		return nil, errors.New("the job spec of the canary analysis is empty")
+	}
+	if err := json.Unmarshal(j.Spec.Raw, spec); err != nil {
+		return nil, errors.Wrap(err, "decode the job spec of the canary analysis error")
+	}
+	return spec, nil
+}
+
 //Create the helm chart create options
 type Create struct {
 	// Description install custom description
@@ -156,6 +329,86 @@ type HelmOperationStatus struct {
 	Conditions          []Condition  `json:"conditions,omitempty"`
 	CurrentChartVersion string       `json:"currentChartVersion,omitempty"`
 	ReleaseStatus       string       `json:"releaseStatus"`
+	// ReleaseName the release name which installed or adopted by this helm operation
+	ReleaseName string `json:"releaseName,omitempty"`
+	// ReleaseNamespace the release namespace which installed or adopted by this helm operation
+	ReleaseNamespace string `json:"releaseNamespace,omitempty"`
+	// Health the aggregated health of the release resources, one of Healthy, Progressing or Degraded
+	Health string `json:"health,omitempty"`
+	// Suspension who and when suspend the helm operation
+	Suspension *Suspension `json:"suspension,omitempty"`
+	// LastHandledReconcileAt the last handled value of the reconcile-at annotation
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+	// LastHandledForceUpgrade the last handled value of the force-upgrade annotation
+	LastHandledForceUpgrade string `json:"lastHandledForceUpgrade,omitempty"`
+	// LastHandledRollbackTo the last handled value of the rollback-to-revision annotation
+	LastHandledRollbackTo string `json:"lastHandledRollbackTo,omitempty"`
+	// LastHandledReinstall the last handled value of the reinstall annotation
+	LastHandledReinstall string `json:"lastHandledReinstall,omitempty"`
+	// ObservedGeneration the generation of the spec which the release applied
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Fingerprint the hash of the values, the chart and the options which the release applied,
+	// the release is upgraded only when the fingerprint changed or the release drifted
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// ReleaseRevision the revision of the release which the helm operation applied or observed,
+	// the release is drifted if the revision changed outside of the helm operation
+	ReleaseRevision int `json:"releaseRevision,omitempty"`
+	// Canary the state of the last canary upgrade
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// AutoUpdateChartVersion the newer chart version found in the repo which the release auto updated to,
+	// the release is upgraded by the reconcile of the helm operation with the upgrade strategy
+	AutoUpdateChartVersion string `json:"autoUpdateChartVersion,omitempty"`
+	// PinnedChartDigest the digest of the chart version recorded by spec.pinChartDigest
+	PinnedChartDigest *PinnedChartDigest `json:"pinnedChartDigest,omitempty"`
+	// RolledBackGeneration the generation of the helm operation when the release manually rolled back,
+	// the release is not upgraded or auto updated until the generation changed
+	RolledBackGeneration int64 `json:"rolledBackGeneration,omitempty"`
+	// LastAssessedTime the time the release and the health of the release resources last checked,
+	// the release is not checked again before the health check period
+	LastAssessedTime *metav1.Time `json:"lastAssessedTime,omitempty"`
+}
+
+//PinnedChartDigest the digest of the chart archive which the chart version applied with
+type PinnedChartDigest struct {
+	//ChartVersion the chart version
+	ChartVersion string `json:"chartVersion"`
+	//Digest the sha256 digest of the chart archive
+	Digest string `json:"digest"`
+	//PinnedTime the time the digest recorded
+	PinnedTime *metav1.Time `json:"pinnedTime,omitempty"`
+}
+
+const (
+	//CanaryPhasePaused the canary step applied, waiting for the pause to end
+	CanaryPhasePaused = "Paused"
+	//CanaryPhaseAnalyzing the analysis of the canary step is running
+	CanaryPhaseAnalyzing = "Analyzing"
+	//CanaryPhaseSucceeded the analysis succeeded and the values applied
+	CanaryPhaseSucceeded = "Succeeded"
+	//CanaryPhaseFailed the analysis failed and the release rolled back, the canary is not retried until the spec changed
+	CanaryPhaseFailed = "Failed"
+)
+
+//CanaryStatus the state of the canary upgrade
+type CanaryStatus struct {
+	//Phase the phase of the canary upgrade, one of Paused, Analyzing, Succeeded or Failed
+	Phase string `json:"phase"`
+	//Fingerprint the fingerprint of the desired release which the canary upgrade for
+	Fingerprint string `json:"fingerprint,omitempty"`
+	//ChartVersion the chart version of the canary step
+	ChartVersion string `json:"chartVersion,omitempty"`
+	//PreviousRevision the release revision before the canary step, the release rolled back to it if the analysis failed
+	PreviousRevision int `json:"previousRevision,omitempty"`
+	//CanaryRevision the release revision of the canary step
+	CanaryRevision int `json:"canaryRevision,omitempty"`
+	//StartTime the time the canary step applied
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	//AnalysisStartTime the time the analysis started
+	AnalysisStartTime *metav1.Time `json:"analysisStartTime,omitempty"`
+	//JobName the name of the analysis job
+	JobName string `json:"jobName,omitempty"`
+	//Message the result message of the analysis
+	Message string `json:"message,omitempty"`
 }
 
 type Condition struct {
@@ -171,7 +424,12 @@ type Condition struct {
 //+kubebuilder:printcolumn:name="ChartName",type="string",JSONPath=".spec.chartName"
 //+kubebuilder:printcolumn:name="ChartVersion",type="string",JSONPath=".spec.chartVersion"
 //+kubebuilder:printcolumn:name="RepoName",type="string",JSONPath=".spec.chartRepoName"
+//+kubebuilder:printcolumn:name="Release",type="string",JSONPath=".status.releaseName",priority=1
+//+kubebuilder:printcolumn:name="ReleaseNamespace",type="string",JSONPath=".status.releaseNamespace",priority=1
 //+kubebuilder:printcolumn:name="AutoUpdate",type="bool",JSONPath=".spec.autoUpdate"
+//+kubebuilder:printcolumn:name="Health",type="string",JSONPath=".status.health"
+//+kubebuilder:printcolumn:name="Canary",type="string",JSONPath=".status.canary.phase",priority=1
+//+kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // HelmOperation is the Schema for the helmoperations API
@@ -192,6 +450,91 @@ type HelmOperationList struct {
 	Items           []HelmOperation `json:"items"`
 }
 
+// GetReleaseName get the helm release name managed by the helm operation
+func (r *HelmOperation) GetReleaseName() string {
+	if r.Spec.ReleaseName != "" {
+		return r.Spec.ReleaseName
+	}
+	return r.Name
+}
+
+// GetReleaseNamespace get the namespace which the helm release installed
+func (r *HelmOperation) GetReleaseNamespace() string {
+	if r.Spec.TargetNamespace != "" {
+		return r.Spec.TargetNamespace
+	}
+	return r.Namespace
+}
+
+// GetServiceAccountUsername get the user name of the service account which the helm actions impersonate,
+// return empty if the service account not set
+func (r *HelmOperation) GetServiceAccountUsername() string {
+	if r.Spec.ServiceAccountName == "" {
+		return ""
+	}
+	return ServiceAccountUsernamePrefix + r.Namespace + ":" + r.Spec.ServiceAccountName
+}
+
+// IsReleaseOwned check the release is installed or adopted by the helm operation
+func (r *HelmOperation) IsReleaseOwned() bool {
+	if r.Status.ReleaseName == "" {
+		// the helm operation created before the release owner recorded
+		return r.Status.CurrentChartVersion != ""
+	}
+	return r.Status.ReleaseName == r.GetReleaseName() && r.Status.ReleaseNamespace == r.GetReleaseNamespace()
+}
+
+// GetDependencyKey get the namespaced name of the helm operation which the dependency reference
+func (r *HelmOperation) GetDependencyKey(dependency DependencyReference) types.NamespacedName {
+	var namespace = dependency.Namespace
+	if namespace == "" {
+		namespace = r.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: dependency.Name}
+}
+
+// GetChartRepoRef get the resolved reference of the chart repo,
+// the ChartRepoName reference the HelmRepo in the namespace of the helm operation
+func (r *HelmOperation) GetChartRepoRef() ChartRepoReference {
+	if r.Spec.ChartRepoRef == nil {
+		return ChartRepoReference{Kind: HelmRepoKind, Namespace: r.Namespace, Name: r.Spec.ChartRepoName}
+	}
+	ref := *r.Spec.ChartRepoRef
+	if ref.Kind == "" {
+		ref.Kind = HelmRepoKind
+	}
+	if ref.Kind == HelmRepoKind && ref.Namespace == "" {
+		ref.Namespace = r.Namespace
+	}
+	return ref
+}
+
+// targetClusterKey the key of the cluster which the release installed, empty for the local cluster.
+// The key is the kubeconfig secret reference, the secrets are not read by the webhook, so two secrets with
+// the kubeconfig of the same server are different keys, and the server changed in the secret keep the key
+func (r *HelmOperation) targetClusterKey() string {
+	if r.Spec.KubeConfigSecretRef == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", r.Namespace, r.Spec.KubeConfigSecretRef.Name)
+}
+
+// RollbackToRevisionValue the value of the rollback-to-revision annotation which request the rollback at the time
+func RollbackToRevisionValue(revision int, requestedAt time.Time) string {
+	return fmt.Sprintf("%d@%s", revision, requestedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// ParseRollbackToRevision get the revision from the value of the rollback-to-revision annotation,
+// the value without the requested time is the revision
+func ParseRollbackToRevision(value string) (int, error) {
+	revisionText := strings.SplitN(value, "@", 2)[0]
+	revision, err := strconv.Atoi(revisionText)
+	if err != nil || revision <= 0 {
+		return 0, errors.Errorf("revision %s is not a positive integer", revisionText)
+	}
+	return revision, nil
+}
+
 func init() {
 	SchemeBuilder.Register(&HelmOperation{}, &HelmOperationList{})
 }
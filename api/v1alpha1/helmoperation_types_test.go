@@ -0,0 +1,65 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseRollbackToRevision(t *testing.T) {
+	requestedAt := time.Date(2021, 6, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "revision with requested time", value: "3@2021-06-01T08:30:00Z", want: 3},
+		{name: "value of RollbackToRevisionValue", value: RollbackToRevisionValue(12, requestedAt), want: 12},
+		{name: "bare revision", value: "5", want: 5},
+		{name: "zero revision", value: "0", wantErr: true},
+		{name: "zero revision with requested time", value: "0@2021-06-01T08:30:00Z", wantErr: true},
+		{name: "negative revision", value: "-1", wantErr: true},
+		{name: "not a number", value: "latest", wantErr: true},
+		{name: "empty revision", value: "@2021-06-01T08:30:00Z", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRollbackToRevision(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRollbackToRevision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRollbackToRevision() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_RollbackToRevisionValue(t *testing.T) {
+	// the same revision requested again is a new value, the rollback runs again
+	first := RollbackToRevisionValue(2, time.Date(2021, 6, 1, 8, 30, 0, 0, time.UTC))
+	second := RollbackToRevisionValue(2, time.Date(2021, 6, 1, 9, 0, 0, 0, time.FixedZone("CST", 8*3600)))
+	if first == second {
+		t.Errorf("expect different values for the requests at different time, got %s", first)
+	}
+	if first != "2@2021-06-01T08:30:00Z" {
+		t.Errorf("expect the requested time in utc, got %s", first)
+	}
+}
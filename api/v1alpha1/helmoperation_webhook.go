@@ -17,9 +17,22 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chartutil"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -27,7 +40,19 @@ import (
 // log is for logging in this package.
 var helmoperationlog = logf.Log.WithName("helmoperation-resource")
 
+// chartDigestPattern the sha256 digest with or without the algorithm prefix
+var chartDigestPattern = regexp.MustCompile(`^(sha256:)?[0-9a-fA-F]{64}$`)
+
+// helmOperationClient the client to find other helm operations for validation
+var helmOperationClient client.Client
+
+const (
+	//DefaultKubeConfigSecretKey the default key in the secret data for the kubeconfig
+	DefaultKubeConfigSecretKey = "kubeconfig"
+)
+
 func (r *HelmOperation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	helmOperationClient = mgr.GetClient()
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -42,8 +67,12 @@ var _ webhook.Defaulter = &HelmOperation{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *HelmOperation) Default() {
 	helmoperationlog.Info("default", "name", r.Name)
-
-	// TODO(user): fill in your defaulting logic.
+	if r.Spec.KubeConfigSecretRef != nil && r.Spec.KubeConfigSecretRef.Key == "" {
+		r.Spec.KubeConfigSecretRef.Key = DefaultKubeConfigSecretKey
+	}
+	if r.Spec.ChartRepoRef != nil && r.Spec.ChartRepoRef.Kind == "" {
+		r.Spec.ChartRepoRef.Kind = HelmRepoKind
+	}
 }
 
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
@@ -57,7 +86,221 @@ func (r *HelmOperation) ValidateCreate() error {
 	if r.Spec.ChartVersion == "" || r.Spec.ChartName == "" {
 		return errors.New("chart name or chart version can not empty")
 	}
-	// TODO(user): fill in your validation logic upon object creation.
+	if err := r.commonValidate(); err != nil {
+		return err
+	}
+	if err := r.validateDependencyCycle(); err != nil {
+		return err
+	}
+	return r.validateReleaseUnique()
+}
+
+// commonValidate check the spec itself, the checks listing the other helm operations are not included
+func (r *HelmOperation) commonValidate() error {
+	if r.Spec.KubeConfigSecretRef != nil && r.Spec.KubeConfigSecretRef.Name == "" {
+		return errors.New("kubeconfig secret name can not empty")
+	}
+	if err := r.validateServiceAccountName(); err != nil {
+		return err
+	}
+	if err := r.validateChartRepoRef(); err != nil {
+		return err
+	}
+	if err := chartutil.ValidateReleaseName(r.GetReleaseName()); err != nil {
+		return errors.Wrapf(err, "release name %s is invalid", r.GetReleaseName())
+	}
+	if err := r.validateDependencies(); err != nil {
+		return err
+	}
+	if err := r.validateUpgradeStrategy(); err != nil {
+		return err
+	}
+	return r.validateChartDigest()
+}
+
+// validateServiceAccountName check the service account is a name in the namespace of the helm operation,
+// the user name of a service account like `system:serviceaccount:<namespace>:<name>` is not allowed
+func (r *HelmOperation) validateServiceAccountName() error {
+	name := r.Spec.ServiceAccountName
+	if name == "" {
+		return nil
+	}
+	if strings.HasPrefix(name, ServiceAccountUsernamePrefix) {
+		if !strings.HasPrefix(name, ServiceAccountUsernamePrefix+r.Namespace+":") {
+			return errors.Errorf("service account %s is not in the namespace %s of the helm operation", name, r.Namespace)
+		}
+		return errors.Errorf("service account %s must be the name of the service account, not the user name", name)
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return errors.Errorf("service account name %s is invalid: %s", name, strings.Join(errs, ","))
+	}
+	return nil
+}
+
+// validateChartRepoRef check the HelmRepo is in the namespace of the helm operation,
+// the access of the ClusterHelmRepo is checked by the controller with the allowed namespaces
+func (r *HelmOperation) validateChartRepoRef() error {
+	ref := r.Spec.ChartRepoRef
+	if ref == nil {
+		return nil
+	}
+	if ref.Name == "" {
+		return errors.New("chart repo name can not empty")
+	}
+	if r.Spec.ChartRepoName != "" && r.Spec.ChartRepoName != ref.Name {
+		return errors.New("chart repo name and chart repo ref can not reference different repos")
+	}
+	switch ref.Kind {
+	case "", HelmRepoKind:
+		if ref.Namespace != "" && ref.Namespace != r.Namespace {
+			return errors.New("the HelmRepo is only visible to the helm operations in its namespace, use a ClusterHelmRepo instead")
+		}
+	case ClusterHelmRepoKind:
+		if ref.Namespace != "" {
+			return errors.New("the namespace can not set for a ClusterHelmRepo")
+		}
+	default:
+		return errors.Errorf("chart repo kind %s not support", ref.Kind)
+	}
+	return nil
+}
+
+// validateDependencies check the dependency references
+func (r *HelmOperation) validateDependencies() error {
+	var self = types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+	for _, dependency := range r.Spec.DependsOn {
+		if dependency.Name == "" {
+			return errors.New("dependency name can not empty")
+		}
+		if r.GetDependencyKey(dependency) == self {
+			return errors.New("helm operation can not depend on itself")
+		}
+		if dependency.ChartVersion != "" {
+			if _, err := semver.NewConstraint(dependency.ChartVersion); err != nil {
+				return errors.Wrapf(err, "dependency %s chart version constraint is invalid", dependency.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDependencyCycle check there is no dependency cycle with the existing helm operations
+func (r *HelmOperation) validateDependencyCycle() error {
+	if helmOperationClient == nil || len(r.Spec.DependsOn) == 0 {
+		return nil
+	}
+	var self = types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+	var visited = map[types.NamespacedName]bool{}
+	var path []string
+	var visit func(operation *HelmOperation) error
+	visit = func(operation *HelmOperation) error {
+		for _, dependency := range operation.Spec.DependsOn {
+			key := operation.GetDependencyKey(dependency)
+			if key == self {
+				return errors.Errorf("dependency cycle found: %s -> %s", strings.Join(path, " -> "), key.String())
+			}
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			var item = &HelmOperation{}
+			if err := helmOperationClient.Get(context.Background(), key, item); err != nil {
+				if k8serrors.IsNotFound(err) {
+					// the dependency may be created later
+					continue
+				}
+				return errors.Wrapf(err, "get dependency helm operation %s error", key.String())
+			}
+			path = append(path, key.String())
+			if err := visit(item); err != nil {
+				return err
+			}
+			path = path[:len(path)-1]
+		}
+		return nil
+	}
+	path = append(path, self.String())
+	return visit(r)
+}
+
+// validateUpgradeStrategy check the canary analysis has exactly one of prometheus or job
+func (r *HelmOperation) validateUpgradeStrategy() error {
+	if r.Spec.UpgradeStrategy == nil || r.Spec.UpgradeStrategy.Canary == nil {
+		return nil
+	}
+	canary := r.Spec.UpgradeStrategy.Canary
+	if canary.Pause != nil && canary.Pause.Duration < 0 {
+		return errors.New("canary pause can not be negative")
+	}
+	analysis := canary.Analysis
+	if analysis == nil {
+		return nil
+	}
+	if analysis.Timeout != nil && analysis.Timeout.Duration <= 0 {
+		return errors.New("canary analysis timeout must be positive")
+	}
+	switch {
+	case analysis.Prometheus != nil && analysis.Job != nil:
+		return errors.New("only one of prometheus or job can be set in the canary analysis")
+	case analysis.Prometheus != nil:
+		prometheus := analysis.Prometheus
+		if _, err := url.ParseRequestURI(prometheus.Address); err != nil {
+			return errors.Wrap(err, "canary prometheus address is invalid")
+		}
+		if prometheus.Query == "" {
+			return errors.New("canary prometheus query can not empty")
+		}
+		if _, err := strconv.ParseFloat(prometheus.Threshold, 64); err != nil {
+			return errors.Wrapf(err, "canary prometheus threshold %s is not a number", prometheus.Threshold)
+		}
+	case analysis.Job != nil:
+		jobSpec, err := analysis.Job.GetJobSpec()
+		if err != nil {
+			return err
+		}
+		if len(jobSpec.Template.Spec.Containers) == 0 {
+			return errors.New("canary analysis job must have containers")
+		}
+	default:
+		return errors.New("one of prometheus or job must be set in the canary analysis")
+	}
+	return nil
+}
+
+// validateChartDigest check the digest is a sha256 digest, the digest can not pin the versions auto updated
+func (r *HelmOperation) validateChartDigest() error {
+	if r.Spec.ChartDigest == "" {
+		return nil
+	}
+	if !chartDigestPattern.MatchString(r.Spec.ChartDigest) {
+		return errors.Errorf("chart digest %s is not a sha256 digest like sha256:<hex>", r.Spec.ChartDigest)
+	}
+	if r.Spec.AutoUpdate {
+		return errors.New("chart digest can not be set with auto update")
+	}
+	return nil
+}
+
+// validateReleaseUnique check there is no other helm operation manage the same release
+func (r *HelmOperation) validateReleaseUnique() error {
+	if helmOperationClient == nil {
+		return nil
+	}
+	var operationList = &HelmOperationList{}
+	if err := helmOperationClient.List(context.Background(), operationList); err != nil {
+		return errors.Wrap(err, "list helm operations error")
+	}
+	for _, item := range operationList.Items {
+		if item.Namespace == r.Namespace && item.Name == r.Name {
+			continue
+		}
+		if item.targetClusterKey() == r.targetClusterKey() &&
+			item.GetReleaseNamespace() == r.GetReleaseNamespace() &&
+			item.GetReleaseName() == r.GetReleaseName() {
+			return errors.Errorf("release %s/%s is already managed by helm operation %s/%s",
+				r.GetReleaseNamespace(), r.GetReleaseName(), item.Namespace, item.Name)
+		}
+	}
 	return nil
 }
 
@@ -68,11 +311,26 @@ func (r *HelmOperation) ValidateUpdate(old runtime.Object) error {
 	if !ok {
 		return nil
 	}
-	if r.Spec.ChartName != oldOperation.Spec.ChartName || r.Spec.ChartRepoName != oldOperation.Spec.ChartRepoName {
+	// the finalizer removed and the status updated on deletion, the spec is not used any more
+	if r.DeletionTimestamp != nil {
+		return nil
+	}
+	if r.Spec.ChartName != oldOperation.Spec.ChartName || r.GetChartRepoRef() != oldOperation.GetChartRepoRef() {
 		return errors.New("chart name or chart repo can not change for update")
 	}
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+	if r.GetReleaseName() != oldOperation.GetReleaseName() || r.GetReleaseNamespace() != oldOperation.GetReleaseNamespace() ||
+		r.targetClusterKey() != oldOperation.targetClusterKey() {
+		return errors.New("release name, target namespace or target cluster can not change for update")
+	}
+	if err := r.commonValidate(); err != nil {
+		return err
+	}
+	// the release identity can not change, the release unique is checked on create, only the changed
+	// dependencies may introduce a cycle
+	if reflect.DeepEqual(r.Spec.DependsOn, oldOperation.Spec.DependsOn) {
+		return nil
+	}
+	return r.validateDependencyCycle()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
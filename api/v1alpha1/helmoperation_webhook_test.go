@@ -0,0 +1,317 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// useFakeHelmOperationClient set the client of the webhook to a fake client with the helm operations
+func useFakeHelmOperationClient(t *testing.T, operations ...*HelmOperation) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	var objects []client.Object
+	for _, item := range operations {
+		objects = append(objects, item)
+	}
+	old := helmOperationClient
+	helmOperationClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	t.Cleanup(func() {
+		helmOperationClient = old
+	})
+}
+
+func newTestHelmOperation(namespace, name string, modify func(spec *HelmOperationSpec)) *HelmOperation {
+	operation := &HelmOperation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       HelmOperationSpec{ChartName: "nginx", ChartVersion: "1.0.0"},
+	}
+	if modify != nil {
+		modify(&operation.Spec)
+	}
+	return operation
+}
+
+func dependsOn(names ...string) func(spec *HelmOperationSpec) {
+	return func(spec *HelmOperationSpec) {
+		for _, name := range names {
+			spec.DependsOn = append(spec.DependsOn, DependencyReference{Name: name})
+		}
+	}
+}
+
+func TestHelmOperation_validateReleaseUnique(t *testing.T) {
+	useFakeHelmOperationClient(t,
+		newTestHelmOperation("apps", "web", nil),
+		newTestHelmOperation("apps", "api", func(spec *HelmOperationSpec) {
+			spec.ReleaseName = "backend"
+			spec.TargetNamespace = "backend"
+		}),
+		newTestHelmOperation("apps", "remote-web", func(spec *HelmOperationSpec) {
+			spec.ReleaseName = "web"
+			spec.KubeConfigSecretRef = &KubeConfigSecretRef{Name: "remote"}
+		}),
+	)
+	tests := []struct {
+		name      string
+		operation *HelmOperation
+		wantErr   bool
+	}{
+		{
+			name:      "update the helm operation itself",
+			operation: newTestHelmOperation("apps", "web", nil),
+		},
+		{
+			name: "same cluster namespace and name",
+			operation: newTestHelmOperation("apps", "web-copy", func(spec *HelmOperationSpec) {
+				spec.ReleaseName = "web"
+			}),
+			wantErr: true,
+		},
+		{
+			name: "same release from another namespace",
+			operation: newTestHelmOperation("tools", "backend", func(spec *HelmOperationSpec) {
+				spec.TargetNamespace = "backend"
+			}),
+			wantErr: true,
+		},
+		{
+			name: "same remote cluster",
+			operation: newTestHelmOperation("apps", "remote-web-copy", func(spec *HelmOperationSpec) {
+				spec.ReleaseName = "web"
+				spec.KubeConfigSecretRef = &KubeConfigSecretRef{Name: "remote"}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "same name in another namespace",
+			operation: newTestHelmOperation("apps", "web-copy", func(spec *HelmOperationSpec) {
+				spec.ReleaseName = "web"
+				spec.TargetNamespace = "web"
+			}),
+		},
+		{
+			name: "same name in another cluster",
+			operation: newTestHelmOperation("apps", "other-web", func(spec *HelmOperationSpec) {
+				spec.ReleaseName = "web"
+				spec.KubeConfigSecretRef = &KubeConfigSecretRef{Name: "other"}
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.operation.validateReleaseUnique(); (err != nil) != tt.wantErr {
+				t.Errorf("validateReleaseUnique() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHelmOperation_validateDependencies(t *testing.T) {
+	// the existing helm operations: a -> b -> c, d -> e -> a, database and cache have no dependency
+	useFakeHelmOperationClient(t,
+		newTestHelmOperation("apps", "a", dependsOn("b")),
+		newTestHelmOperation("apps", "b", dependsOn("c")),
+		newTestHelmOperation("apps", "c", nil),
+		newTestHelmOperation("apps", "d", dependsOn("e")),
+		newTestHelmOperation("apps", "e", dependsOn("a")),
+		newTestHelmOperation("apps", "database", nil),
+		newTestHelmOperation("apps", "cache", dependsOn("database")),
+	)
+	tests := []struct {
+		name      string
+		operation *HelmOperation
+		wantErr   bool
+	}{
+		{
+			name:      "self dependency",
+			operation: newTestHelmOperation("apps", "web", dependsOn("web")),
+			wantErr:   true,
+		},
+		{
+			name: "self dependency with namespace",
+			operation: newTestHelmOperation("apps", "web", func(spec *HelmOperationSpec) {
+				spec.DependsOn = []DependencyReference{{Name: "web", Namespace: "apps"}}
+			}),
+			wantErr: true,
+		},
+		{
+			name:      "two helm operations cycle",
+			operation: newTestHelmOperation("apps", "c", dependsOn("b")),
+			wantErr:   true,
+		},
+		{
+			name:      "longer cycle",
+			operation: newTestHelmOperation("apps", "c", dependsOn("d")),
+			wantErr:   true,
+		},
+		{
+			name:      "valid dag",
+			operation: newTestHelmOperation("apps", "web", dependsOn("a", "cache", "database")),
+		},
+		{
+			name:      "dependency not created yet",
+			operation: newTestHelmOperation("apps", "web", dependsOn("queue")),
+		},
+		{
+			name: "dependency in another namespace",
+			operation: newTestHelmOperation("apps", "web", func(spec *HelmOperationSpec) {
+				spec.DependsOn = []DependencyReference{{Name: "web", Namespace: "tools"}}
+			}),
+		},
+		{
+			name: "invalid chart version constraint",
+			operation: newTestHelmOperation("apps", "web", func(spec *HelmOperationSpec) {
+				spec.DependsOn = []DependencyReference{{Name: "database", ChartVersion: "not a version"}}
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.operation.validateDependencies()
+			if err == nil {
+				err = tt.operation.validateDependencyCycle()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// unavailableClient fail all the requests, the validation using it is not expected to list the helm operations
+type unavailableClient struct {
+	client.Client
+}
+
+func (unavailableClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return errors.New("the helm operations are not expected to be read")
+}
+
+func (unavailableClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return errors.New("the helm operations are not expected to be listed")
+}
+
+func TestHelmOperation_ValidateUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		old       *HelmOperation
+		operation func(old *HelmOperation) *HelmOperation
+		existing  []*HelmOperation
+		wantErr   bool
+	}{
+		{
+			name: "finalizer added",
+			old:  newTestHelmOperation("apps", "web", dependsOn("database")),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				operation.Finalizers = append(operation.Finalizers, "finalizer.helmoperation.helmops.shijunlee.net")
+				return operation
+			},
+		},
+		{
+			name: "chart version updated",
+			old:  newTestHelmOperation("apps", "web", dependsOn("database")),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				operation.Spec.ChartVersion = "1.1.0"
+				return operation
+			},
+		},
+		{
+			name: "finalizer removed on deletion",
+			old: newTestHelmOperation("apps", "web", func(spec *HelmOperationSpec) {
+				// the spec created before the validation added
+				spec.ServiceAccountName = ServiceAccountUsernamePrefix + "apps:deployer"
+			}),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				now := metav1.Now()
+				operation.DeletionTimestamp = &now
+				operation.Finalizers = nil
+				return operation
+			},
+		},
+		{
+			name: "invalid spec",
+			old:  newTestHelmOperation("apps", "web", nil),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				operation.Spec.ServiceAccountName = ServiceAccountUsernamePrefix + "apps:deployer"
+				return operation
+			},
+			wantErr: true,
+		},
+		{
+			name: "release name changed",
+			old:  newTestHelmOperation("apps", "web", nil),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				operation.Spec.ReleaseName = "frontend"
+				return operation
+			},
+			wantErr: true,
+		},
+		{
+			name: "dependency cycle added",
+			old:  newTestHelmOperation("apps", "web", nil),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				operation.Spec.DependsOn = []DependencyReference{{Name: "api"}}
+				return operation
+			},
+			existing: []*HelmOperation{newTestHelmOperation("apps", "api", dependsOn("web"))},
+			wantErr:  true,
+		},
+		{
+			name: "dependency added",
+			old:  newTestHelmOperation("apps", "web", nil),
+			operation: func(old *HelmOperation) *HelmOperation {
+				operation := old.DeepCopy()
+				operation.Spec.DependsOn = []DependencyReference{{Name: "api"}}
+				return operation
+			},
+			existing: []*HelmOperation{newTestHelmOperation("apps", "api", nil)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.existing != nil {
+				useFakeHelmOperationClient(t, tt.existing...)
+			} else {
+				old := helmOperationClient
+				helmOperationClient = unavailableClient{}
+				t.Cleanup(func() {
+					helmOperationClient = old
+				})
+			}
+			if err := tt.operation(tt.old).ValidateUpdate(tt.old); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
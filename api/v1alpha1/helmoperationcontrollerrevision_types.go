@@ -0,0 +1,72 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//DefaultRevisionHistoryLimit the default number of the controller revisions to keep for a helm operation
+	DefaultRevisionHistoryLimit = 10
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=hocr
+//+kubebuilder:printcolumn:name="Operation",type="string",JSONPath=".metadata.ownerReferences[0].name"
+//+kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".revision"
+//+kubebuilder:printcolumn:name="Action",type="string",JSONPath=".action"
+//+kubebuilder:printcolumn:name="ChartVersion",type="string",JSONPath=".spec.chartVersion"
+//+kubebuilder:printcolumn:name="ReleaseRevision",type="integer",JSONPath=".releaseRevision"
+//+kubebuilder:printcolumn:name="TriggeredBy",type="string",JSONPath=".triggeredBy"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HelmOperationControllerRevision is the immutable record of a successful apply of a helm operation,
+// it is created by the controller and owned by the helm operation
+type HelmOperationControllerRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	//Revision the revision number, increase for every successful apply of the helm operation
+	Revision int64 `json:"revision"`
+	//Action the helm action of the apply, like install, upgrade or rollback
+	Action string `json:"action,omitempty"`
+	//Spec the resolved spec of the helm operation when applied
+	Spec HelmOperationSpec `json:"spec"`
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//Values the merged values of the chart default values and the release values
+	Values CreateParam `json:"values,omitempty"`
+	//ChartDigest the sha256 digest of the chart files
+	ChartDigest string `json:"chartDigest,omitempty"`
+	//ReleaseRevision the helm release revision number
+	ReleaseRevision int `json:"releaseRevision,omitempty"`
+	//TriggeredBy who triggered the apply, a user name or the helm repo for auto update
+	TriggeredBy string `json:"triggeredBy,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HelmOperationControllerRevisionList contains a list of HelmOperationControllerRevision
+type HelmOperationControllerRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HelmOperationControllerRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HelmOperationControllerRevision{}, &HelmOperationControllerRevisionList{})
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var helmoperationcontrollerrevisionlog = logf.Log.WithName("helmoperationcontrollerrevision-resource")
+
+func (r *HelmOperationControllerRevision) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-helmops-shijunlee-net-v1alpha1-helmoperationcontrollerrevision,mutating=false,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=helmoperationcontrollerrevisions,verbs=update,versions=v1alpha1,name=vhelmoperationcontrollerrevision.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &HelmOperationControllerRevision{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *HelmOperationControllerRevision) ValidateCreate() error {
+	return nil
+}
+
+// ValidateUpdate the revision record is immutable, only the metadata can be changed
+func (r *HelmOperationControllerRevision) ValidateUpdate(old runtime.Object) error {
+	helmoperationcontrollerrevisionlog.Info("validate update", "name", r.Name)
+	oldRevision, ok := old.(*HelmOperationControllerRevision)
+	if !ok {
+		return nil
+	}
+	if r.Revision != oldRevision.Revision || r.Action != oldRevision.Action ||
+		r.ChartDigest != oldRevision.ChartDigest || r.ReleaseRevision != oldRevision.ReleaseRevision ||
+		r.TriggeredBy != oldRevision.TriggeredBy ||
+		!reflect.DeepEqual(r.Spec, oldRevision.Spec) ||
+		!reflect.DeepEqual(r.Values.Object, oldRevision.Values.Object) {
+		return errors.New("helm operation controller revision is immutable")
+	}
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *HelmOperationControllerRevision) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//HelmOperationSetLabel the label on the helm operation for the name of the helm operation set which created it
+	HelmOperationSetLabel = "helmops.shijunlee.net/helmoperationset"
+	//HelmOperationSetElementLabel the label on the helm operation for the generator element name
+	HelmOperationSetElementLabel = "helmops.shijunlee.net/element"
+)
+
+// HelmOperationSetSpec defines the desired state of HelmOperationSet
+type HelmOperationSetSpec struct {
+	//Template the template for the helm operations created by the set
+	Template HelmOperationTemplate `json:"template"`
+
+	//Generators generate the elements, each element create one helm operation
+	Generators []HelmOperationSetGenerator `json:"generators"`
+}
+
+//HelmOperationTemplate the template of the helm operation
+type HelmOperationTemplate struct {
+	//Labels the labels add to the helm operations
+	Labels map[string]string `json:"labels,omitempty"`
+	//Annotations the annotations add to the helm operations
+	Annotations map[string]string `json:"annotations,omitempty"`
+	//Spec the helm operation spec, the values of the element will merge to the spec values
+	Spec HelmOperationSpec `json:"spec"`
+}
+
+//HelmOperationSetGenerator only one of the generator type can be set
+type HelmOperationSetGenerator struct {
+	//List generate the elements from the list
+	List *ListGenerator `json:"list,omitempty"`
+	//NamespaceSelector generate one element for each namespace match the selector
+	NamespaceSelector *NamespaceSelectorGenerator `json:"namespaceSelector,omitempty"`
+	//ConfigMap generate the elements from a config map in the namespace of the set
+	ConfigMap *ConfigMapGenerator `json:"configMap,omitempty"`
+}
+
+//ListGenerator the elements config in the helm operation set
+type ListGenerator struct {
+	Elements []GeneratorElement `json:"elements"`
+}
+
+//NamespaceSelectorGenerator the element name and target namespace is the namespace name
+type NamespaceSelectorGenerator struct {
+	//Selector the label selector for the namespaces
+	Selector metav1.LabelSelector `json:"selector"`
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//Values the values for all elements from the namespaces
+	Values CreateParam `json:"values,omitempty"`
+}
+
+//ConfigMapGenerator each key of the config map data is a element name,
+// the value is a yaml with the namespace and values of the element
+type ConfigMapGenerator struct {
+	//Name the config map name
+	Name string `json:"name"`
+}
+
+//GeneratorElement the element to generate a helm operation
+type GeneratorElement struct {
+	//Name the element name, the helm operation name is `{set name}-{element name}`
+	Name string `json:"name"`
+	//Namespace the target namespace for the release, default is the template target namespace
+	Namespace string `json:"namespace,omitempty"`
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//Values the values merged over the template values
+	Values CreateParam `json:"values,omitempty"`
+}
+
+// HelmOperationSetStatus defines the observed state of HelmOperationSet
+type HelmOperationSetStatus struct {
+	LastUpdateTime *metav1.Time `json:"updateTime,omitempty"`
+	Conditions     []Condition  `json:"conditions,omitempty"`
+	//Total the count of the helm operations created by the set
+	Total int32 `json:"total"`
+	//Ready the count of the helm operations which release is deployed
+	Ready int32 `json:"ready"`
+	//Failed the count of the helm operations which release is failed
+	Failed int32 `json:"failed"`
+	//Upgrading the count of the helm operations which release is installing, upgrading or rolling back
+	Upgrading int32 `json:"upgrading"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="ChartName",type="string",JSONPath=".spec.template.spec.chartName"
+//+kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.total"
+//+kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.ready"
+//+kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+//+kubebuilder:printcolumn:name="Upgrading",type="integer",JSONPath=".status.upgrading"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HelmOperationSet is the Schema for the helmoperationsets API
+type HelmOperationSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HelmOperationSetSpec   `json:"spec,omitempty"`
+	Status HelmOperationSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HelmOperationSetList contains a list of HelmOperationSet
+type HelmOperationSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HelmOperationSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HelmOperationSet{}, &HelmOperationSetList{})
+}
@@ -23,9 +23,42 @@ import (
 type RepoType string
 
 var (
-	//+kubebuilder:validation:Enum=ChartMuseum,Git
+	//+kubebuilder:validation:Enum=ChartMuseum,Git,S3,ConfigMap,LocalPath,Embedded
 	RepoTypeChartMuseum RepoType = "ChartMuseum"
 	RepoTypeGit         RepoType = "Git"
+	RepoTypeS3          RepoType = "S3"
+	RepoTypeConfigMap   RepoType = "ConfigMap"
+	RepoTypeLocalPath   RepoType = "LocalPath"
+	RepoTypeEmbedded    RepoType = "Embedded"
+)
+
+const (
+	//S3AccessKeyIDSecretKey the key of the access key id in the s3 credential secret
+	S3AccessKeyIDSecretKey = "accessKeyID"
+	//S3SecretAccessKeySecretKey the key of the secret access key in the s3 credential secret
+	S3SecretAccessKeySecretKey = "secretAccessKey"
+	//S3SessionTokenSecretKey the key of the optional session token in the s3 credential secret
+	S3SessionTokenSecretKey = "sessionToken"
+	//MaxCatalogCharts the max count of the charts listed in the catalog of the repo status
+	MaxCatalogCharts = 200
+	//MaxChangedDigests the max count of the changed chart versions kept in the repo status, the oldest are dropped
+	MaxChangedDigests = 50
+	//AuthUsernameSecretKey the key of the username in the auth secret
+	AuthUsernameSecretKey = "username"
+	//AuthPasswordSecretKey the key of the password in the auth secret
+	AuthPasswordSecretKey = "password"
+	//AuthTokenSecretKey the key of the bearer token in the auth secret
+	AuthTokenSecretKey = "token"
+	//AuthClientIDSecretKey the key of the oauth2 client id in the auth secret
+	AuthClientIDSecretKey = "clientID"
+	//AuthClientSecretSecretKey the key of the oauth2 client secret in the auth secret
+	AuthClientSecretSecretKey = "clientSecret"
+	//TLSCASecretKey the key of the ca bundle in the tls secret
+	TLSCASecretKey = "ca.crt"
+	//TLSCertSecretKey the key of the client certificate in the tls secret
+	TLSCertSecretKey = "tls.crt"
+	//TLSKeySecretKey the key of the private key of the client certificate in the tls secret
+	TLSKeySecretKey = "tls.key"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -36,30 +69,162 @@ type HelmRepoSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	//RepoType Chart repo type support git or chart museum
+	//RepoType Chart repo type support git, chart museum, s3 or the in-cluster sources ConfigMap, LocalPath and Embedded
 	RepoType RepoType `json:"repoType,omitempty"`
 
-	//RepoURL chart repo url
+	//RepoURL chart repo url, it is the endpoint of the s3 repo if the s3 endpoint not set
 	RepoURL string `json:"repoURL,omitempty"`
 
+	//S3 the bucket of the index.yaml and the chart archives for the s3 repo
+	S3 *S3Repo `json:"s3,omitempty"`
+
+	//ConfigMap the configmaps and secrets which hold the chart archives for the ConfigMap repo
+	ConfigMap *ConfigMapRepo `json:"configMap,omitempty"`
+
+	//LocalPath the directory of the chart archives and the chart directories for the LocalPath repo
+	LocalPath *LocalPathRepo `json:"localPath,omitempty"`
+
+	//Embedded the configmap which hold the index.yaml and the chart archives for the Embedded repo
+	Embedded *EmbeddedRepo `json:"embedded,omitempty"`
+
 	//Username the user name for chart repo auth
 	Username string `json:"username,omitempty"`
 
 	//Password the user password for chart repo auth
 	Password string `json:"password,omitempty"`
 
+	//Auth the auth of the requests to the http repos like ChartMuseum and S3, the Username and the Password
+	// are used as the basic auth if it is not set
+	Auth *RepoAuth `json:"auth,omitempty"`
+
 	//InsecureSkipTLS is skip tls verify
 	InsecureSkipTLS bool `json:"insecureSkipTLS,omitempty"`
 
-	//TLSSecretName if use tls get the tls secret name
-	// <em>notice:</em> current not support
+	//TLSSecretName the secret in the namespace of the HelmRepo which hold the tls data like TLSSecretRef
+	// Deprecated: use TLSSecretRef instead
 	TLSSecretName string `json:"tlsSecretName,omitempty"`
 
+	//TLSSecretRef the secret which hold the ca bundle `ca.crt` and the client certificate `tls.crt` and `tls.key`
+	// of the repo, the namespace is default to the namespace of the HelmRepo and must be set for the ClusterHelmRepo
+	TLSSecretRef *SecretReference `json:"tlsSecretRef,omitempty"`
+
+	//Timeout the timeout of each request to the repo, like the index and the chart download
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
 	// git auth token for git operation
 	GitAuthToken string `json:"gitAuthToken,omitempty"`
 
 	// if user git repo must set git branch ,if not set default is master
 	GitBranch string `json:"gitBranch,omitempty"`
+
+	//Suspend stop the auto update of the helm operations from this repo until it unset
+	Suspend bool `json:"suspend,omitempty"`
+
+	//DigestChangePolicy the action when the digest of an existing chart version changed between the syncs,
+	// Alert post the DigestChanged event to the alerts of the helm operations which use the version,
+	// Block also stop deploying the version until the helm operation pin the new digest with spec.chartDigest.
+	// default is Ignore
+	DigestChangePolicy DigestChangePolicy `json:"digestChangePolicy,omitempty"`
+}
+
+//+kubebuilder:validation:Enum=Ignore;Alert;Block
+//DigestChangePolicy the action when the digest of an existing chart version changed
+type DigestChangePolicy string
+
+const (
+	DigestChangePolicyIgnore DigestChangePolicy = "Ignore"
+	DigestChangePolicyAlert  DigestChangePolicy = "Alert"
+	DigestChangePolicyBlock  DigestChangePolicy = "Block"
+)
+
+//S3Repo the s3 compatible bucket which hold the index.yaml and the chart archives
+type S3Repo struct {
+	//Endpoint the s3 endpoint like `https://s3.us-east-1.amazonaws.com` or `http://minio:9000`,
+	// default is the aws endpoint of the region
+	Endpoint string `json:"endpoint,omitempty"`
+	//Bucket the bucket name
+	Bucket string `json:"bucket"`
+	//Prefix the key prefix of the index.yaml and the chart archives
+	Prefix string `json:"prefix,omitempty"`
+	//Region the region of the bucket, default is us-east-1
+	Region string `json:"region,omitempty"`
+	//PathStyle use the path style url like `<endpoint>/<bucket>/<key>`, it is usually required by minio
+	PathStyle bool `json:"pathStyle,omitempty"`
+	//SecretRef the secret which hold the `accessKeyID`, `secretAccessKey` and the optional `sessionToken`,
+	// the namespace is default to the namespace of the HelmRepo and must be set for the ClusterHelmRepo.
+	// the requests are not signed if not set
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+}
+
+//RepoAuthType the auth type of the repo
+//+kubebuilder:validation:Enum=Basic;Bearer;TokenExchange;ClientCredentials
+type RepoAuthType string
+
+const (
+	//RepoAuthTypeBasic the basic auth with the username and the password
+	RepoAuthTypeBasic RepoAuthType = "Basic"
+	//RepoAuthTypeBearer the static bearer token
+	RepoAuthTypeBearer RepoAuthType = "Bearer"
+	//RepoAuthTypeTokenExchange the bearer token got from the realm of the WWW-Authenticate challenge
+	// with the username and the password, like harbor and the docker registry token auth
+	RepoAuthTypeTokenExchange RepoAuthType = "TokenExchange"
+	//RepoAuthTypeClientCredentials the bearer token got with the oauth2 client credentials grant
+	RepoAuthTypeClientCredentials RepoAuthType = "ClientCredentials"
+)
+
+//RepoAuth the auth of the requests to the repo, the tokens are cached and refreshed before they expired
+type RepoAuth struct {
+	//Type the auth type Basic, Bearer, TokenExchange or ClientCredentials
+	Type RepoAuthType `json:"type"`
+	//SecretRef the secret which hold the credentials, `username` and `password` for Basic and TokenExchange,
+	// `token` for Bearer, `clientID` and `clientSecret` for ClientCredentials.
+	// the namespace is default to the namespace of the HelmRepo and must be set for the ClusterHelmRepo
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+	//TokenURL the token endpoint of the ClientCredentials auth
+	TokenURL string `json:"tokenURL,omitempty"`
+	//Scopes the scopes requested by the ClientCredentials auth
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+//ConfigMapRepo the configmaps and secrets which hold the `.tgz` chart archives, the archives are base64
+// encoded in the configmap data, or raw in the configmap binary data and the secret data
+type ConfigMapRepo struct {
+	//Selector select the configmaps and secrets by labels
+	Selector metav1.LabelSelector `json:"selector"`
+	//Namespace the namespace of the configmaps and secrets, default to the namespace of the HelmRepo
+	// and must be set for the ClusterHelmRepo
+	Namespace string `json:"namespace,omitempty"`
+}
+
+//LocalPathRepo the directory on a volume mounted to the controller, only the ClusterHelmRepo support it
+type LocalPathRepo struct {
+	//Path the absolute path of the directory, the `.tgz` files and the dirs with Chart.yaml under it are the charts
+	Path string `json:"path"`
+}
+
+//EmbeddedRepo the configmap which hold the index.yaml and the chart archives, the urls in the index
+// are the keys of the archives in the configmap, the index is generated from the archives if not exist
+type EmbeddedRepo struct {
+	//Name the configmap name
+	Name string `json:"name"`
+	//Namespace the configmap namespace, default to the namespace of the HelmRepo and must be set for the ClusterHelmRepo
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// GetTLSSecretRef get the tls secret reference, the TLSSecretName is used if the TLSSecretRef not set
+func (s *HelmRepoSpec) GetTLSSecretRef() *SecretReference {
+	if s.TLSSecretRef == nil && s.TLSSecretName != "" {
+		return &SecretReference{Name: s.TLSSecretName}
+	}
+	return s.TLSSecretRef
+}
+
+//SecretReference the reference of a secret
+type SecretReference struct {
+	//Name the secret name
+	Name string `json:"name"`
+	//Namespace the secret namespace
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // HelmRepoStatus defines the observed state of HelmRepo
@@ -67,18 +232,96 @@ type HelmRepoStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	Conditions []Condition `json:"conditions,omitempty"`
+	// Suspension who and when suspend the helm repo
+	Suspension *Suspension `json:"suspension,omitempty"`
+	// CircuitBreaker the circuit breaker of the requests to the http repos like ChartMuseum and S3
+	CircuitBreaker *CircuitBreakerStatus `json:"circuitBreaker,omitempty"`
+	// IndexRefreshTime the last time the cached index refreshed by the sync, only set for the repos
+	// which cache the index like ChartMuseum
+	IndexRefreshTime *metav1.Time `json:"indexRefreshTime,omitempty"`
+	// Catalog the summary of the charts in the repo at the last succeeded sync
+	Catalog *ChartCatalog `json:"catalog,omitempty"`
+	// ChangedDigests the chart versions which digest changed since the version first seen by the manager,
+	// only detected if the digest change policy is Alert or Block
+	ChangedDigests []ChangedChartDigest `json:"changedDigests,omitempty"`
+}
+
+//ChangedChartDigest a chart version which content re-pushed with another digest
+type ChangedChartDigest struct {
+	//Name the chart name
+	Name string `json:"name"`
+	//Version the chart version
+	Version string `json:"version"`
+	//OriginalDigest the digest when the version first seen
+	OriginalDigest string `json:"originalDigest"`
+	//Digest the current digest of the version in the repo
+	Digest string `json:"digest"`
+	//DetectedTime the time the current digest detected
+	DetectedTime *metav1.Time `json:"detectedTime,omitempty"`
+}
+
+// GetChangedDigest find the changed digest of the chart version, return nil if the digest not changed
+func (s *HelmRepoStatus) GetChangedDigest(chartName, version string) *ChangedChartDigest {
+	for i := range s.ChangedDigests {
+		if s.ChangedDigests[i].Name == chartName && s.ChangedDigests[i].Version == version {
+			return &s.ChangedDigests[i]
+		}
+	}
+	return nil
+}
+
+//ChartCatalog the summary of the charts in the repo, at most MaxCatalogCharts charts are listed to keep
+// the status small, the full list is served by the catalog api of the manager
+type ChartCatalog struct {
+	//ChartCount the count of the charts in the repo
+	ChartCount int32 `json:"chartCount"`
+	//VersionCount the count of the chart versions in the repo
+	VersionCount int32 `json:"versionCount"`
+	//Truncated the charts are truncated to the first MaxCatalogCharts charts by name
+	Truncated bool `json:"truncated,omitempty"`
+	//Charts the summary of the charts sorted by name
+	Charts []CatalogChart `json:"charts,omitempty"`
+}
+
+//CatalogChart the summary of a chart in the repo
+type CatalogChart struct {
+	//Name the chart name
+	Name string `json:"name"`
+	//LatestVersion the latest version of the chart
+	LatestVersion string `json:"latestVersion"`
+	//VersionCount the count of the versions of the chart
+	VersionCount int32 `json:"versionCount"`
+	//LastChanged the time the latest version or the version count changed
+	LastChanged *metav1.Time `json:"lastChanged,omitempty"`
+}
+
+//CircuitBreakerStatus the state of the circuit breaker, the requests to the repo are rejected without sending
+// when it is open, until the cooldown passed and a probe request succeeded
+type CircuitBreakerStatus struct {
+	//State the breaker state Closed, Open or HalfOpen
+	State string `json:"state"`
+	//ConsecutiveFailures the failed requests since the last succeeded request
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	//LastError the error of the last failed request
+	LastError string `json:"lastError,omitempty"`
+	//LastTransitionTime the time the state changed
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:path=helmrepos
-//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:resource:scope=Namespaced
 //+kubebuilder:printcolumn:name="Repo_Name",type="string",JSONPath=".spec.repoName"
 //+kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.repoURL"
 //+kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.repoType"
+//+kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend"
+//+kubebuilder:printcolumn:name="Breaker",type="string",JSONPath=".status.circuitBreaker.state",priority=1
+//+kubebuilder:printcolumn:name="Charts",type="integer",JSONPath=".status.catalog.chartCount"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
-// HelmRepo is the Schema for the helmrepos API
+// HelmRepo is the Schema for the helmrepos API,
+// the helm repo is only visible to the helm operations in the same namespace
 type HelmRepo struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -18,8 +18,11 @@ package v1alpha1
 
 import (
 	"errors"
+	"fmt"
+	"path"
 	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -44,8 +47,13 @@ var _ webhook.Defaulter = &HelmRepo{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (r *HelmRepo) Default() {
 	helmrepolog.Info("default", "name", r.Name)
-	if r.Spec.GitBranch == "" {
-		r.Spec.GitBranch = "master"
+	r.Spec.setDefaults()
+}
+
+// setDefaults set the default values of the repo spec shared by HelmRepo and ClusterHelmRepo
+func (s *HelmRepoSpec) setDefaults() {
+	if s.GitBranch == "" {
+		s.GitBranch = "master"
 	}
 }
 
@@ -62,11 +70,110 @@ func (r *HelmRepo) ValidateCreate() error {
 }
 
 func (r *HelmRepo) commonValidate() error {
-	if !(strings.HasPrefix(strings.ToLower(r.Spec.RepoURL), "http") || strings.HasPrefix(strings.ToLower(r.Spec.RepoURL), "git@")) {
+	if s3 := r.Spec.S3; s3 != nil && s3.SecretRef != nil && s3.SecretRef.Namespace != "" && s3.SecretRef.Namespace != r.Namespace {
+		return errors.New("the s3 secret must be in the namespace of the helm repo")
+	}
+	if auth := r.Spec.Auth; auth != nil && auth.SecretRef != nil && auth.SecretRef.Namespace != "" && auth.SecretRef.Namespace != r.Namespace {
+		return errors.New("the auth secret must be in the namespace of the helm repo")
+	}
+	if ref := r.Spec.TLSSecretRef; ref != nil && ref.Namespace != "" && ref.Namespace != r.Namespace {
+		return errors.New("the tls secret must be in the namespace of the helm repo")
+	}
+	if c := r.Spec.ConfigMap; c != nil && c.Namespace != "" && c.Namespace != r.Namespace {
+		return errors.New("the chart configmaps must be in the namespace of the helm repo")
+	}
+	if e := r.Spec.Embedded; e != nil && e.Namespace != "" && e.Namespace != r.Namespace {
+		return errors.New("the embedded chart configmap must be in the namespace of the helm repo")
+	}
+	if r.Spec.RepoType == RepoTypeLocalPath {
+		return errors.New("the LocalPath repo is only supported by the cluster helm repo")
+	}
+	return r.Spec.validate()
+}
+
+// validate the repo spec shared by HelmRepo and ClusterHelmRepo
+func (s *HelmRepoSpec) validate() error {
+	if ref := s.GetTLSSecretRef(); ref != nil && ref.Name == "" {
+		return errors.New("tls secret name can not empty")
+	}
+	if s.Timeout != nil && s.Timeout.Duration < 0 {
+		return errors.New("the timeout of the repo can not be negative")
+	}
+	if err := s.validateAuth(); err != nil {
+		return err
+	}
+	switch s.RepoType {
+	case RepoTypeS3:
+		return s.validateS3()
+	case RepoTypeConfigMap:
+		if s.ConfigMap == nil {
+			return errors.New("the configmap selector must be set for the ConfigMap repo")
+		}
+		if _, err := metav1.LabelSelectorAsSelector(&s.ConfigMap.Selector); err != nil {
+			return fmt.Errorf("the configmap selector is invalid: %v", err)
+		}
+		return nil
+	case RepoTypeLocalPath:
+		if s.LocalPath == nil || !path.IsAbs(s.LocalPath.Path) {
+			return errors.New("the absolute path must be set for the LocalPath repo")
+		}
+		return nil
+	case RepoTypeEmbedded:
+		if s.Embedded == nil || s.Embedded.Name == "" {
+			return errors.New("the configmap name must be set for the Embedded repo")
+		}
+		return nil
+	}
+	if !(strings.HasPrefix(strings.ToLower(s.RepoURL), "http") || strings.HasPrefix(strings.ToLower(s.RepoURL), "git@")) {
 		return errors.New("repo url not support")
 	}
-	if r.Spec.RepoType != RepoTypeGit && r.Spec.RepoType != RepoTypeChartMuseum {
-		return errors.New("repo type only support Git or ChartMuseum")
+	if s.RepoType != RepoTypeGit && s.RepoType != RepoTypeChartMuseum {
+		return errors.New("repo type only support Git, ChartMuseum, S3, ConfigMap, LocalPath or Embedded")
+	}
+	return nil
+}
+
+// validateAuth check the secret and the token url required by the auth type
+func (s *HelmRepoSpec) validateAuth() error {
+	if s.Auth == nil {
+		return nil
+	}
+	if s.Auth.SecretRef != nil && s.Auth.SecretRef.Name == "" {
+		return errors.New("auth secret name can not empty")
+	}
+	switch s.Auth.Type {
+	case RepoAuthTypeBasic, RepoAuthTypeTokenExchange:
+	case RepoAuthTypeBearer:
+		if s.Auth.SecretRef == nil {
+			return errors.New("the secret of the token must be set for the Bearer auth")
+		}
+	case RepoAuthTypeClientCredentials:
+		if s.Auth.SecretRef == nil {
+			return errors.New("the secret of the client id and secret must be set for the ClientCredentials auth")
+		}
+		if !strings.HasPrefix(strings.ToLower(s.Auth.TokenURL), "http") {
+			return fmt.Errorf("the token url %s of the ClientCredentials auth is not a http or https url", s.Auth.TokenURL)
+		}
+	default:
+		return fmt.Errorf("auth type %s not support", s.Auth.Type)
+	}
+	return nil
+}
+
+// validateS3 check the bucket of the s3 repo, the endpoint is the s3 endpoint or the repo url
+func (s *HelmRepoSpec) validateS3() error {
+	if s.S3 == nil || s.S3.Bucket == "" {
+		return errors.New("the s3 bucket must be set for the S3 repo")
+	}
+	endpoint := s.S3.Endpoint
+	if endpoint == "" {
+		endpoint = s.RepoURL
+	}
+	if endpoint != "" && !strings.HasPrefix(strings.ToLower(endpoint), "http") {
+		return fmt.Errorf("s3 endpoint %s is not a http or https url", endpoint)
+	}
+	if s.S3.SecretRef != nil && s.S3.SecretRef.Name == "" {
+		return errors.New("s3 secret name can not empty")
 	}
 	return nil
 }
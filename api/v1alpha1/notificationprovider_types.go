@@ -0,0 +1,88 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//NotificationProviderType the type of the notification provider
+type NotificationProviderType string
+
+const (
+	//NotificationProviderSlack post the message to a slack incoming webhook
+	NotificationProviderSlack NotificationProviderType = "Slack"
+	//NotificationProviderMSTeams post the message to a microsoft teams incoming webhook
+	NotificationProviderMSTeams NotificationProviderType = "MSTeams"
+	//NotificationProviderGeneric post the event as json to a webhook, signed with the hmac key if set
+	NotificationProviderGeneric NotificationProviderType = "Generic"
+
+	//NotificationAddressSecretKey the key of the webhook address in the provider secret
+	NotificationAddressSecretKey = "address"
+	//NotificationHMACKeySecretKey the key of the hmac key in the provider secret, only for the generic provider
+	NotificationHMACKeySecretKey = "hmacKey"
+)
+
+// NotificationProviderSpec defines the desired state of NotificationProvider
+type NotificationProviderSpec struct {
+	//+kubebuilder:validation:Enum=Slack;MSTeams;Generic
+	//Type the type of the provider
+	Type NotificationProviderType `json:"type"`
+	//Address the webhook address, the address in the secret take precedence over it
+	Address string `json:"address,omitempty"`
+	//Channel the slack channel to post, default is the channel of the incoming webhook
+	Channel string `json:"channel,omitempty"`
+	//Username the slack user name of the message, default is helmops
+	Username string `json:"username,omitempty"`
+	//SecretRef the secret in the namespace of the provider which hold the `address` or the `hmacKey`
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+	//Suspend stop to post the messages of the provider
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+//LocalObjectReference the reference of an object in the same namespace
+type LocalObjectReference struct {
+	//Name the name of the object
+	Name string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+//+kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NotificationProvider is the Schema for the notificationproviders API,
+// it is the destination of the alert messages
+type NotificationProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotificationProviderSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NotificationProviderList contains a list of NotificationProvider
+type NotificationProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationProvider `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotificationProvider{}, &NotificationProviderList{})
+}
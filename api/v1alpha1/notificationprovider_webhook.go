@@ -0,0 +1,85 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var notificationproviderlog = logf.Log.WithName("notificationprovider-resource")
+
+func (r *NotificationProvider) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-helmops-shijunlee-net-v1alpha1-notificationprovider,mutating=false,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=notificationproviders,verbs=create;update,versions=v1alpha1,name=vnotificationprovider.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &NotificationProvider{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *NotificationProvider) ValidateCreate() error {
+	notificationproviderlog.Info("validate create", "name", r.Name)
+	return r.commonValidate()
+}
+
+func (r *NotificationProvider) commonValidate() error {
+	switch r.Spec.Type {
+	case NotificationProviderSlack, NotificationProviderMSTeams, NotificationProviderGeneric:
+	default:
+		return errors.Errorf("notification provider type %s is not supported", r.Spec.Type)
+	}
+	if r.Spec.SecretRef != nil {
+		if r.Spec.SecretRef.Name == "" {
+			return errors.New("secret name can not empty")
+		}
+		// the address may be in the secret
+		if r.Spec.Address == "" {
+			return nil
+		}
+	}
+	if r.Spec.Address == "" {
+		return errors.New("the address or the secret of the address must be set")
+	}
+	address, err := url.Parse(r.Spec.Address)
+	if err != nil {
+		return errors.Wrapf(err, "address %s is invalid", r.Spec.Address)
+	}
+	if address.Scheme != "http" && address.Scheme != "https" {
+		return errors.Errorf("address %s is not a http or https url", r.Spec.Address)
+	}
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *NotificationProvider) ValidateUpdate(old runtime.Object) error {
+	notificationproviderlog.Info("validate update", "name", r.Name)
+	return r.commonValidate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *NotificationProvider) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//SuspendedByAnnotation the user who set spec.suspend, recorded by the webhook
+	SuspendedByAnnotation = "helmops.shijunlee.net/suspended-by"
+	//SuspendedAtAnnotation the time in RFC3339 when spec.suspend set, recorded by the webhook
+	SuspendedAtAnnotation = "helmops.shijunlee.net/suspended-at"
+)
+
+//Suspension who and when suspend the reconciliation
+type Suspension struct {
+	//SuspendedBy the user who set spec.suspend
+	SuspendedBy string `json:"suspendedBy,omitempty"`
+	//SuspendedAt the time when spec.suspend set
+	SuspendedAt *metav1.Time `json:"suspendedAt,omitempty"`
+}
+
+// GetSuspension get the suspension recorded in the annotations of the object
+func GetSuspension(obj metav1.Object) *Suspension {
+	annotations := obj.GetAnnotations()
+	var suspension = &Suspension{SuspendedBy: annotations[SuspendedByAnnotation]}
+	if suspendedAt, err := time.Parse(time.RFC3339, annotations[SuspendedAtAnnotation]); err == nil {
+		var t = metav1.NewTime(suspendedAt)
+		suspension.SuspendedAt = &t
+	}
+	return suspension
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const suspendWebhookPath = "/mutate-helmops-shijunlee-net-v1alpha1-suspend"
+
+var suspendlog = logf.Log.WithName("suspend-resource")
+
+// SetupSuspendWebhookWithManager register the webhook which record who and when suspend the helm operations and helm repos
+func SetupSuspendWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(suspendWebhookPath, &webhook.Admission{Handler: &suspendRecorder{}})
+}
+
+//+kubebuilder:webhook:path=/mutate-helmops-shijunlee-net-v1alpha1-suspend,mutating=true,failurePolicy=fail,sideEffects=None,groups=helmops.shijunlee.net,resources=helmoperations;helmrepos;clusterhelmrepos,verbs=create;update,versions=v1alpha1,name=msuspend.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// suspendRecorder the defaulter can not get the request user, so record the suspension in a separate webhook
+type suspendRecorder struct{}
+
+var _ admission.Handler = &suspendRecorder{}
+
+// Handle set the suspension annotations when spec.suspend set, and remove them when spec.suspend unset
+func (s *suspendRecorder) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &obj.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	suspend, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if suspend {
+		var oldAnnotations map[string]string
+		var oldSuspend bool
+		if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+			oldObj := &unstructured.Unstructured{}
+			if err := json.Unmarshal(req.OldObject.Raw, &oldObj.Object); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+			oldSuspend, _, _ = unstructured.NestedBool(oldObj.Object, "spec", "suspend")
+			oldAnnotations = oldObj.GetAnnotations()
+		}
+		if oldSuspend && oldAnnotations[SuspendedByAnnotation] != "" {
+			// keep the user who suspend it first, the annotations can not be changed by others
+			annotations[SuspendedByAnnotation] = oldAnnotations[SuspendedByAnnotation]
+			annotations[SuspendedAtAnnotation] = oldAnnotations[SuspendedAtAnnotation]
+		} else {
+			annotations[SuspendedByAnnotation] = req.UserInfo.Username
+			annotations[SuspendedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+			suspendlog.Info("suspend", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name, "user", req.UserInfo.Username)
+		}
+	} else {
+		delete(annotations, SuspendedByAnnotation)
+		delete(annotations, SuspendedAtAnnotation)
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	marshaled, err := json.Marshal(obj.Object)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func newChartsCommand(o *options) *cobra.Command {
+	var allVersions, cluster bool
+	cmd := &cobra.Command{
+		Use:   "charts REPO [CHART]",
+		Short: "List the charts and versions available in a helm repo",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			repo, err := o.chartRepo(context.Background(), c, o.repoReference(args[0], cluster))
+			if err != nil {
+				return err
+			}
+			chartVersions, err := repo.Operation.ListCharts()
+			if err != nil {
+				return err
+			}
+			var names []string
+			for name := range chartVersions {
+				if len(args) == 2 && name != args[1] {
+					continue
+				}
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "CHART\tVERSION")
+			for _, name := range names {
+				versions := chartVersions[name]
+				if len(versions) == 0 {
+					continue
+				}
+				// the versions are sorted with the latest at the end
+				sort.Sort(versions)
+				if !allVersions && len(args) == 1 {
+					fmt.Fprintf(w, "%s\t%s\n", name, versions[len(versions)-1].Version)
+					continue
+				}
+				for i := len(versions) - 1; i >= 0; i-- {
+					fmt.Fprintf(w, "%s\t%s\n", name, versions[i].Version)
+				}
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVar(&allVersions, "all-versions", false, "list all the versions of the charts, not only the latest")
+	cmd.Flags().BoolVar(&cluster, "cluster", false, "the repo is a cluster helm repo")
+	return cmd
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+// errDiffFound exit with code 1 like kubectl diff when the operation differs from the release
+var errDiffFound = errors.New("differences found")
+
+func newDiffCommand(o *options) *cobra.Command {
+	var manifest bool
+	cmd := &cobra.Command{
+		Use:   "diff OPERATION",
+		Short: "Diff a helm operation against its live release",
+		Long: `Diff the chart and values of a helm operation against its live release.
+
+With --manifest the chart is rendered with a dry run upgrade and the manifests are compared too.
+Exit status: 0 no differences were found, 1 differences were found, >1 an error occurred.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			operation, err := o.getHelmOperation(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			kubeClient, err := o.kubernetesClient(ctx, c, operation)
+			if err != nil {
+				return err
+			}
+			getOptions := &actions.GetOptions{
+				ReleaseName:       operation.GetReleaseName(),
+				Namespace:         operation.GetReleaseNamespace(),
+				KubernetesOptions: kubeClient,
+			}
+			rel, err := getOptions.Run()
+			if err != nil {
+				return errors.Wrapf(err, "get release %s/%s error", operation.GetReleaseNamespace(), operation.GetReleaseName())
+			}
+			var liveChart string
+			if rel.Chart != nil && rel.Chart.Metadata != nil {
+				liveChart = fmt.Sprintf("%s-%s\n", rel.Chart.Metadata.Name, rel.Chart.Metadata.Version)
+			}
+			liveValues, err := yaml.Marshal(rel.Config)
+			if err != nil {
+				return err
+			}
+			desiredValues, err := yaml.Marshal(operation.Spec.Values.Object)
+			if err != nil {
+				return err
+			}
+			var diffs = []difflib.UnifiedDiff{
+				newUnifiedDiff("chart", liveChart, fmt.Sprintf("%s-%s\n", operation.Spec.ChartName, operation.Spec.ChartVersion)),
+				newUnifiedDiff("values", string(liveValues), string(desiredValues)),
+			}
+			if manifest {
+				desiredManifest, err := o.renderManifest(ctx, operation, kubeClient)
+				if err != nil {
+					return err
+				}
+				diffs = append(diffs, newUnifiedDiff("manifest", rel.Manifest, desiredManifest))
+			}
+			var found bool
+			for _, diff := range diffs {
+				text, err := difflib.GetUnifiedDiffString(diff)
+				if err != nil {
+					return err
+				}
+				if text == "" {
+					continue
+				}
+				found = true
+				fmt.Fprint(os.Stdout, text)
+			}
+			if found {
+				return errDiffFound
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&manifest, "manifest", false, "render the chart with a dry run upgrade and diff the manifests")
+	return cmd
+}
+
+// renderManifest render the manifest of the helm operation with a dry run upgrade
+func (o *options) renderManifest(ctx context.Context, operation *helmopsv1alpha1.HelmOperation,
+	kubeClient *actions.KubernetesClient) (string, error) {
+	c, err := o.client()
+	if err != nil {
+		return "", err
+	}
+	repo, err := o.chartRepo(ctx, c, operation.GetChartRepoRef())
+	if err != nil {
+		return "", err
+	}
+	chartOpts, err := o.chartOptions(repo, operation.Spec.ChartName, operation.Spec.ChartVersion)
+	if err != nil {
+		return "", err
+	}
+	upgradeOptions := &actions.UpgradeOptions{
+		Values:            operation.Spec.Values.Object,
+		Namespace:         operation.GetReleaseNamespace(),
+		ReleaseName:       operation.GetReleaseName(),
+		DryRun:            true,
+		SkipCRDs:          operation.Spec.Upgrade.SkipCRDs,
+		DisableHooks:      operation.Spec.Upgrade.DisableHooks,
+		ResetValues:       operation.Spec.Upgrade.ResetValues,
+		ReuseValues:       operation.Spec.Upgrade.ReuseValues,
+		ChartOpts:         chartOpts,
+		KubernetesOptions: kubeClient,
+	}
+	rel, err := upgradeOptions.Run()
+	if err != nil {
+		return "", errors.Wrap(err, "render the chart with dry run upgrade error")
+	}
+	return rel.Manifest, nil
+}
+
+func newUnifiedDiff(name, live, desired string) difflib.UnifiedDiff {
+	return difflib.UnifiedDiff{
+		A:        difflib.SplitLines(live),
+		B:        difflib.SplitLines(desired),
+		FromFile: "live/" + name,
+		ToFile:   "desired/" + name,
+		Context:  3,
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/releaseutil"
+
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+func newHistoryCommand(o *options) *cobra.Command {
+	var max int
+	cmd := &cobra.Command{
+		Use:   "history OPERATION",
+		Short: "Print the revision history of the release managed by a helm operation",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			operation, err := o.getHelmOperation(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			kubeClient, err := o.kubernetesClient(ctx, c, operation)
+			if err != nil {
+				return err
+			}
+			historyOptions := &actions.HistoryOptions{
+				Max:               max,
+				Namespace:         operation.GetReleaseNamespace(),
+				KubernetesOptions: kubeClient,
+				ReleaseName:       operation.GetReleaseName(),
+			}
+			releases, err := historyOptions.Run()
+			if err != nil {
+				return err
+			}
+			releaseutil.SortByRevision(releases)
+			if max > 0 && len(releases) > max {
+				releases = releases[len(releases)-max:]
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "REVISION\tUPDATED\tSTATUS\tCHART\tAPP VERSION\tDESCRIPTION")
+			for _, rel := range releases {
+				var chartName, appVersion, updated string
+				if rel.Chart != nil && rel.Chart.Metadata != nil {
+					chartName = fmt.Sprintf("%s-%s", rel.Chart.Metadata.Name, rel.Chart.Metadata.Version)
+					appVersion = rel.Chart.Metadata.AppVersion
+				}
+				if rel.Info != nil {
+					updated = rel.Info.LastDeployed.Format("2006-01-02 15:04:05")
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", rel.Version, updated, rel.Info.Status,
+						chartName, appVersion, rel.Info.Description)
+					continue
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", rel.Version, updated, "", chartName, appVersion, "")
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().IntVar(&max, "max", 256, "maximum number of revisions to include in the history")
+	return cmd
+}
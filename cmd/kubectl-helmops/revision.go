@@ -0,0 +1,130 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// listRevisions list the controller revisions of the helm operation sorted by the revision number
+func listRevisions(ctx context.Context, c client.Client,
+	operation *helmopsv1alpha1.HelmOperation) ([]helmopsv1alpha1.HelmOperationControllerRevision, error) {
+	var revisionList = &helmopsv1alpha1.HelmOperationControllerRevisionList{}
+	if err := c.List(ctx, revisionList, client.InNamespace(operation.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "list helm operation controller revisions error")
+	}
+	var revisions []helmopsv1alpha1.HelmOperationControllerRevision
+	for _, item := range revisionList.Items {
+		if metav1.IsControlledBy(&item, operation) {
+			revisions = append(revisions, item)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+func newRevisionsCommand(o *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revisions OPERATION",
+		Short: "List the controller revisions recorded for each apply of a helm operation",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			operation, err := o.getHelmOperation(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			revisions, err := listRevisions(ctx, c, operation)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "REVISION\tCREATED\tACTION\tCHART VERSION\tRELEASE REVISION\tTRIGGERED BY")
+			for _, item := range revisions {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", item.Revision,
+					item.CreationTimestamp.Format("2006-01-02 15:04:05"), item.Action,
+					item.Spec.ChartVersion, item.ReleaseRevision, item.TriggeredBy)
+			}
+			return w.Flush()
+		},
+	}
+	return cmd
+}
+
+func newRestoreCommand(o *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore OPERATION REVISION",
+		Short: "Restore the spec of a helm operation to a controller revision",
+		Long: `Restore the spec of a helm operation to the desired state recorded in a controller revision.
+
+The chart version and values of the revision are applied by the controller like any other spec change,
+the suspend of the helm operation is not changed.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return errors.Errorf("revision %s is invalid", args[1])
+			}
+			ctx := context.Background()
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			operation, err := o.getHelmOperation(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			revisions, err := listRevisions(ctx, c, operation)
+			if err != nil {
+				return err
+			}
+			for _, item := range revisions {
+				if item.Revision != number {
+					continue
+				}
+				spec := item.Spec
+				spec.Suspend = operation.Spec.Suspend
+				operation.Spec = spec
+				if err = c.Update(ctx, operation); err != nil {
+					return errors.Wrapf(err, "restore helm operation %s error", args[0])
+				}
+				fmt.Printf("helmoperation/%s restored to revision %d\n", args[0], number)
+				return nil
+			}
+			return errors.Errorf("revision %d of helm operation %s not found", number, args[0])
+		},
+	}
+	return cmd
+}
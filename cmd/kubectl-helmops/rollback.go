@@ -0,0 +1,68 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+func newRollbackCommand(o *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback OPERATION REVISION",
+		Short: "Rollback the release of a helm operation to the revision",
+		Long: `Rollback the release of a helm operation to the revision.
+
+The rollback is done by the controller through the rollback-to-revision annotation,
+use "kubectl helmops history" to find the revisions of the release.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			revision, err := strconv.Atoi(args[1])
+			if err != nil || revision <= 0 {
+				return errors.Errorf("revision %s is invalid", args[1])
+			}
+			ctx := context.Background()
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			operation, err := o.getHelmOperation(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			// the controller only handle the annotation when the value changed, the requested time make the
+			// value unique so the same revision can be requested again
+			patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+				helmopsv1alpha1.RollbackToRevisionAnnotation, helmopsv1alpha1.RollbackToRevisionValue(revision, time.Now())))
+			if err = c.Patch(ctx, operation, client.RawPatch(types.MergePatchType, patch)); err != nil {
+				return errors.Wrapf(err, "rollback helm operation %s error", args[0])
+			}
+			fmt.Printf("helmoperation/%s rollback to revision %d requested\n", args[0], revision)
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/charts"
+	"github.com/shijunLee/helmops/pkg/charts/archive"
+	"github.com/shijunLee/helmops/pkg/charts/s3"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(helmopsv1alpha1.AddToScheme(scheme))
+}
+
+// options the common options of all the commands
+type options struct {
+	configFlags *genericclioptions.ConfigFlags
+	// cacheDir the local cache path for the git chart repos
+	cacheDir string
+}
+
+func newRootCommand() *cobra.Command {
+	o := &options{configFlags: genericclioptions.NewConfigFlags(true)}
+	cmd := &cobra.Command{
+		Use:           "kubectl-helmops",
+		Short:         "Manage the helmops helm operations and helm repos",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+	defaultCacheDir := filepath.Join(os.TempDir(), "kubectl-helmops")
+	if dir, err := os.UserCacheDir(); err == nil {
+		defaultCacheDir = filepath.Join(dir, "kubectl-helmops")
+	}
+	cmd.PersistentFlags().StringVar(&o.cacheDir, "chart-cache-dir", defaultCacheDir, "the local cache path for the git chart repos")
+	cmd.AddCommand(
+		newChartsCommand(o),
+		newShowCommand(o),
+		newDiffCommand(o),
+		newSuspendCommand(o, true),
+		newSuspendCommand(o, false),
+		newRollbackCommand(o),
+		newHistoryCommand(o),
+		newRevisionsCommand(o),
+		newRestoreCommand(o),
+	)
+	return cmd
+}
+
+// client create the controller runtime client for the helmops resources
+func (o *options) client() (client.Client, error) {
+	restConfig, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
+
+// namespace get the namespace from the flags or the current context
+func (o *options) namespace() string {
+	namespace, _, err := o.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil || namespace == "" {
+		return corev1.NamespaceDefault
+	}
+	return namespace
+}
+
+// getHelmOperation get the helm operation by name in the current namespace
+func (o *options) getHelmOperation(ctx context.Context, c client.Client, name string) (*helmopsv1alpha1.HelmOperation, error) {
+	operation := &helmopsv1alpha1.HelmOperation{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: o.namespace(), Name: name}, operation)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get helm operation %s/%s error", o.namespace(), name)
+	}
+	return operation, nil
+}
+
+// kubernetesClient create the kubernetes client for the helm actions of the operation,
+// same as the controller the kubeconfig secret of the operation is used for the remote cluster
+func (o *options) kubernetesClient(ctx context.Context, c client.Client,
+	operation *helmopsv1alpha1.HelmOperation) (*actions.KubernetesClient, error) {
+	var opts []actions.Option
+	if username := operation.GetServiceAccountUsername(); username != "" {
+		// render with the permissions of the service account like the controller
+		opts = append(opts, actions.WithImpersonate(rest.ImpersonationConfig{UserName: username}))
+	}
+	secretRef := operation.Spec.KubeConfigSecretRef
+	if secretRef == nil {
+		restConfig, err := o.configFlags.ToRESTConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, actions.WithRestConfig(restConfig))
+		return actions.NewKubernetesClient(opts...), nil
+	}
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: operation.Namespace, Name: secretRef.Name}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get kubeconfig secret %s/%s error", operation.Namespace, secretRef.Name)
+	}
+	var key = secretRef.Key
+	if key == "" {
+		key = helmopsv1alpha1.DefaultKubeConfigSecretKey
+	}
+	data, ok := secret.Data[key]
+	if !ok || len(data) == 0 {
+		return nil, fmt.Errorf("kubeconfig key %s not found in secret %s/%s", key, secret.Namespace, secret.Name)
+	}
+	if _, err = clientcmd.Load(data); err != nil {
+		return nil, errors.Wrapf(err, "load kubeconfig from secret %s/%s error", secret.Namespace, secret.Name)
+	}
+	opts = append(opts, actions.WithConfigString(string(data)),
+		actions.WithClientName(fmt.Sprintf("%s-%s", secret.Namespace, secret.Name)))
+	return actions.NewKubernetesClient(opts...), nil
+}
+
+// repoReference the reference of the repo in the command args, the HelmRepo is in the namespace of the command
+func (o *options) repoReference(name string, cluster bool) helmopsv1alpha1.ChartRepoReference {
+	if cluster {
+		return helmopsv1alpha1.ChartRepoReference{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: name}
+	}
+	return helmopsv1alpha1.ChartRepoReference{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: o.namespace(), Name: name}
+}
+
+// chartRepo create the chart repo client from the HelmRepo or ClusterHelmRepo resource
+func (o *options) chartRepo(ctx context.Context, c client.Client, ref helmopsv1alpha1.ChartRepoReference) (*charts.ChartRepo, error) {
+	var spec *helmopsv1alpha1.HelmRepoSpec
+	if ref.Kind == helmopsv1alpha1.ClusterHelmRepoKind {
+		clusterHelmRepo := &helmopsv1alpha1.ClusterHelmRepo{}
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.Name}, clusterHelmRepo); err != nil {
+			return nil, errors.Wrapf(err, "get cluster helm repo %s error", ref.Name)
+		}
+		spec = &clusterHelmRepo.Spec.HelmRepoSpec
+	} else {
+		helmRepo := &helmopsv1alpha1.HelmRepo{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, helmRepo); err != nil {
+			return nil, errors.Wrapf(err, "get helm repo %s/%s error", ref.Namespace, ref.Name)
+		}
+		spec = &helmRepo.Spec
+	}
+	repoOptions, err := o.repoOptions(ctx, c, spec, ref.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	// the same cache layout as the controller, the repos with the same name not conflict
+	return charts.NewChartRepo(path.Join(ref.Kind, ref.Namespace, ref.Name),
+		string(spec.RepoType), spec.RepoURL, spec.Username,
+		spec.Password, spec.GitAuthToken, spec.GitBranch,
+		o.cacheDir, spec.InsecureSkipTLS, 0, repoOptions...)
+}
+
+// repoOptions create the options of the repo types like the controller, the secrets are read with the user permissions
+func (o *options) repoOptions(ctx context.Context, c client.Client, spec *helmopsv1alpha1.HelmRepoSpec,
+	namespace string) ([]charts.RepoOption, error) {
+	var opts []charts.RepoOption
+	var transport utils.TransportOptions
+	if spec.Timeout != nil {
+		transport.Timeout = spec.Timeout.Duration
+	}
+	if ref := spec.GetTLSSecretRef(); ref != nil {
+		secret, err := repoSecret(ctx, c, ref, namespace)
+		if err != nil {
+			return nil, err
+		}
+		transport.CAData = secret.Data[helmopsv1alpha1.TLSCASecretKey]
+		transport.CertData = secret.Data[helmopsv1alpha1.TLSCertSecretKey]
+		transport.KeyData = secret.Data[helmopsv1alpha1.TLSKeySecretKey]
+	}
+	if spec.Auth != nil {
+		config := utils.AuthConfig{
+			Type:     string(spec.Auth.Type),
+			Username: spec.Username,
+			Password: spec.Password,
+			TokenURL: spec.Auth.TokenURL,
+			Scopes:   spec.Auth.Scopes,
+		}
+		if ref := spec.Auth.SecretRef; ref != nil {
+			secret, err := repoSecret(ctx, c, ref, namespace)
+			if err != nil {
+				return nil, err
+			}
+			config.Username = string(secret.Data[helmopsv1alpha1.AuthUsernameSecretKey])
+			config.Password = string(secret.Data[helmopsv1alpha1.AuthPasswordSecretKey])
+			config.Token = string(secret.Data[helmopsv1alpha1.AuthTokenSecretKey])
+			config.ClientID = string(secret.Data[helmopsv1alpha1.AuthClientIDSecretKey])
+			config.ClientSecret = string(secret.Data[helmopsv1alpha1.AuthClientSecretSecretKey])
+		}
+		// the token requests share the tls and the retry of the repo but are not authorized by the provider
+		tokenClient, err := utils.NewHTTPClient(transport)
+		if err != nil {
+			return nil, errors.Wrap(err, "create the token client of the repo error")
+		}
+		if transport.Auth, err = utils.NewAuthProvider(config, tokenClient); err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, charts.WithTransport(transport))
+	if spec.S3 != nil {
+		config := s3.Config{
+			Endpoint:  spec.S3.Endpoint,
+			Bucket:    spec.S3.Bucket,
+			Prefix:    spec.S3.Prefix,
+			Region:    spec.S3.Region,
+			PathStyle: spec.S3.PathStyle,
+		}
+		if ref := spec.S3.SecretRef; ref != nil {
+			secret, err := repoSecret(ctx, c, ref, namespace)
+			if err != nil {
+				return nil, err
+			}
+			config.AccessKeyID = string(secret.Data[helmopsv1alpha1.S3AccessKeyIDSecretKey])
+			config.SecretAccessKey = string(secret.Data[helmopsv1alpha1.S3SecretAccessKeySecretKey])
+			config.SessionToken = string(secret.Data[helmopsv1alpha1.S3SessionTokenSecretKey])
+		}
+		opts = append(opts, charts.WithS3Config(config))
+	}
+	switch spec.RepoType {
+	case helmopsv1alpha1.RepoTypeConfigMap:
+		if spec.ConfigMap == nil {
+			return nil, errors.New("the configmap selector of the ConfigMap repo is not set")
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&spec.ConfigMap.Selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "the configmap selector of the ConfigMap repo is invalid")
+		}
+		if spec.ConfigMap.Namespace != "" {
+			namespace = spec.ConfigMap.Namespace
+		}
+		opts = append(opts, charts.WithArchiveLoader(archive.ConfigMapLoader(ctx, c, namespace, selector)))
+	case helmopsv1alpha1.RepoTypeEmbedded:
+		if spec.Embedded == nil {
+			return nil, errors.New("the configmap of the Embedded repo is not set")
+		}
+		if spec.Embedded.Namespace != "" {
+			namespace = spec.Embedded.Namespace
+		}
+		opts = append(opts, charts.WithArchiveLoader(archive.EmbeddedLoader(ctx, c, namespace, spec.Embedded.Name)))
+	case helmopsv1alpha1.RepoTypeLocalPath:
+		return nil, errors.New("the LocalPath repo is on the controller volume and can not be read by the plugin")
+	}
+	return opts, nil
+}
+
+// repoSecret get the secret referenced by the repo, the namespace of the reference is default to the repo namespace
+func repoSecret(ctx context.Context, c client.Client, ref *helmopsv1alpha1.SecretReference, namespace string) (*corev1.Secret, error) {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "get repo secret %s/%s error", namespace, ref.Name)
+	}
+	return secret, nil
+}
+
+// chartOptions resolve the chart location of the chart version in the repo
+func (o *options) chartOptions(repo *charts.ChartRepo, chartName, chartVersion string) (*actions.ChartOpts, error) {
+	if chartVersion == "" {
+		version, err := repo.Operation.GetChartLastVersion(chartName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get the latest version of chart %s error", chartName)
+		}
+		chartVersion = version
+	}
+	url, pathType, err := repo.Operation.GetChartVersionUrl(chartName, chartVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get chart %s version %s error", chartName, chartVersion)
+	}
+	chartOpts := &actions.ChartOpts{
+		ChartName:             chartName,
+		ChartVersion:          chartVersion,
+		InsecureSkipTLSVerify: repo.InsecureSkipTLS,
+		Transport:             repo.Transport,
+		DependencyCacheDir:    filepath.Join(o.cacheDir, ".dependencies"),
+	}
+	switch pathType {
+	case "file":
+		chartOpts.LocalPath = url
+		if localRepo, ok := repo.Operation.(charts.LocalRepo); ok {
+			chartOpts.LocalRootPath = localRepo.RootPath()
+		}
+	case "http":
+		chartOpts.ChartURL = url
+	case archive.PathType:
+		archiveRepo, ok := repo.Operation.(charts.ArchiveRepo)
+		if !ok {
+			return nil, errors.Errorf("repo %s not support chart archive", repo.Name)
+		}
+		if chartOpts.ChartArchive, err = archiveRepo.ChartArchive(chartName, chartVersion); err != nil {
+			return nil, err
+		}
+	}
+	return chartOpts, nil
+}
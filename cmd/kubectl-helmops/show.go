@@ -0,0 +1,78 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	helmactions "helm.sh/helm/v3/pkg/action"
+
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+func newShowCommand(o *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the information of a chart in a helm repo",
+	}
+	cmd.AddCommand(
+		newShowSubCommand(o, "readme", "Show the README of the chart", helmactions.ShowReadme),
+		newShowSubCommand(o, "values", "Show the default values of the chart", helmactions.ShowValues),
+		newShowSubCommand(o, "chart", "Show the definition of the chart", helmactions.ShowChart),
+		newShowSubCommand(o, "all", "Show all the information of the chart", helmactions.ShowAll),
+	)
+	return cmd
+}
+
+func newShowSubCommand(o *options, use, short string, format helmactions.ShowOutputFormat) *cobra.Command {
+	var version string
+	var cluster bool
+	cmd := &cobra.Command{
+		Use:   use + " REPO CHART",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			repo, err := o.chartRepo(context.Background(), c, o.repoReference(args[0], cluster))
+			if err != nil {
+				return err
+			}
+			chartOpts, err := o.chartOptions(repo, args[1], version)
+			if err != nil {
+				return err
+			}
+			showOptions := &actions.ShowOptions{
+				OutputFormat: format,
+				ChartOpts:    chartOpts,
+			}
+			out, err := showOptions.Run()
+			if err != nil {
+				return err
+			}
+			fmt.Print(out)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&version, "version", "", "the chart version, the latest version is used if not set")
+	cmd.Flags().BoolVar(&cluster, "cluster", false, "the repo is a cluster helm repo")
+	return cmd
+}
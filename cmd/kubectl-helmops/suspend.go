@@ -0,0 +1,74 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// newSuspendCommand create the suspend command or the resume command when suspend is false
+func newSuspendCommand(o *options, suspend bool) *cobra.Command {
+	var repo, clusterRepo bool
+	use, done, short := "suspend", "suspended", "Suspend the reconcile of a helm operation or the auto update of a helm repo"
+	if !suspend {
+		use, done, short = "resume", "resumed", "Resume a suspended helm operation or helm repo"
+	}
+	cmd := &cobra.Command{
+		Use:   use + " NAME",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			var obj client.Object = &helmopsv1alpha1.HelmOperation{}
+			var key = types.NamespacedName{Namespace: o.namespace(), Name: args[0]}
+			var kind = "helmoperation"
+			if repo {
+				obj = &helmopsv1alpha1.HelmRepo{}
+				kind = "helmrepo"
+			}
+			if clusterRepo {
+				obj = &helmopsv1alpha1.ClusterHelmRepo{}
+				key = types.NamespacedName{Name: args[0]}
+				kind = "clusterhelmrepo"
+			}
+			ctx := context.Background()
+			if err = c.Get(ctx, key, obj); err != nil {
+				return errors.Wrapf(err, "get %s %s error", kind, args[0])
+			}
+			patch := []byte(fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend))
+			if err = c.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+				return errors.Wrapf(err, "%s %s %s error", use, kind, args[0])
+			}
+			fmt.Printf("%s/%s %s\n", kind, args[0], done)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&repo, "repo", false, "the name is a helm repo instead of a helm operation")
+	cmd.Flags().BoolVar(&clusterRepo, "cluster-repo", false, "the name is a cluster helm repo instead of a helm operation")
+	return cmd
+}
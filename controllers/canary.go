@@ -0,0 +1,303 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/analysis"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+const (
+	// defaultCanaryPause the time to wait after the canary step applied if spec.upgradeStrategy.canary.pause not set
+	defaultCanaryPause = time.Minute
+	// defaultCanaryAnalysisTimeout the time to wait for the analysis result if the analysis timeout not set
+	defaultCanaryAnalysisTimeout = 10 * time.Minute
+	// canaryCheckPeriod the period to check the analysis result
+	canaryCheckPeriod = 10 * time.Second
+)
+
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;create;delete
+
+// canaryUpgrade run the canary step of the upgrade: apply the new chart version with the canary values, pause,
+// then run the analysis. return true if the analysis succeeded and the values should be applied, otherwise the
+// reconcile should return with the result
+func (r *HelmOperationReconciler) canaryUpgrade(ctx context.Context, log logr.Logger, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation, rel *release.Release, chartOptions *actions.ChartOpts,
+	fingerprint string) (bool, ctrl.Result, error) {
+	strategy := operation.Spec.UpgradeStrategy.Canary
+	canary := operation.Status.Canary
+	if canary == nil || canary.Fingerprint != fingerprint || canary.Phase == helmopsv1alpha1.CanaryPhaseSucceeded {
+		return false, r.startCanary(ctx, log, kubeClient, operation, rel, chartOptions, fingerprint), nil
+	}
+	now := time.Now()
+	switch canary.Phase {
+	case helmopsv1alpha1.CanaryPhaseFailed:
+		// the canary is not retried until the spec changed, keep assessing the rolled back release
+		return false, r.assessReleaseHealth(ctx, log, kubeClient, operation, rel, ctrl.Result{}), nil
+	case helmopsv1alpha1.CanaryPhasePaused:
+		pause := defaultCanaryPause
+		if strategy.Pause != nil {
+			pause = strategy.Pause.Duration
+		}
+		if remaining := canary.StartTime.Add(pause).Sub(now); remaining > 0 {
+			return false, ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		if strategy.Analysis == nil {
+			return true, ctrl.Result{}, nil
+		}
+		canary.Phase = helmopsv1alpha1.CanaryPhaseAnalyzing
+		canary.AnalysisStartTime = &metav1.Time{Time: now}
+		if strategy.Analysis.Job != nil {
+			canary.JobName = canaryJobName(operation.GetReleaseName(), canary.CanaryRevision)
+		}
+		setCanaryCondition(operation, helmopsv1alpha1.ConditionStatusUnknown, "the canary analysis started")
+		if err := r.Client.Status().Update(ctx, operation); err != nil {
+			return false, ctrl.Result{}, err
+		}
+		// the status is decoded from the response of the update
+		canary = operation.Status.Canary
+	}
+
+	result, err := r.runCanaryAnalysis(ctx, kubeClient, operation)
+	timeout := defaultCanaryAnalysisTimeout
+	if strategy.Analysis.Timeout != nil {
+		timeout = strategy.Analysis.Timeout.Duration
+	}
+	timedOut := now.Sub(canary.AnalysisStartTime.Time) > timeout
+	switch {
+	case result != nil && result.Succeeded:
+		canary.Message = result.Message
+		return true, ctrl.Result{}, nil
+	case result != nil:
+		return false, r.failCanary(ctx, log, kubeClient, operation, result.Message), nil
+	case timedOut:
+		message := fmt.Sprintf("the canary analysis not finished in %s", timeout)
+		if err != nil {
+			message = fmt.Sprintf("%s: %v", message, err)
+		}
+		return false, r.failCanary(ctx, log, kubeClient, operation, message), nil
+	case err != nil:
+		// the analysis is retried until the timeout, e.g. the prometheus is not reachable
+		log.Error(err, "run the canary analysis error")
+		canary.Message = err.Error()
+		if updateErr := r.Client.Status().Update(ctx, operation); updateErr != nil {
+			return false, ctrl.Result{}, updateErr
+		}
+	}
+	return false, ctrl.Result{RequeueAfter: canaryCheckPeriod}, nil
+}
+
+// startCanary upgrade the release to the new chart version with the canary values merged over the values
+func (r *HelmOperationReconciler) startCanary(ctx context.Context, log logr.Logger, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation, rel *release.Release, chartOptions *actions.ChartOpts, fingerprint string) ctrl.Result {
+	var previousRevision = rel.Version
+	if previous := operation.Status.Canary; canaryInProgress(operation) {
+		// the spec changed during the canary, the new canary rollback to the revision before the previous one
+		previousRevision = previous.PreviousRevision
+		r.cleanupCanary(ctx, log, kubeClient, operation)
+	}
+	strategy := operation.Spec.UpgradeStrategy.Canary
+	upgradeOptions := newUpgradeOptions(operation, kubeClient, chartOptions)
+	upgradeOptions.Values = mergeValues(operation.Spec.Values.Object, strategy.Values.Object)
+	canaryRelease, err := runUpgrade(upgradeOptions)
+	if err != nil {
+		log.Error(err, "upgrade release with the canary values error")
+		setReadyFailed(operation, "CanaryUpgradeFailed", err)
+		r.Dispatcher.Notify(operation, helmopsv1alpha1.AlertEventFailed, nil, fmt.Sprintf("canary upgrade failed: %v", err))
+		if updateErr := r.Client.Status().Update(ctx, operation); updateErr != nil {
+			log.Error(updateErr, "update helm operation status error")
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+	operation.Status.Canary = &helmopsv1alpha1.CanaryStatus{
+		Phase:            helmopsv1alpha1.CanaryPhasePaused,
+		Fingerprint:      fingerprint,
+		ChartVersion:     canaryRelease.Chart.Metadata.Version,
+		PreviousRevision: previousRevision,
+		CanaryRevision:   canaryRelease.Version,
+		StartTime:        &metav1.Time{Time: time.Now()},
+	}
+	// the fingerprint is recorded after the values applied
+	markReleaseApplied(operation, canaryRelease, "")
+	recordRevision(ctx, r.Client, r.Scheme, log, operation, canaryRelease, revisionActionCanary, appliedBy(operation))
+	r.Dispatcher.Notify(operation, helmopsv1alpha1.AlertEventUpgraded, canaryRelease, "the canary step applied")
+	operation.Status.CurrentChartVersion = canaryRelease.Chart.Metadata.Version
+	operation.Status.ReleaseStatus = string(canaryRelease.Info.Status)
+	setCanaryCondition(operation, helmopsv1alpha1.ConditionStatusUnknown,
+		fmt.Sprintf("the canary step applied at revision %d, paused before the analysis", canaryRelease.Version))
+	markHealthProgressing(operation, "the canary step applied, waiting for the health assessment")
+	setReadyCondition(operation, canaryRelease)
+	if err = r.Client.Status().Update(ctx, operation); err != nil {
+		log.Error(err, "update helm operation status error")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+	pause := defaultCanaryPause
+	if strategy.Pause != nil {
+		pause = strategy.Pause.Duration
+	}
+	return ctrl.Result{RequeueAfter: pause}
+}
+
+// runCanaryAnalysis run the prometheus query or check the analysis job, return nil result if the job is running
+func (r *HelmOperationReconciler) runCanaryAnalysis(ctx context.Context, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation) (*analysis.Result, error) {
+	spec := operation.Spec.UpgradeStrategy.Canary.Analysis
+	if spec.Prometheus != nil {
+		prometheus := &analysis.Prometheus{
+			Address:   spec.Prometheus.Address,
+			Query:     spec.Prometheus.Query,
+			Operator:  string(spec.Prometheus.Operator),
+			Threshold: spec.Prometheus.Threshold,
+		}
+		return prometheus.Run(ctx)
+	}
+	if spec.Job == nil {
+		return nil, errors.New("one of prometheus or job must be set in the canary analysis")
+	}
+	job, err := newCanaryJob(kubeClient, operation)
+	if err != nil {
+		return nil, err
+	}
+	jobSpec, err := spec.Job.GetJobSpec()
+	if err != nil {
+		return nil, err
+	}
+	job.Spec = *jobSpec
+	if err = job.Start(ctx); err != nil {
+		return nil, err
+	}
+	return job.Check(ctx)
+}
+
+// failCanary rollback the release to the revision before the canary step
+func (r *HelmOperationReconciler) failCanary(ctx context.Context, log logr.Logger, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation, message string) ctrl.Result {
+	canary := operation.Status.Canary
+	log.Info("the canary analysis failed, rollback the release", "revision", canary.PreviousRevision, "message", message)
+	r.cleanupCanary(ctx, log, kubeClient, operation)
+	rel, err := rollbackToRevision(operation, kubeClient, canary.PreviousRevision)
+	if err != nil {
+		// the rollback is retried in the next reconcile
+		log.Error(err, "rollback the canary release error")
+		setReadyFailed(operation, "CanaryRollbackFailed", err)
+		if updateErr := r.Client.Status().Update(ctx, operation); updateErr != nil {
+			log.Error(updateErr, "update helm operation status error")
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+	canary.Phase = helmopsv1alpha1.CanaryPhaseFailed
+	canary.Message = message
+	markReleaseApplied(operation, rel, "")
+	recordRevision(ctx, r.Client, r.Scheme, log, operation, rel, revisionActionCanaryRollback, appliedBy(operation))
+	r.Dispatcher.Notify(operation, helmopsv1alpha1.AlertEventRolledBack, rel,
+		fmt.Sprintf("the canary analysis failed, rolled back to revision %d: %s", canary.PreviousRevision, message))
+	operation.Status.CurrentChartVersion = rel.Chart.Metadata.Version
+	operation.Status.ReleaseStatus = string(rel.Info.Status)
+	setCanaryCondition(operation, helmopsv1alpha1.ConditionStatusFalse, message)
+	setReadyFailed(operation, "CanaryFailed", errors.Errorf("the canary of chart version %s failed: %s", canary.ChartVersion, message))
+	markHealthProgressing(operation, "the canary release rolled back, waiting for the health assessment")
+	if err = r.Client.Status().Update(ctx, operation); err != nil {
+		log.Error(err, "update helm operation status error")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}
+	}
+	return ctrl.Result{RequeueAfter: healthCheckPeriod}
+}
+
+// finishCanary mark the canary in progress succeeded after the values applied
+func (r *HelmOperationReconciler) finishCanary(ctx context.Context, log logr.Logger, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation) {
+	if !canaryInProgress(operation) {
+		return
+	}
+	r.cleanupCanary(ctx, log, kubeClient, operation)
+	canary := operation.Status.Canary
+	canary.Phase = helmopsv1alpha1.CanaryPhaseSucceeded
+	if canary.Message == "" {
+		canary.Message = "the canary step finished without analysis"
+	}
+	setCanaryCondition(operation, helmopsv1alpha1.ConditionStatusTrue, canary.Message)
+}
+
+// cleanupCanary delete the analysis job of the canary
+func (r *HelmOperationReconciler) cleanupCanary(ctx context.Context, log logr.Logger, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation) {
+	if operation.Status.Canary == nil || operation.Status.Canary.JobName == "" {
+		return
+	}
+	job, err := newCanaryJob(kubeClient, operation)
+	if err == nil {
+		err = job.Delete(ctx)
+	}
+	if err != nil {
+		log.Error(err, "delete the canary analysis job error", "job", operation.Status.Canary.JobName)
+	}
+}
+
+// newCanaryJob the analysis job of the canary in the release namespace of the target cluster
+func newCanaryJob(kubeClient *actions.KubernetesClient, operation *helmopsv1alpha1.HelmOperation) (*analysis.Job, error) {
+	config, err := kubeClient.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.Job{
+		Client:    clientset,
+		Namespace: operation.GetReleaseNamespace(),
+		Name:      operation.Status.Canary.JobName,
+		Labels:    map[string]string{helmopsv1alpha1.CanaryAnalysisLabel: operation.GetReleaseName()},
+	}, nil
+}
+
+// canaryJobName the analysis job name of the canary revision, the release name is truncated to keep the name
+// a valid label value
+func canaryJobName(releaseName string, revision int) string {
+	suffix := fmt.Sprintf("-canary-%d", revision)
+	if maxLength := 63 - len(suffix); len(releaseName) > maxLength {
+		releaseName = releaseName[:maxLength]
+	}
+	return releaseName + suffix
+}
+
+// canaryInProgress check the canary step applied and the values not applied yet
+func canaryInProgress(operation *helmopsv1alpha1.HelmOperation) bool {
+	canary := operation.Status.Canary
+	return canary != nil && (canary.Phase == helmopsv1alpha1.CanaryPhasePaused || canary.Phase == helmopsv1alpha1.CanaryPhaseAnalyzing)
+}
+
+func setCanaryCondition(operation *helmopsv1alpha1.HelmOperation, status, message string) {
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeCanary,
+		Status:  status,
+		Reason:  operation.Status.Canary.Phase,
+		Message: message,
+	})
+}
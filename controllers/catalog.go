@@ -0,0 +1,171 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/catalog"
+	"github.com/shijunLee/helmops/pkg/charts"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// NewCatalogServer create the read-only catalog api of the repos synced by the manager, the requests are
+// authenticated by the bearer token and the user must be allowed to get the HelmRepo or ClusterHelmRepo
+func NewCatalogServer(mgr ctrl.Manager, addr, certFile, keyFile string) *catalog.Server {
+	return &catalog.Server{
+		Addr:       addr,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		Source:     repoCatalogSource{client: mgr.GetClient()},
+		Authorizer: kubeCatalogAuthorizer{client: mgr.GetClient()},
+		Log:        ctrl.Log.WithName("catalog"),
+	}
+}
+
+// catalogRepoKey the key of the chart repo in the repo cache
+func catalogRepoKey(ref catalog.RepoRef) repoKey {
+	return repoKey{Kind: ref.Kind, Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// repoCatalogSource serve the charts from the repo cache
+type repoCatalogSource struct {
+	client client.Client
+}
+
+var _ catalog.Source = repoCatalogSource{}
+
+func (s repoCatalogSource) chartRepo(ref catalog.RepoRef) (*charts.ChartRepo, error) {
+	key := catalogRepoKey(ref)
+	chartRepo, ok := getCachedChartRepo(key)
+	if !ok {
+		return nil, errors.Wrapf(catalog.RepoNotFoundErr, "the %s", key)
+	}
+	return chartRepo, nil
+}
+
+// ListCharts implement catalog.Source
+func (s repoCatalogSource) ListCharts(ref catalog.RepoRef) (map[string]utils.CommonChartVersions, error) {
+	chartRepo, err := s.chartRepo(ref)
+	if err != nil {
+		return nil, err
+	}
+	return chartRepo.Operation.ListCharts()
+}
+
+// ChartOptions implement catalog.Source, the dependencies of the charts in git are resolved
+// from the helm repos visible to the namespace of the repo
+func (s repoCatalogSource) ChartOptions(ref catalog.RepoRef, chartName, chartVersion string) (*actions.ChartOpts, error) {
+	chartRepo, err := s.chartRepo(ref)
+	if err != nil {
+		return nil, err
+	}
+	return newRepoChartOptions(chartRepo, chartName, chartVersion,
+		repoCacheResolver{ctx: context.Background(), client: s.client, namespace: ref.Namespace})
+}
+
+// catalogTokenAudience the audience of the tokens accepted by the catalog api, the tokens issued for
+// the kube-apiserver or other services are rejected, e.g. `kubectl create token <sa> --audience helmops-catalog`
+const catalogTokenAudience = "helmops-catalog"
+
+// kubeCatalogAuthorizer authenticate the bearer token with the TokenReview and check the user
+// can get the repo with the SubjectAccessReview
+type kubeCatalogAuthorizer struct {
+	client client.Client
+}
+
+var _ catalog.Authorizer = kubeCatalogAuthorizer{}
+
+// Authorize implement catalog.Authorizer
+func (a kubeCatalogAuthorizer) Authorize(req *http.Request, ref catalog.RepoRef) error {
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return catalog.UnauthenticatedErr
+	}
+	tokenReview := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     strings.TrimPrefix(authorization, "Bearer "),
+			Audiences: []string{catalogTokenAudience},
+		},
+	}
+	if err := a.client.Create(req.Context(), tokenReview); err != nil {
+		return errors.Wrap(err, "create token review error")
+	}
+	if !tokenReview.Status.Authenticated {
+		if tokenReview.Status.Error != "" {
+			return errors.Wrap(catalog.UnauthenticatedErr, tokenReview.Status.Error)
+		}
+		return catalog.UnauthenticatedErr
+	}
+	if !hasAudience(tokenReview.Status.Audiences, catalogTokenAudience) {
+		return errors.Wrapf(catalog.UnauthenticatedErr, "the token is not issued for the audience %s", catalogTokenAudience)
+	}
+	user := tokenReview.Status.User
+	var extra = map[string]authorizationv1.ExtraValue{}
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	var resource = "helmrepos"
+	if ref.Kind == helmopsv1alpha1.ClusterHelmRepoKind {
+		resource = "clusterhelmrepos"
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ref.Namespace,
+				Verb:      "get",
+				Group:     helmopsv1alpha1.GroupVersion.Group,
+				Resource:  resource,
+				Name:      ref.Name,
+			},
+		},
+	}
+	if err := a.client.Create(req.Context(), accessReview); err != nil {
+		return errors.Wrap(err, "create subject access review error")
+	}
+	if !accessReview.Status.Allowed {
+		return errors.Wrapf(catalog.ForbiddenErr, "user %s can not get the %s", user.Username, catalogRepoKey(ref))
+	}
+	return nil
+}
+
+// hasAudience check the authenticated audiences contain the audience
+func hasAudience(audiences []string, audience string) bool {
+	for _, item := range audiences {
+		if item == audience {
+			return true
+		}
+	}
+	return false
+}
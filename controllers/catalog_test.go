@@ -0,0 +1,81 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/shijunLee/helmops/pkg/catalog"
+)
+
+// reviewClient answer the token reviews with the audiences of the token and allow all the access reviews
+type reviewClient struct {
+	client.Client
+	tokenAudiences []string
+	requested      []string
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		c.requested = review.Spec.Audiences
+		// the apiserver authenticate the token if it is issued for one of the requested audiences
+		for _, audience := range c.tokenAudiences {
+			if hasAudience(review.Spec.Audiences, audience) {
+				review.Status.Authenticated = true
+				review.Status.Audiences = []string{audience}
+				review.Status.User = authenticationv1.UserInfo{Username: "reader"}
+			}
+		}
+	case *authorizationv1.SubjectAccessReview:
+		review.Status.Allowed = true
+	}
+	return nil
+}
+
+func Test_kubeCatalogAuthorizerAudience(t *testing.T) {
+	ref := catalog.RepoRef{Kind: catalog.KindHelmRepo, Namespace: "team-a", Name: "stable"}
+	tests := []struct {
+		name           string
+		tokenAudiences []string
+		wantErr        error
+	}{
+		{name: "catalog token", tokenAudiences: []string{catalogTokenAudience}},
+		{name: "apiserver token", tokenAudiences: []string{"https://kubernetes.default.svc"}, wantErr: catalog.UnauthenticatedErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews := &reviewClient{tokenAudiences: tt.tokenAudiences}
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/namespaces/team-a/helmrepos/stable/charts", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			err := kubeCatalogAuthorizer{client: reviews}.Authorize(req, ref)
+			if errors.Cause(err) != tt.wantErr {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(reviews.requested) != 1 || reviews.requested[0] != catalogTokenAudience {
+				t.Errorf("expect the token reviewed for the audience %s, got %v", catalogTokenAudience, reviews.requested)
+			}
+		})
+	}
+}
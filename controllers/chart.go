@@ -0,0 +1,186 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/charts"
+	"github.com/shijunLee/helmops/pkg/charts/archive"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+const (
+	dependencyCacheDirName = ".dependencies"
+)
+
+var (
+	// dependencyCacheDir the cache dir of the dependency charts of the charts in git,
+	// the dir name can not conflict with the git repos which cached in the dirs of the repo kinds
+	dependencyCacheDir string
+)
+
+// resolveChartOptions find the chart of the helm operation from the repo cache,
+// the helm operation must be allowed to use the chart repo and the digest of the chart version must be allowed
+func resolveChartOptions(ctx context.Context, c client.Client, operation *helmopsv1alpha1.HelmOperation) (*actions.ChartOpts, error) {
+	key := operationRepoKey(operation)
+	if err := checkRepoAccess(ctx, c, operation.Namespace, key); err != nil {
+		return nil, err
+	}
+	chartRepo, ok := getCachedChartRepo(key)
+	if !ok {
+		return nil, errors.Errorf("the %s not found or not synced", key)
+	}
+	chartOptions, err := newRepoChartOptions(chartRepo, operation.Spec.ChartName, desiredChartVersion(operation),
+		repoCacheResolver{ctx: ctx, client: c, namespace: operation.Namespace})
+	if err != nil {
+		return nil, err
+	}
+	if err = checkChartDigest(ctx, c, operation, key, chartOptions); err != nil {
+		return nil, err
+	}
+	return chartOptions, nil
+}
+
+// newRepoChartOptions create the chart options of the chart version in the chart repo,
+// the dependencies of the charts in git are resolved from the helm repos with the resolver
+func newRepoChartOptions(chartRepo *charts.ChartRepo, chartName, chartVersion string,
+	resolver actions.NamedRepoResolver) (*actions.ChartOpts, error) {
+	if !chartRepo.Operation.CheckChartExist(chartName, chartVersion) {
+		return nil, errors.Errorf("chart %s version %s not found in repo %s", chartName, chartVersion, chartRepo.Name)
+	}
+	url, pathType, err := chartRepo.Operation.GetChartVersionUrl(chartName, chartVersion)
+	if err != nil {
+		return nil, err
+	}
+	chartOptions := &actions.ChartOpts{
+		ChartName:             chartName,
+		ChartVersion:          chartVersion,
+		InsecureSkipTLSVerify: chartRepo.InsecureSkipTLS,
+		Transport:             chartRepo.Transport,
+		DependencyCacheDir:    dependencyCacheDir,
+		DependencyResolver:    resolver,
+	}
+	switch pathType {
+	case "file":
+		chartOptions.LocalPath = url
+		if localRepo, ok := chartRepo.Operation.(charts.LocalRepo); ok {
+			chartOptions.LocalRootPath = localRepo.RootPath()
+		}
+	case "http":
+		chartOptions.ChartURL = url
+	case archive.PathType:
+		archiveRepo, ok := chartRepo.Operation.(charts.ArchiveRepo)
+		if !ok {
+			return nil, errors.Errorf("repo %s not support chart archive", chartRepo.Name)
+		}
+		if chartOptions.ChartArchive, err = archiveRepo.ChartArchive(chartName, chartVersion); err != nil {
+			return nil, err
+		}
+	}
+	if chartVersions, err := chartRepo.Operation.ListCharts(); err == nil {
+		for _, item := range chartVersions[chartName] {
+			if item.Version == chartVersion {
+				chartOptions.Digest = item.Digest
+			}
+		}
+	}
+	return chartOptions, nil
+}
+
+// getCachedChartRepo get the chart repo of the helm repo from the repo cache
+func getCachedChartRepo(key repoKey) (*charts.ChartRepo, bool) {
+	repoInfo, ok := repoCache.Load(key.String())
+	if !ok {
+		return nil, false
+	}
+	chartRepo, ok := repoInfo.(*charts.ChartRepo)
+	return chartRepo, ok
+}
+
+// repoCacheResolver resolve the named dependency repositories of the charts with the helm repos,
+// the name reference the HelmRepo in the namespace first, then the ClusterHelmRepo allowed in the namespace
+type repoCacheResolver struct {
+	ctx       context.Context
+	client    client.Client
+	namespace string
+}
+
+var _ actions.NamedRepoResolver = repoCacheResolver{}
+
+// findChartRepo find the visible chart repo by name
+func (r repoCacheResolver) findChartRepo(repoName string) (*charts.ChartRepo, bool) {
+	key := repoKey{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: r.namespace, Name: repoName}
+	if chartRepo, ok := getCachedChartRepo(key); ok {
+		return chartRepo, true
+	}
+	key = repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: repoName}
+	if err := checkRepoAccess(r.ctx, r.client, r.namespace, key); err != nil {
+		return nil, false
+	}
+	return getCachedChartRepo(key)
+}
+
+// RepoURL implement actions.NamedRepoResolver
+func (r repoCacheResolver) RepoURL(repoName string) (string, bool) {
+	chartRepo, ok := r.findChartRepo(repoName)
+	if !ok {
+		return "", false
+	}
+	return chartRepo.URL, true
+}
+
+// ResolveChart implement actions.NamedRepoResolver, find the latest chart version match the constraint
+func (r repoCacheResolver) ResolveChart(repoName, chartName, versionConstraint string) (*actions.ChartOpts, error) {
+	chartRepo, ok := r.findChartRepo(repoName)
+	if !ok {
+		return nil, errors.Errorf("helm repo %s not found", repoName)
+	}
+	if versionConstraint == "" {
+		version, err := chartRepo.Operation.GetChartLastVersion(chartName)
+		if err != nil {
+			return nil, err
+		}
+		return newRepoChartOptions(chartRepo, chartName, version, r)
+	}
+	if chartRepo.Operation.CheckChartExist(chartName, versionConstraint) {
+		return newRepoChartOptions(chartRepo, chartName, versionConstraint, r)
+	}
+	chartVersions, err := chartRepo.Operation.ListCharts()
+	if err != nil {
+		return nil, err
+	}
+	var matched string
+	for _, item := range chartVersions[chartName] {
+		ok, err := utils.CheckVersionConstraint(item.Version, versionConstraint)
+		if err != nil {
+			return nil, err
+		}
+		if ok && (matched == "" || utils.GetVersionGreaterThan(item.Version, matched)) {
+			matched = item.Version
+		}
+	}
+	if matched == "" {
+		return nil, errors.Errorf("chart %s version %s not found in helm repo %s", chartName, versionConstraint, repoName)
+	}
+	return newRepoChartOptions(chartRepo, chartName, matched, r)
+}
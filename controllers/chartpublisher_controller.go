@@ -0,0 +1,171 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/charts/git"
+	"github.com/shijunLee/helmops/pkg/charts/publisher"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+// defaultPublishInterval the default interval to check the chart version in git
+const defaultPublishInterval = time.Minute
+
+// ChartPublisherReconciler reconciles a ChartPublisher object
+type ChartPublisherReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=chartpublishers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=chartpublishers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=chartpublishers/finalizers,verbs=update
+
+// Reconcile check the chart version in the git path of the source repo every interval, the chart is linted,
+// packaged and pushed to the target repo when the version not exist in the target repo.
+func (r *ChartPublisherReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("chartpublisher", req.NamespacedName)
+
+	chartPublisher := &helmopsv1alpha1.ChartPublisher{}
+	err := r.Client.Get(ctx, req.NamespacedName, chartPublisher)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "find chart publisher resource from client error")
+		return ctrl.Result{}, err
+	}
+	if !chartPublisher.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	var status = &chartPublisher.Status
+	status.Suspension, status.Conditions = suspendStatus(chartPublisher, chartPublisher.Spec.Suspend, status.Conditions)
+	if chartPublisher.Spec.Suspend {
+		status.ObservedGeneration = chartPublisher.Generation
+		return ctrl.Result{}, r.Client.Status().Update(ctx, chartPublisher)
+	}
+
+	result, err := r.publish(ctx, chartPublisher)
+	var condition = helmopsv1alpha1.Condition{
+		Type:   helmopsv1alpha1.ConditionTypePublished,
+		Status: helmopsv1alpha1.ConditionStatusTrue,
+	}
+	switch {
+	case err != nil:
+		log.Error(err, "publish chart error")
+		condition.Status = helmopsv1alpha1.ConditionStatusFalse
+		condition.Reason = "PublishFailed"
+		if _, ok := errors.Cause(err).(*publisher.LintError); ok {
+			condition.Reason = "LintFailed"
+		}
+		condition.Message = err.Error()
+	case result.Published:
+		log.Info("chart pushed to the target repo", "chart", result.Name, "version", result.Version)
+		condition.Reason = "Published"
+		condition.Message = fmt.Sprintf("chart %s version %s pushed to the %s", result.Name, result.Version,
+			chartRepoKey(chartPublisher.GetTargetRef()))
+		status.AddPublishedVersion(helmopsv1alpha1.PublishedChartVersion{
+			Version:       result.Version,
+			Digest:        result.Digest,
+			PublishedTime: metav1.Now(),
+		})
+	default:
+		condition.Reason = "UpToDate"
+		condition.Message = fmt.Sprintf("chart %s version %s already exist in the %s", result.Name, result.Version,
+			chartRepoKey(chartPublisher.GetTargetRef()))
+	}
+	if result != nil {
+		status.ChartName = result.Name
+		status.LastCheckedVersion = result.Version
+	}
+	status.Conditions = helmopsv1alpha1.SetCondition(status.Conditions, condition)
+	status.ObservedGeneration = chartPublisher.Generation
+	now := metav1.Now()
+	status.LastCheckTime = &now
+	if err = r.Client.Status().Update(ctx, chartPublisher); err != nil {
+		return ctrl.Result{}, err
+	}
+	var interval = defaultPublishInterval
+	if chartPublisher.Spec.Interval != nil {
+		interval = chartPublisher.Spec.Interval.Duration
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// publish push the chart in the git path of the source repo to the target repo,
+// the source and target repos must be synced and visible to the namespace of the publisher
+func (r *ChartPublisherReconciler) publish(ctx context.Context, chartPublisher *helmopsv1alpha1.ChartPublisher) (*publisher.Result, error) {
+	sourceKey, targetKey := chartRepoKey(chartPublisher.GetSourceRef()), chartRepoKey(chartPublisher.GetTargetRef())
+	for _, key := range []repoKey{sourceKey, targetKey} {
+		if err := checkRepoAccess(ctx, r.Client, chartPublisher.Namespace, key); err != nil {
+			return nil, err
+		}
+	}
+	sourceRepo, ok := getCachedChartRepo(sourceKey)
+	if !ok {
+		return nil, errors.Errorf("the %s not found or not synced", sourceKey)
+	}
+	gitRepo, ok := sourceRepo.Operation.(*git.Repo)
+	if !ok {
+		return nil, errors.Errorf("the source %s is not a Git repo", sourceKey)
+	}
+	targetRepo, ok := getCachedChartRepo(targetKey)
+	if !ok {
+		return nil, errors.Errorf("the %s not found or not synced", targetKey)
+	}
+	target, ok := targetRepo.Operation.(publisher.Target)
+	if !ok {
+		return nil, errors.Errorf("the target %s is not a ChartMuseum repo", targetKey)
+	}
+	chartPath, err := gitRepo.ChartPath(chartPublisher.Spec.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "find chart path %s in the %s error", chartPublisher.Spec.Path, sourceKey)
+	}
+	p := &publisher.Publisher{
+		Namespace:          chartPublisher.Namespace,
+		DependencyCacheDir: dependencyCacheDir,
+		Resolver:           repoCacheResolver{ctx: ctx, client: r.Client, namespace: chartPublisher.Namespace},
+	}
+	if !chartPublisher.Spec.Lint.Disabled {
+		p.Lint = &actions.LintOptions{Strict: chartPublisher.Spec.Lint.Strict}
+	}
+	return p.Publish(chartPath, target)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ChartPublisherReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&helmopsv1alpha1.ChartPublisher{}).
+		// the status updates not trigger the publish, the git path is checked every interval
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// ClusterHelmRepoReconciler reconciles a ClusterHelmRepo object,
+// the repo sync jobs and the auto update workers are shared with the HelmRepoReconciler
+type ClusterHelmRepoReconciler struct {
+	*HelmRepoReconciler
+}
+
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=clusterhelmrepos,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=clusterhelmrepos/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=clusterhelmrepos/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// Reconcile start the sync job of the ClusterHelmRepo
+func (r *ClusterHelmRepoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("clusterhelmrepo", req.Name)
+	clusterHelmRepo := &helmopsv1alpha1.ClusterHelmRepo{}
+	err := r.Client.Get(ctx, req.NamespacedName, clusterHelmRepo)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "find repo resource from client error", "ResourceName", req.Name)
+		return ctrl.Result{}, err
+	}
+	key := repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: clusterHelmRepo.Name}
+	return r.reconcileRepo(ctx, log, clusterHelmRepo, &clusterHelmRepo.Spec.HelmRepoSpec, &clusterHelmRepo.Status, key)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterHelmRepoReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&helmopsv1alpha1.ClusterHelmRepo{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+// dependencyRequeuePeriod the period to recheck the dependencies which not ready,
+// the dependency status change also trigger the reconcile
+const dependencyRequeuePeriod = 30 * time.Second
+
+// checkDependencies check all the dependencies of the helm operation are ready and set the DependenciesReady condition,
+// return the message of the first blocking dependency, empty if all the dependencies are ready
+func checkDependencies(ctx context.Context, c client.Client, operation *helmopsv1alpha1.HelmOperation) (string, error) {
+	if len(operation.Spec.DependsOn) == 0 {
+		return "", nil
+	}
+	for _, dependency := range operation.Spec.DependsOn {
+		message, err := checkDependency(ctx, c, operation, dependency)
+		if err != nil {
+			return "", err
+		}
+		if message != "" {
+			operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+				Type:    helmopsv1alpha1.ConditionTypeDependenciesReady,
+				Status:  helmopsv1alpha1.ConditionStatusFalse,
+				Reason:  "DependencyNotReady",
+				Message: message,
+			})
+			return message, nil
+		}
+	}
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeDependenciesReady,
+		Status:  helmopsv1alpha1.ConditionStatusTrue,
+		Reason:  "DependenciesReady",
+		Message: fmt.Sprintf("%d dependencies are ready", len(operation.Spec.DependsOn)),
+	})
+	return "", nil
+}
+
+// checkDependency return the reason message if the dependency is not ready
+func checkDependency(ctx context.Context, c client.Client, operation *helmopsv1alpha1.HelmOperation,
+	dependency helmopsv1alpha1.DependencyReference) (string, error) {
+	key := operation.GetDependencyKey(dependency)
+	item := &helmopsv1alpha1.HelmOperation{}
+	if err := c.Get(ctx, key, item); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Sprintf("dependency %s not found", key.String()), nil
+		}
+		return "", err
+	}
+	ready := helmopsv1alpha1.GetCondition(item.Status.Conditions, helmopsv1alpha1.ConditionTypeReady)
+	if ready == nil || ready.Status != helmopsv1alpha1.ConditionStatusTrue {
+		return fmt.Sprintf("dependency %s is not ready", key.String()), nil
+	}
+	var currentVersion = item.Status.CurrentChartVersion
+	if dependency.ChartVersion != "" {
+		match, err := utils.CheckVersionConstraint(currentVersion, dependency.ChartVersion)
+		if err != nil || !match {
+			return fmt.Sprintf("dependency %s chart version %s not match %s", key.String(), currentVersion, dependency.ChartVersion), nil
+		}
+		return "", nil
+	}
+	// the auto update may upgrade the release great than the spec chart version
+	if currentVersion != item.Spec.ChartVersion && !utils.GetVersionGreaterThan(currentVersion, item.Spec.ChartVersion) {
+		return fmt.Sprintf("dependency %s chart version %s not upgraded to %s", key.String(), currentVersion, item.Spec.ChartVersion), nil
+	}
+	return "", nil
+}
+
+// mapDependencyToOperations enqueue the helm operations which depend on the changed helm operation
+func (r *HelmOperationReconciler) mapDependencyToOperations(obj client.Object) []reconcile.Request {
+	var operationList = &helmopsv1alpha1.HelmOperationList{}
+	if err := r.Client.List(context.Background(), operationList); err != nil {
+		r.Log.Error(err, "list helm operations error")
+		return nil
+	}
+	var key = types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	var result []reconcile.Request
+	for i := range operationList.Items {
+		item := &operationList.Items[i]
+		for _, dependency := range item.Spec.DependsOn {
+			if item.GetDependencyKey(dependency) == key {
+				result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}})
+				break
+			}
+		}
+	}
+	return result
+}
+
+// waitForDependencies return true if the install or upgrade must wait for the dependencies,
+// the blocking dependency is recorded in the status
+func waitForDependencies(ctx context.Context, c client.Client, log logr.Logger, operation *helmopsv1alpha1.HelmOperation) (bool, error) {
+	message, err := checkDependencies(ctx, c, operation)
+	if err != nil {
+		return true, err
+	}
+	if message == "" {
+		return false, nil
+	}
+	log.Info("wait for the dependencies ready", "helmoperation",
+		types.NamespacedName{Namespace: operation.Namespace, Name: operation.Name}, "message", message)
+	return true, c.Status().Update(ctx, operation)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+// repoDigests the digests of the chart versions at the last sync of each repo, the digest changes are
+// detected from the second sync after the manager started
+var repoDigests sync.Map
+
+// chartDigests the digests of the loaded charts for the repos which index not provide the digests,
+// the map of the chart versions is stored by the repo key and removed after each sync of the repo,
+// so the changed charts in the git or the local path are loaded again
+var chartDigests sync.Map
+
+// cachedChartDigest get the digest of the loaded chart version of the repo
+func cachedChartDigest(key repoKey, chartName, chartVersion string) (string, bool) {
+	versions, ok := chartDigests.Load(key.String())
+	if !ok {
+		return "", false
+	}
+	digest, ok := versions.(*sync.Map).Load(chartName + "/" + chartVersion)
+	if !ok {
+		return "", false
+	}
+	return digest.(string), true
+}
+
+// storeChartDigest cache the digest of the loaded chart version of the repo
+func storeChartDigest(key repoKey, chartName, chartVersion, digest string) {
+	versions, _ := chartDigests.LoadOrStore(key.String(), &sync.Map{})
+	versions.(*sync.Map).Store(chartName+"/"+chartVersion, digest)
+}
+
+// chartVersionKey the key of the chart version in the digests
+func chartVersionKey(chartName, version string) string {
+	return chartName + "@" + version
+}
+
+// detectDigestChanges compare the digests of the chart versions with the last sync, return the changed digests
+// for the repo status and post the DigestChanged events of the new changes to the helm operations which use the versions.
+// the version is removed from the changed digests when the digest restored or the version removed from the repo
+func (r *HelmRepoReconciler) detectDigestChanges(ctx context.Context, key repoKey, policy helmopsv1alpha1.DigestChangePolicy,
+	previous []helmopsv1alpha1.ChangedChartDigest, chartVersions map[string]utils.CommonChartVersions,
+	now metav1.Time) []helmopsv1alpha1.ChangedChartDigest {
+	var digests = map[string]string{}
+	for name, versions := range chartVersions {
+		for _, item := range versions {
+			if item.Digest != "" {
+				digests[chartVersionKey(name, item.Version)] = actions.NormalizeDigest(item.Digest)
+			}
+		}
+	}
+	last, ok := repoDigests.Load(key.String())
+	repoDigests.Store(key.String(), digests)
+	if policy == "" || policy == helmopsv1alpha1.DigestChangePolicyIgnore {
+		return nil
+	}
+	var result, detected []helmopsv1alpha1.ChangedChartDigest
+	var known = map[string]bool{}
+	for _, item := range previous {
+		versionKey := chartVersionKey(item.Name, item.Version)
+		digest, exist := digests[versionKey]
+		if !exist || digest == item.OriginalDigest {
+			continue
+		}
+		known[versionKey] = true
+		if digest != item.Digest {
+			item.Digest = digest
+			item.DetectedTime = &now
+			detected = append(detected, item)
+		}
+		result = append(result, item)
+	}
+	if ok {
+		var changed []helmopsv1alpha1.ChangedChartDigest
+		for name, versions := range chartVersions {
+			for _, item := range versions {
+				versionKey := chartVersionKey(name, item.Version)
+				lastDigest, exist := last.(map[string]string)[versionKey]
+				if known[versionKey] || !exist || digests[versionKey] == "" || lastDigest == digests[versionKey] {
+					continue
+				}
+				changed = append(changed, helmopsv1alpha1.ChangedChartDigest{Name: name, Version: item.Version,
+					OriginalDigest: lastDigest, Digest: digests[versionKey], DetectedTime: &now})
+			}
+		}
+		sort.Slice(changed, func(i, j int) bool {
+			return chartVersionKey(changed[i].Name, changed[i].Version) < chartVersionKey(changed[j].Name, changed[j].Version)
+		})
+		result = append(result, changed...)
+		detected = append(detected, changed...)
+	}
+	if len(result) > helmopsv1alpha1.MaxChangedDigests {
+		result = result[len(result)-helmopsv1alpha1.MaxChangedDigests:]
+	}
+	if len(detected) > 0 {
+		r.notifyDigestChanges(ctx, key, policy, detected)
+	}
+	return result
+}
+
+// notifyDigestChanges post the DigestChanged events to the helm operations which use the changed chart versions
+func (r *HelmRepoReconciler) notifyDigestChanges(ctx context.Context, key repoKey, policy helmopsv1alpha1.DigestChangePolicy,
+	changed []helmopsv1alpha1.ChangedChartDigest) {
+	var operationList = &helmopsv1alpha1.HelmOperationList{}
+	if err := r.List(ctx, operationList, client.InNamespace(key.Namespace)); err != nil {
+		r.Log.Error(err, "list helm operation error")
+		return
+	}
+	for _, item := range changed {
+		r.Log.Info("the digest of the chart version changed", "repo", key.String(), "chart", item.Name,
+			"version", item.Version, "originalDigest", item.OriginalDigest, "digest", item.Digest)
+		message := fmt.Sprintf("chart %s version %s digest changed from %s to %s in the %s", item.Name, item.Version,
+			item.OriginalDigest, item.Digest, key)
+		if policy == helmopsv1alpha1.DigestChangePolicyBlock {
+			message += ", the version is blocked"
+		}
+		for i := range operationList.Items {
+			operation := &operationList.Items[i]
+			if operationRepoKey(operation) == key && operation.Spec.ChartName == item.Name &&
+				desiredChartVersion(operation) == item.Version {
+				r.Dispatcher.Notify(operation, helmopsv1alpha1.AlertEventDigestChanged, nil, message)
+			}
+		}
+	}
+}
+
+// checkChartDigest set the digest which the chart archive must have, the digest pinned by the helm operation take
+// precedence over the digest change policy of the repo, the version which digest changed is blocked by the Block policy
+func checkChartDigest(ctx context.Context, c client.Client, operation *helmopsv1alpha1.HelmOperation, key repoKey,
+	chartOptions *actions.ChartOpts) error {
+	chartOptions.ExpectedDigest = expectedChartDigest(operation, chartOptions.ChartVersion)
+	if chartOptions.ExpectedDigest != "" {
+		return chartOptions.CheckDigest()
+	}
+	changed, err := blockedDigestChange(ctx, c, key, chartOptions.ChartName, chartOptions.ChartVersion)
+	if err != nil {
+		return err
+	}
+	if changed != nil {
+		return errors.Wrapf(actions.ChartDigestMismatchErr, "chart %s version %s digest changed from %s to %s in the %s, "+
+			"set spec.chartDigest to deploy the changed version", changed.Name, changed.Version, changed.OriginalDigest,
+			changed.Digest, key)
+	}
+	return nil
+}
+
+// blockedDigestChange find the changed digest of the chart version if the repo block the changed versions,
+// return nil if the version is not blocked
+func blockedDigestChange(ctx context.Context, c client.Client, key repoKey, chartName,
+	version string) (*helmopsv1alpha1.ChangedChartDigest, error) {
+	_, spec, status, err := getRepoObject(ctx, c, key)
+	if err != nil {
+		return nil, err
+	}
+	if spec.DigestChangePolicy != helmopsv1alpha1.DigestChangePolicyBlock {
+		return nil, nil
+	}
+	return status.GetChangedDigest(chartName, version), nil
+}
+
+// expectedChartDigest the digest of the chart version pinned by spec.chartDigest or spec.pinChartDigest,
+// return empty if the version is not pinned
+func expectedChartDigest(operation *helmopsv1alpha1.HelmOperation, chartVersion string) string {
+	if operation.Spec.ChartDigest != "" && chartVersion == operation.Spec.ChartVersion {
+		return operation.Spec.ChartDigest
+	}
+	pinned := operation.Status.PinnedChartDigest
+	if operation.Spec.PinChartDigest && pinned != nil && pinned.ChartVersion == chartVersion {
+		return pinned.Digest
+	}
+	return ""
+}
+
+// pinChartDigest record the digest of the chart archive which applied if spec.pinChartDigest set,
+// the digest is recorded once for each chart version
+func pinChartDigest(operation *helmopsv1alpha1.HelmOperation, chartOptions *actions.ChartOpts) {
+	if !operation.Spec.PinChartDigest || chartOptions.Digest == "" {
+		return
+	}
+	if pinned := operation.Status.PinnedChartDigest; pinned != nil && pinned.ChartVersion == chartOptions.ChartVersion {
+		return
+	}
+	now := metav1.Now()
+	operation.Status.PinnedChartDigest = &helmopsv1alpha1.PinnedChartDigest{
+		ChartVersion: chartOptions.ChartVersion,
+		Digest:       actions.NormalizeDigest(chartOptions.Digest),
+		PinnedTime:   &now,
+	}
+}
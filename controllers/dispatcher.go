@@ -0,0 +1,223 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/notifier"
+)
+
+const (
+	// eventQueueSize the events are dropped when the queue is full, the reconcile never blocked by the webhooks
+	eventQueueSize = 100
+	// defaultAlertTemplate the message template of the alert which not set the template
+	defaultAlertTemplate = "[{{ .Type }}] {{ .Namespace }}/{{ .Name }} {{ .ChartName }} {{ .ChartVersion }}{{ if .Message }}: {{ .Message }}{{ end }}"
+)
+
+// alertEvent the event with the labels of the helm operation for the alert selectors
+type alertEvent struct {
+	event  notifier.Event
+	labels map[string]string
+}
+
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=alerts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=notificationproviders,verbs=get;list;watch
+
+// EventDispatcher post the release lifecycle events of the helm operations to the providers of the matched alerts,
+// the events are posted in the background so the reconcile not blocked by the webhooks
+type EventDispatcher struct {
+	client  client.Client
+	log     logr.Logger
+	events  chan alertEvent
+	limiter *notifier.RateLimiter
+	// newVersions the last new version notified for each helm operation
+	newVersions sync.Map
+}
+
+// NewEventDispatcher create the dispatcher, the same message of an alert is posted once in the rate limit interval
+func NewEventDispatcher(c client.Client, log logr.Logger, rateLimitInterval time.Duration) *EventDispatcher {
+	return &EventDispatcher{
+		client:  c,
+		log:     log,
+		events:  make(chan alertEvent, eventQueueSize),
+		limiter: notifier.NewRateLimiter(rateLimitInterval),
+	}
+}
+
+// Start post the queued events until the context done, implement manager.Runnable
+func (d *EventDispatcher) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case item := <-d.events:
+			d.dispatch(ctx, item)
+		}
+	}
+}
+
+// Notify queue the event of the helm operation, the release is nil if the action failed
+func (d *EventDispatcher) Notify(operation *helmopsv1alpha1.HelmOperation, eventType string, rel *release.Release, message string) {
+	if d == nil {
+		return
+	}
+	event := notifier.Event{
+		Type:         eventType,
+		Namespace:    operation.Namespace,
+		Name:         operation.Name,
+		ChartName:    operation.Spec.ChartName,
+		ChartVersion: desiredChartVersion(operation),
+		Message:      message,
+		Timestamp:    time.Now(),
+	}
+	if rel != nil {
+		event.Revision = rel.Version
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			event.ChartVersion = rel.Chart.Metadata.Version
+		}
+	}
+	d.enqueue(operation, event)
+}
+
+// NotifyNewVersion queue the event of the new chart version, each version is notified once for the helm operation
+func (d *EventDispatcher) NotifyNewVersion(operation *helmopsv1alpha1.HelmOperation, version string) {
+	if d == nil {
+		return
+	}
+	key := types.NamespacedName{Namespace: operation.Namespace, Name: operation.Name}.String()
+	if last, ok := d.newVersions.Load(key); ok && last == version {
+		return
+	}
+	d.newVersions.Store(key, version)
+	d.enqueue(operation, notifier.Event{
+		Type:         helmopsv1alpha1.AlertEventNewVersion,
+		Namespace:    operation.Namespace,
+		Name:         operation.Name,
+		ChartName:    operation.Spec.ChartName,
+		ChartVersion: version,
+		Message:      fmt.Sprintf("new version %s found, the release run %s", version, desiredChartVersion(operation)),
+		Timestamp:    time.Now(),
+	})
+}
+
+func (d *EventDispatcher) enqueue(operation *helmopsv1alpha1.HelmOperation, event notifier.Event) {
+	select {
+	case d.events <- alertEvent{event: event, labels: operation.GetLabels()}:
+	default:
+		d.log.Info("the event queue is full, drop the event", "type", event.Type,
+			"helmoperation", fmt.Sprintf("%s/%s", event.Namespace, event.Name))
+	}
+}
+
+// dispatch post the event to the providers of the alerts which select the helm operation
+func (d *EventDispatcher) dispatch(ctx context.Context, item alertEvent) {
+	var alertList = &helmopsv1alpha1.AlertList{}
+	if err := d.client.List(ctx, alertList, client.InNamespace(item.event.Namespace)); err != nil {
+		d.log.Error(err, "list alerts error", "namespace", item.event.Namespace)
+		return
+	}
+	for i := range alertList.Items {
+		alert := &alertList.Items[i]
+		if alert.Spec.Suspend || !alert.HasEventType(item.event.Type) {
+			continue
+		}
+		if alert.Spec.Selector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(alert.Spec.Selector)
+			if err != nil || !selector.Matches(labels.Set(item.labels)) {
+				continue
+			}
+		}
+		message, err := renderAlertMessage(alert, item.event)
+		if err != nil {
+			d.log.Error(err, "render alert message error", "alert", alert.Name)
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%s/%s/%s", alert.Namespace, alert.Name, item.event.Name, item.event.Type, message)
+		if !d.limiter.Allow(key) {
+			continue
+		}
+		if err = d.post(ctx, alert, item.event, message); err != nil {
+			d.log.Error(err, "post alert message error", "alert", alert.Name, "provider", alert.Spec.ProviderRef.Name)
+		}
+	}
+}
+
+// post the message to the provider of the alert
+func (d *EventDispatcher) post(ctx context.Context, alert *helmopsv1alpha1.Alert, event notifier.Event, message string) error {
+	provider := &helmopsv1alpha1.NotificationProvider{}
+	err := d.client.Get(ctx, types.NamespacedName{Namespace: alert.Namespace, Name: alert.Spec.ProviderRef.Name}, provider)
+	if err != nil {
+		return err
+	}
+	if provider.Spec.Suspend {
+		return nil
+	}
+	opts := notifier.Options{
+		Address:  provider.Spec.Address,
+		Channel:  provider.Spec.Channel,
+		Username: provider.Spec.Username,
+	}
+	if provider.Spec.SecretRef != nil {
+		secret := &corev1.Secret{}
+		err = d.client.Get(ctx, types.NamespacedName{Namespace: provider.Namespace, Name: provider.Spec.SecretRef.Name}, secret)
+		if err != nil {
+			return errors.Wrapf(err, "get the secret of the provider %s error", provider.Name)
+		}
+		if address, ok := secret.Data[helmopsv1alpha1.NotificationAddressSecretKey]; ok {
+			opts.Address = string(address)
+		}
+		opts.HMACKey = secret.Data[helmopsv1alpha1.NotificationHMACKeySecretKey]
+	}
+	n, err := notifier.New(string(provider.Spec.Type), opts)
+	if err != nil {
+		return err
+	}
+	return n.Post(ctx, event, message)
+}
+
+// renderAlertMessage render the message of the event with the template of the alert
+func renderAlertMessage(alert *helmopsv1alpha1.Alert, event notifier.Event) (string, error) {
+	text := alert.Spec.Template
+	if text == "" {
+		text = defaultAlertTemplate
+	}
+	tmpl, err := template.New(alert.Name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"helm.sh/helm/v3/pkg/release"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+// desiredChartVersion the chart version which the release should run,
+// the version auto updated from the repo is kept when it is greater than the spec version
+func desiredChartVersion(operation *helmopsv1alpha1.HelmOperation) string {
+	var version = operation.Spec.ChartVersion
+	if utils.GetVersionGreaterThan(operation.Status.CurrentChartVersion, version) {
+		version = operation.Status.CurrentChartVersion
+	}
+	if operation.Spec.AutoUpdate && utils.GetVersionGreaterThan(operation.Status.AutoUpdateChartVersion, version) {
+		version = operation.Status.AutoUpdateChartVersion
+	}
+	return version
+}
+
+// releaseFingerprint the canonical hash of the desired state of the release, the values are hashed with
+// the json encoding so the numbers decoded as int or float64 have the same fingerprint.
+// the chart in the chart options is loaded if the repo not provide the digest of the chart and
+// the digest not cached since the last sync of the repo
+func releaseFingerprint(operation *helmopsv1alpha1.HelmOperation, chartOptions *actions.ChartOpts) (string, error) {
+	digest := chartOptions.Digest
+	key := operationRepoKey(operation)
+	if digest == "" {
+		digest, _ = cachedChartDigest(key, chartOptions.ChartName, chartOptions.ChartVersion)
+	}
+	if digest == "" {
+		c, err := chartOptions.LoadChart()
+		if err != nil {
+			return "", err
+		}
+		// keep the loaded chart for the helm action, the chart not load twice
+		chartOptions.Chart = c
+		if digest, err = chartDigest(c); err != nil {
+			return "", err
+		}
+		storeChartDigest(key, chartOptions.ChartName, chartOptions.ChartVersion, digest)
+	}
+	values, err := canonicalValues(operation.Spec.Values.Object)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(struct {
+		Repo               helmopsv1alpha1.ChartRepoReference `json:"repo"`
+		ChartName          string                             `json:"chartName"`
+		ChartVersion       string                             `json:"chartVersion"`
+		ChartDigest        string                             `json:"chartDigest"`
+		Values             json.RawMessage                    `json:"values"`
+		ReleaseName        string                             `json:"releaseName"`
+		ReleaseNamespace   string                             `json:"releaseNamespace"`
+		ServiceAccountName string                             `json:"serviceAccountName"`
+		Create             helmopsv1alpha1.Create             `json:"create"`
+		Upgrade            helmopsv1alpha1.Upgrade            `json:"upgrade"`
+	}{
+		Repo:               operation.GetChartRepoRef(),
+		ChartName:          chartOptions.ChartName,
+		ChartVersion:       chartOptions.ChartVersion,
+		ChartDigest:        digest,
+		Values:             values,
+		ReleaseName:        operation.GetReleaseName(),
+		ReleaseNamespace:   operation.GetReleaseNamespace(),
+		ServiceAccountName: operation.Spec.ServiceAccountName,
+		Create:             operation.Spec.Create,
+		Upgrade:            operation.Spec.Upgrade,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalValues encode the values to json, the map keys are sorted and the nil values are the same as empty
+func canonicalValues(values map[string]interface{}) (json.RawMessage, error) {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return json.Marshal(values)
+}
+
+// valuesEqual compare the values with the json encoding
+func valuesEqual(a, b map[string]interface{}) bool {
+	dataA, err := canonicalValues(a)
+	if err != nil {
+		return false
+	}
+	dataB, err := canonicalValues(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(dataA, dataB)
+}
+
+// releaseDrifted check the release is changed outside of the helm operation after the revision recorded,
+// e.g. upgraded or rolled back with the helm cli
+func releaseDrifted(operation *helmopsv1alpha1.HelmOperation, rel *release.Release) bool {
+	return operation.Status.ReleaseRevision != 0 && operation.Status.ReleaseRevision != rel.Version
+}
+
+// markReleaseApplied record the fingerprint and the revision of the release which the helm operation applied,
+// the fingerprint is not changed if it is empty
+func markReleaseApplied(operation *helmopsv1alpha1.HelmOperation, rel *release.Release, fingerprint string) {
+	if fingerprint != "" {
+		operation.Status.Fingerprint = fingerprint
+	}
+	operation.Status.ReleaseRevision = rel.Version
+	operation.Status.ObservedGeneration = operation.Generation
+}
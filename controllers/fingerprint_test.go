@@ -0,0 +1,184 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+func newFingerprintOperation(t *testing.T, values string) *helmopsv1alpha1.HelmOperation {
+	operation := &helmopsv1alpha1.HelmOperation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", Generation: 1},
+		Spec: helmopsv1alpha1.HelmOperationSpec{
+			ChartName:     "nginx",
+			ChartVersion:  "1.0.0",
+			ChartRepoName: "stable",
+		},
+	}
+	if values != "" {
+		if err := json.Unmarshal([]byte(values), &operation.Spec.Values.Object); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return operation
+}
+
+func newFingerprintChartOptions(digest string) *actions.ChartOpts {
+	return &actions.ChartOpts{ChartName: "nginx", ChartVersion: "1.0.0", Digest: digest}
+}
+
+func Test_releaseFingerprint(t *testing.T) {
+	const values = `{"replicaCount": 2, "image": {"repository": "nginx", "tag": "1.21"}, "ports": [80, 443]}`
+	base, err := releaseFingerprint(newFingerprintOperation(t, values), newFingerprintChartOptions("sha256:aaaa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		operation    func() *helmopsv1alpha1.HelmOperation
+		digest       string
+		wantSameHash bool
+	}{
+		{
+			name: "status changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				operation := newFingerprintOperation(t, values)
+				operation.Generation = 2
+				operation.Status.ReleaseRevision = 3
+				operation.Status.Fingerprint = base
+				operation.Status.CurrentChartVersion = "1.0.0"
+				operation.Status.Conditions = helmopsv1alpha1.SetCondition(nil, helmopsv1alpha1.Condition{
+					Type:   helmopsv1alpha1.ConditionTypeReleaseOwned,
+					Status: helmopsv1alpha1.ConditionStatusTrue,
+				})
+				return operation
+			},
+			wantSameHash: true,
+		},
+		{
+			name: "values key order",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				return newFingerprintOperation(t, `{"ports": [80, 443], "image": {"tag": "1.21", "repository": "nginx"}, "replicaCount": 2}`)
+			},
+			wantSameHash: true,
+		},
+		{
+			name: "integer values decoded as int64",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				operation := newFingerprintOperation(t, values)
+				operation.Spec.Values.Object["replicaCount"] = int64(2)
+				return operation
+			},
+			wantSameHash: true,
+		},
+		{
+			name: "values changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				return newFingerprintOperation(t, `{"replicaCount": 3, "image": {"repository": "nginx", "tag": "1.21"}, "ports": [80, 443]}`)
+			},
+		},
+		{
+			name: "nested values changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				return newFingerprintOperation(t, `{"replicaCount": 2, "image": {"repository": "nginx", "tag": "1.22"}, "ports": [80, 443]}`)
+			},
+		},
+		{
+			name: "chart digest changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				return newFingerprintOperation(t, values)
+			},
+			digest: "sha256:bbbb",
+		},
+		{
+			name: "release name changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				operation := newFingerprintOperation(t, values)
+				operation.Spec.ReleaseName = "frontend"
+				return operation
+			},
+		},
+		{
+			name: "release namespace changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				operation := newFingerprintOperation(t, values)
+				operation.Spec.TargetNamespace = "frontend"
+				return operation
+			},
+		},
+		{
+			name: "chart repo changed",
+			operation: func() *helmopsv1alpha1.HelmOperation {
+				operation := newFingerprintOperation(t, values)
+				operation.Spec.ChartRepoName = "incubator"
+				return operation
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest := tt.digest
+			if digest == "" {
+				digest = "sha256:aaaa"
+			}
+			got, err := releaseFingerprint(tt.operation(), newFingerprintChartOptions(digest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == base) != tt.wantSameHash {
+				t.Errorf("releaseFingerprint() = %s, base %s, wantSameHash %v", got, base, tt.wantSameHash)
+			}
+		})
+	}
+}
+
+func Test_desiredChartVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		current    string
+		autoUpdate string
+		enabled    bool
+		want       string
+	}{
+		{name: "spec version", spec: "1.0.0", want: "1.0.0"},
+		{name: "spec version upgraded", spec: "1.2.0", current: "1.0.0", want: "1.2.0"},
+		{name: "current version greater", spec: "1.0.0", current: "1.1.0", want: "1.1.0"},
+		{name: "auto update version", spec: "1.0.0", current: "1.1.0", autoUpdate: "1.3.0", enabled: true, want: "1.3.0"},
+		{name: "auto update disabled", spec: "1.0.0", current: "1.1.0", autoUpdate: "1.3.0", want: "1.1.0"},
+		{name: "auto update version less", spec: "1.2.0", autoUpdate: "1.1.0", enabled: true, want: "1.2.0"},
+		{name: "invalid current version", spec: "1.0.0", current: "latest", want: "1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation := newFingerprintOperation(t, "")
+			operation.Spec.ChartVersion = tt.spec
+			operation.Spec.AutoUpdate = tt.enabled
+			operation.Status.CurrentChartVersion = tt.current
+			operation.Status.AutoUpdateChartVersion = tt.autoUpdate
+			if got := desiredChartVersion(operation); got != tt.want {
+				t.Errorf("desiredChartVersion() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
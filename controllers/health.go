@@ -0,0 +1,156 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/release"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/health"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+const (
+	// healthCheckPeriod the period to assess the release health when the release is not healthy
+	healthCheckPeriod = 30 * time.Second
+	// healthyCheckPeriod the period to assess the release health when the release is healthy
+	healthyCheckPeriod = 5 * time.Minute
+)
+
+// assessReleaseHealth assess the health of the release resources, set the Healthy and Ready condition,
+// and return the result which requeue for the next assessment
+func (r *HelmOperationReconciler) assessReleaseHealth(ctx context.Context, log logr.Logger, kubeClient *actions.KubernetesClient,
+	operation *helmopsv1alpha1.HelmOperation, rel *release.Release, result ctrl.Result) ctrl.Result {
+	var oldStatus = operation.Status.DeepCopy()
+	var resources = actions.ResourcesOptions{
+		Namespace:         operation.GetReleaseNamespace(),
+		Manifest:          rel.Manifest,
+		KubernetesOptions: kubeClient,
+	}
+	var assessment health.Result
+	objects, err := resources.Run()
+	switch {
+	case err == nil:
+		assessment = health.Assess(objects)
+	case k8serrors.IsNotFound(errors.Cause(err)):
+		assessment = health.Result{Status: health.StatusDegraded, Message: err.Error()}
+	default:
+		log.Error(err, "get the release resources error")
+		assessment = health.Result{Status: health.StatusProgressing, Message: err.Error()}
+	}
+	setHealthCondition(operation, assessment)
+	setReadyCondition(operation, rel)
+	assessedTime := metav1.NewTime(time.Now().Truncate(time.Second))
+	operation.Status.LastAssessedTime = &assessedTime
+	if !reflect.DeepEqual(oldStatus, &operation.Status) {
+		if err = r.Client.Status().Update(ctx, operation); err != nil {
+			log.Error(err, "update helm operation health status error")
+			return ctrl.Result{RequeueAfter: 10 * time.Second}
+		}
+	}
+	var period = healthCheckPeriod
+	if assessment.Status == health.StatusHealthy {
+		period = healthyCheckPeriod
+	}
+	if result.RequeueAfter == 0 || result.RequeueAfter > period {
+		result.RequeueAfter = period
+	}
+	return result
+}
+
+// nextReleaseCheck the time until the release and the health of the release resources checked again,
+// the period is shorter when the release is not healthy
+func nextReleaseCheck(operation *helmopsv1alpha1.HelmOperation) time.Duration {
+	if operation.Status.LastAssessedTime == nil {
+		return 0
+	}
+	var period = healthCheckPeriod
+	if operation.Status.Health == string(health.StatusHealthy) {
+		period = healthyCheckPeriod
+	}
+	return time.Until(operation.Status.LastAssessedTime.Add(period))
+}
+
+// setHealthCondition record the health assessment to the status
+func setHealthCondition(operation *helmopsv1alpha1.HelmOperation, assessment health.Result) {
+	var status = helmopsv1alpha1.ConditionStatusTrue
+	switch assessment.Status {
+	case health.StatusProgressing:
+		status = helmopsv1alpha1.ConditionStatusUnknown
+	case health.StatusDegraded:
+		status = helmopsv1alpha1.ConditionStatusFalse
+	}
+	operation.Status.Health = string(assessment.Status)
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeHealthy,
+		Status:  status,
+		Reason:  string(assessment.Status),
+		Message: assessment.Message,
+	})
+}
+
+// markHealthProgressing mark the release progressing after install or upgrade until the next health assessment
+func markHealthProgressing(operation *helmopsv1alpha1.HelmOperation, message string) {
+	setHealthCondition(operation, health.Result{Status: health.StatusProgressing, Message: message})
+}
+
+// setReadyCondition set the Ready condition from the release status and the health of the release resources
+func setReadyCondition(operation *helmopsv1alpha1.HelmOperation, rel *release.Release) {
+	var condition = helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeReady,
+		Status:  helmopsv1alpha1.ConditionStatusTrue,
+		Reason:  "ReleaseDeployed",
+		Message: fmt.Sprintf("release deployed at chart version %s", operation.Status.CurrentChartVersion),
+	}
+	switch {
+	case rel.Info == nil || rel.Info.Status != release.StatusDeployed:
+		condition.Status = helmopsv1alpha1.ConditionStatusFalse
+		condition.Reason = "ReleaseNotDeployed"
+		condition.Message = fmt.Sprintf("release status is %s", operation.Status.ReleaseStatus)
+	case operation.Status.Health != string(health.StatusHealthy):
+		condition.Status = helmopsv1alpha1.ConditionStatusFalse
+		condition.Reason = "Release" + operation.Status.Health
+		if operation.Status.Health == "" {
+			condition.Reason = "ReleaseHealthUnknown"
+		}
+		condition.Message = "the release resources are not healthy"
+		if healthy := helmopsv1alpha1.GetCondition(operation.Status.Conditions, helmopsv1alpha1.ConditionTypeHealthy); healthy != nil {
+			condition.Message = healthy.Message
+		}
+	}
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, condition)
+}
+
+// setReadyFailed set the Ready condition false when the helm action failed
+func setReadyFailed(operation *helmopsv1alpha1.HelmOperation, reason string, err error) {
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeReady,
+		Status:  helmopsv1alpha1.ConditionStatusFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+}
@@ -18,19 +18,22 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
-	"github.com/shijunLee/helmops/pkg/charts"
 	"github.com/shijunLee/helmops/pkg/helm/utils"
+	"github.com/shijunLee/helmops/pkg/metrics"
 
+	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 
 	"github.com/shijunLee/helmops/pkg/helm/actions"
 	"k8s.io/client-go/rest"
 
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -39,10 +42,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	helmOperationFinalizer = "finalizer.helmoperation.helmops.shijunlee.net"
+	// clusterHealthCheckPeriod the period to check the remote cluster health
+	clusterHealthCheckPeriod = 5 * time.Minute
 )
 
 // HelmOperationReconciler reconciles a HelmOperation object
@@ -51,11 +58,14 @@ type HelmOperationReconciler struct {
 	Log        logr.Logger
 	Scheme     *runtime.Scheme
 	RestConfig *rest.Config
+	// Dispatcher post the release events to the alerts, the events are not posted if nil
+	Dispatcher *EventDispatcher
 }
 
 //+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperations,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperations/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperations/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -72,100 +82,179 @@ func (r *HelmOperationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	err := r.Client.Get(ctx, types.NamespacedName{Name: req.Name, Namespace: req.Namespace}, helmOperation)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
+			metrics.DeleteReleaseStatus(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "find helm operation resource from client error", "ResourceName", req.Name, "ResourceName", req.Namespace)
 		return ctrl.Result{}, err
 	}
-	if !helmOperation.DeletionTimestamp.IsZero() {
+	defer func() {
+		if helmOperation.DeletionTimestamp.IsZero() {
+			metrics.SetReleaseStatus(helmOperation.Namespace, helmOperation.Name, helmOperation.Status.ReleaseStatus)
+		} else {
+			metrics.DeleteReleaseStatus(helmOperation.Namespace, helmOperation.Name)
+		}
+	}()
+	kubeClient, err := newKubernetesClient(ctx, r.Client, r.RestConfig, helmOperation)
+	if err != nil {
+		if !helmOperation.DeletionTimestamp.IsZero() && k8serrors.IsNotFound(err) {
+			// the kubeconfig secret or the service account was removed, the release can not be uninstalled any more
+			log.Info("kubeconfig secret or service account not found, skip uninstall the release", "error", err.Error())
+			return ctrl.Result{}, r.deleteFinalizer(ctx, helmOperation)
+		}
+		log.Error(err, "create kubernetes client for helm operation error")
+		helmOperation.Status.Conditions = helmopsv1alpha1.SetCondition(helmOperation.Status.Conditions, helmopsv1alpha1.Condition{
+			Type:    helmopsv1alpha1.ConditionTypeClusterReady,
+			Status:  helmopsv1alpha1.ConditionStatusFalse,
+			Reason:  "KubeConfigError",
+			Message: err.Error(),
+		})
+		if updateErr := r.Client.Status().Update(ctx, helmOperation); updateErr != nil {
+			log.Error(updateErr, "update helm operation status error")
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	if helmOperation.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(helmOperation, helmOperationFinalizer) {
-			controllerutil.AddFinalizer(helmOperation, helmRepoFinalizer)
+			controllerutil.AddFinalizer(helmOperation, helmOperationFinalizer)
 			err = r.Client.Update(ctx, helmOperation)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 		}
 	} else {
-		if err = r.removeFinalizer(ctx, helmOperation); err != nil {
+		if !controllerutil.ContainsFinalizer(helmOperation, helmOperationFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err = r.removeFinalizer(ctx, helmOperation, kubeClient); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.deleteFinalizer(ctx, helmOperation)
+	}
+	var oldStatus = helmOperation.Status.DeepCopy()
+	helmOperation.Status.Suspension, helmOperation.Status.Conditions = suspendStatus(helmOperation,
+		helmOperation.Spec.Suspend, helmOperation.Status.Conditions)
+	if helmOperation.Spec.Suspend {
+		suspendManualOperations(helmOperation)
+	}
+	if !reflect.DeepEqual(oldStatus, &helmOperation.Status) {
+		if err = r.Client.Status().Update(ctx, helmOperation); err != nil {
 			return ctrl.Result{}, err
 		}
-		controllerutil.RemoveFinalizer(helmOperation, helmRepoFinalizer)
 	}
+	var requeueResult = ctrl.Result{}
+	if helmOperation.Spec.KubeConfigSecretRef != nil {
+		// check the remote cluster health period, the status will show the cluster state
+		requeueResult.RequeueAfter = clusterHealthCheckPeriod
+		err = checkClusterHealth(kubeClient, helmOperation)
+		if updateErr := r.Client.Status().Update(ctx, helmOperation); updateErr != nil {
+			return ctrl.Result{}, updateErr
+		}
+		if err != nil {
+			log.Error(err, "the remote cluster is unreachable")
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+	}
+	if helmOperation.Spec.Suspend {
+		// the release is frozen even the manual operations, the reconcile will be triggered again
+		// when spec.suspend unset and the pending manual operations run then
+		log.Info("the helm operation is suspended, skip reconcile")
+		return ctrl.Result{}, nil
+	}
+	var reconcileRequested = helmOperation.GetAnnotations()[helmopsv1alpha1.ReconcileAtAnnotation] !=
+		helmOperation.Status.LastHandledReconcileAt
+	if handled, result, err := r.handleManualOperations(ctx, log, kubeClient, helmOperation); handled {
+		return result, err
+	}
+	if !reconcileRequested {
+		if wait, skip := releaseCheckSkipped(ctx, r.Client, helmOperation); skip {
+			// the events of the status updates and the unchanged spec not get the release again
+			if requeueResult.RequeueAfter == 0 || requeueResult.RequeueAfter > wait {
+				requeueResult.RequeueAfter = wait
+			}
+			return requeueResult, nil
+		}
+	}
+	var releaseName = helmOperation.GetReleaseName()
+	var releaseNamespace = helmOperation.GetReleaseNamespace()
 	var getOptions = actions.GetOptions{
-		ReleaseName:       helmOperation.Name,
-		Namespace:         helmOperation.Namespace,
-		KubernetesOptions: actions.NewKubernetesClient(actions.WithRestConfig(r.RestConfig)),
+		ReleaseName:       releaseName,
+		Namespace:         releaseNamespace,
+		KubernetesOptions: kubeClient,
 	}
 	var notCreate = false
 	release, err := getOptions.Run()
 	if err != nil {
-		if err == driver.ErrReleaseNotFound {
-			notCreate = true
+		if err != driver.ErrReleaseNotFound {
+			log.Error(err, "get helm release error", "releaseName", releaseName, "releaseNamespace", releaseNamespace)
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 		}
+		notCreate = true
 	}
-	chartOptions := &actions.ChartOpts{
-		ChartName:    helmOperation.Spec.ChartName,
-		ChartVersion: helmOperation.Spec.ChartVersion,
-	}
-	repoInfo, ok := repoCache.Load(helmOperation.Spec.ChartRepoName)
-	if !ok {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	if !notCreate && !helmOperation.IsReleaseOwned() {
+		owned, message := r.adoptRelease(helmOperation, release)
+		if err = r.Client.Status().Update(ctx, helmOperation); err != nil {
+			return ctrl.Result{}, err
+		}
+		if !owned {
+			log.Info("the release exists and not owned by the helm operation", "message", message)
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
 	}
-
-	chartRepo, ok := repoInfo.(*charts.ChartRepo)
-	if !ok {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	if !notCreate && helmOperation.Spec.AutoUpdate &&
+		utils.GetVersionGreaterThan(release.Chart.Metadata.Version, desiredChartVersion(helmOperation)) {
+		// the release auto updated but the status not recorded, keep the updated version
+		helmOperation.Status.CurrentChartVersion = release.Chart.Metadata.Version
 	}
-	if !chartRepo.Operation.CheckChartExist(helmOperation.Spec.ChartName, helmOperation.Spec.ChartVersion) {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation , the chart version not exist
+	chartOptions, err := resolveChartOptions(ctx, r.Client, helmOperation)
+	if err != nil {
+		// if repo or chart version not found or not allowed, do not process this operation
+		log.Error(err, "resolve chart of the helm operation error")
+		setReadyFailed(helmOperation, chartErrorReason(err, "ChartNotFound"), err)
+		if updateErr := r.Client.Status().Update(ctx, helmOperation); updateErr != nil {
+			log.Error(updateErr, "update helm operation status error")
+		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
-	url, pathType, err := chartRepo.Operation.GetChartVersionUrl(helmOperation.Spec.ChartName, helmOperation.Spec.ChartVersion)
+	fingerprint, err := releaseFingerprint(helmOperation, chartOptions)
 	if err != nil {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation , the chart version not exist
+		log.Error(err, "compute the fingerprint of the helm operation error")
+		setReadyFailed(helmOperation, chartErrorReason(err, "ChartLoadFailed"), err)
+		if updateErr := r.Client.Status().Update(ctx, helmOperation); updateErr != nil {
+			log.Error(updateErr, "update helm operation status error")
+		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
-	switch pathType {
-	case "file":
-		chartOptions.LocalPath = url
-	case "http":
-		chartOptions.ChartURL = url
-	}
 
 	// if release not create  do create
 	if notCreate {
-		var createInfo = helmOperation.Spec.Create
-		installOptions := actions.InstallOptions{
-			KubernetesOptions:        actions.NewKubernetesClient(actions.WithRestConfig(r.RestConfig)),
-			ReleaseName:              helmOperation.Name,
-			Namespace:                helmOperation.Namespace,
-			CreateNamespace:          helmOperation.Spec.Create.CreateNamespace,
-			ChartOpts:                chartOptions,
-			Description:              createInfo.Description,
-			SkipCRDs:                 createInfo.SkipCRDs,
-			Timeout:                  createInfo.Timeout,
-			NoHook:                   createInfo.NoHook,
-			GenerateName:             createInfo.GenerateName,
-			DisableOpenAPIValidation: createInfo.DisableOpenAPIValidation,
-			IsUpgrade:                createInfo.IsUpgrade,
-			WaitForJobs:              createInfo.WaitForJobs,
-			Replace:                  createInfo.Replace,
-			Wait:                     createInfo.Wait,
-			Values:                   helmOperation.Spec.Values.Object,
+		if blocked, err := waitForDependencies(ctx, r.Client, r.Log, helmOperation); blocked {
+			return ctrl.Result{RequeueAfter: dependencyRequeuePeriod}, err
+		}
+		if err = r.claimRelease(ctx, helmOperation); err != nil {
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 		}
-		release, err = installOptions.Run()
+		installOptions := newInstallOptions(helmOperation, kubeClient, chartOptions)
+		release, err = runInstall(installOptions)
 		if err != nil {
 			log.Error(err, "install release user helm client error")
+			setReadyFailed(helmOperation, "InstallFailed", err)
+			r.Dispatcher.Notify(helmOperation, helmopsv1alpha1.AlertEventFailed, nil, fmt.Sprintf("install failed: %v", err))
+			if updateErr := r.Client.Status().Update(ctx, helmOperation); updateErr != nil {
+				log.Error(updateErr, "update helm operation status error")
+			}
 			return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 		}
+		markReleaseOwned(helmOperation, "Installed", "the release installed by the helm operation")
+		markReleaseApplied(helmOperation, release, fingerprint)
+		pinChartDigest(helmOperation, chartOptions)
+		helmOperation.Status.RolledBackGeneration = 0
+		recordRevision(ctx, r.Client, r.Scheme, log, helmOperation, release, revisionActionInstall, appliedBy(helmOperation))
+		r.Dispatcher.Notify(helmOperation, helmopsv1alpha1.AlertEventInstalled, release, "the release installed")
 		helmOperation.Status.CurrentChartVersion = release.Chart.Metadata.Version
 		helmOperation.Status.ReleaseStatus = string(release.Info.Status)
+		markHealthProgressing(helmOperation, "the release installed, waiting for the health assessment")
+		setReadyCondition(helmOperation, release)
 		err = r.Client.Status().Update(ctx, helmOperation)
 		if err != nil {
 			// if repo not found ,do not process this operation
@@ -173,74 +262,226 @@ func (r *HelmOperationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 		}
 	} else {
-		var values = release.Config
-		var installChartVersion = release.Chart.Metadata.Version
-		// if version change or value changes do update process
-		if (helmOperation.Spec.ChartVersion != installChartVersion && helmOperation.Status.CurrentChartVersion != installChartVersion) ||
-			!reflect.DeepEqual(values, helmOperation.Spec.Values.Object) {
-			// if the installed helm release chart version great the the operation, update operation version and return
-			if utils.GetVersionGreaterThan(installChartVersion, helmOperation.Status.CurrentChartVersion) {
-				helmOperation.Status.CurrentChartVersion = installChartVersion
-				if reflect.DeepEqual(values, helmOperation.Spec.Values.Object) {
-					helmOperation.Status.ReleaseStatus = string(release.Info.Status)
-					err = r.Client.Status().Update(ctx, helmOperation)
-					if err != nil {
-						// if repo not found ,do not process this operation
-						//todo update status for this helmOperation , the chart version not exist
-						return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
-					}
-					return ctrl.Result{}, nil
-				}
+		// the canary values in the release are replaced even the spec changed back to the applied fingerprint
+		// the manually rolled back release is kept until the spec changed
+		var upToDate = (fingerprint == helmOperation.Status.Fingerprint || rolledBack(helmOperation)) &&
+			!releaseDrifted(helmOperation, release) && !canaryInProgress(helmOperation)
+		if helmOperation.Status.Fingerprint == "" && release.Chart.Metadata.Version == chartOptions.ChartVersion &&
+			valuesEqual(release.Config, helmOperation.Spec.Values.Object) {
+			// the release applied before the fingerprint recorded or just adopted, do not upgrade it again
+			upToDate = true
+		}
+		if !upToDate {
+			if releaseDrifted(helmOperation, release) {
+				log.Info("the release changed outside of the helm operation", "revision", release.Version,
+					"appliedRevision", helmOperation.Status.ReleaseRevision)
 			}
-			updateConfig := helmOperation.Spec.Upgrade
-			chart := *chartOptions
-			if utils.GetVersionGreaterThan(helmOperation.Status.CurrentChartVersion, helmOperation.Spec.ChartVersion) {
-				chart.ChartVersion = helmOperation.Status.CurrentChartVersion
+			if blocked, err := waitForDependencies(ctx, r.Client, r.Log, helmOperation); blocked {
+				return ctrl.Result{RequeueAfter: dependencyRequeuePeriod}, err
 			}
-			updateOption := actions.UpgradeOptions{
-				Values:                   helmOperation.Spec.Values.Object,
-				Install:                  updateConfig.Install,
-				Devel:                    updateConfig.Devel,
-				Namespace:                helmOperation.Namespace,
-				SkipCRDs:                 updateConfig.SkipCRDs,
-				Timeout:                  updateConfig.Timeout,
-				Wait:                     updateConfig.Wait,
-				DisableHooks:             updateConfig.DisableHooks,
-				Force:                    updateConfig.Force,
-				ResetValues:              updateConfig.ResetValues,
-				ReuseValues:              updateConfig.ReuseValues,
-				Recreate:                 updateConfig.Recreate,
-				MaxHistory:               updateConfig.MaxHistory,
-				Atomic:                   updateConfig.Atomic,
-				CleanupOnFail:            updateConfig.CleanupOnFail,
-				SubNotes:                 updateConfig.SubNotes,
-				Description:              updateConfig.Description,
-				DisableOpenAPIValidation: updateConfig.DisableOpenAPIValidation,
-				WaitForJobs:              updateConfig.WaitForJobs,
-				ChartOpts:                &chart,
-				KubernetesOptions:        actions.NewKubernetesClient(actions.WithRestConfig(r.RestConfig)),
-				UpgradeCRDs:              updateConfig.UpgradeCRDs,
+			if strategy := helmOperation.Spec.UpgradeStrategy; strategy != nil && strategy.Canary != nil {
+				promote, result, err := r.canaryUpgrade(ctx, log, kubeClient, helmOperation, release, chartOptions, fingerprint)
+				if !promote {
+					return result, err
+				}
 			}
-			release, err = updateOption.Run()
+			var previousVersion = release.Chart.Metadata.Version
+			updateOption := newUpgradeOptions(helmOperation, kubeClient, chartOptions)
+			release, err = runUpgrade(updateOption)
 			if err != nil {
 				log.Error(err, "upgrade release user helm client error")
+				setReadyFailed(helmOperation, "UpgradeFailed", err)
+				r.Dispatcher.Notify(helmOperation, helmopsv1alpha1.AlertEventFailed, nil, fmt.Sprintf("upgrade failed: %v", err))
+				if updateErr := r.Client.Status().Update(ctx, helmOperation); updateErr != nil {
+					log.Error(updateErr, "update helm operation status error")
+				}
 				return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 			}
+			markReleaseApplied(helmOperation, release, fingerprint)
+			pinChartDigest(helmOperation, chartOptions)
+			helmOperation.Status.RolledBackGeneration = 0
+			r.finishCanary(ctx, log, kubeClient, helmOperation)
+			if isAutoUpdate(helmOperation, chartOptions.ChartVersion, previousVersion) {
+				recordRevision(ctx, r.Client, r.Scheme, log, helmOperation, release, revisionActionAutoUpdate,
+					operationRepoKey(helmOperation).String())
+				r.Dispatcher.Notify(helmOperation, helmopsv1alpha1.AlertEventAutoUpdated, release,
+					fmt.Sprintf("the release auto updated from %s", previousVersion))
+			} else {
+				recordRevision(ctx, r.Client, r.Scheme, log, helmOperation, release, revisionActionUpgrade, appliedBy(helmOperation))
+				r.Dispatcher.Notify(helmOperation, helmopsv1alpha1.AlertEventUpgraded, release, "the release upgraded")
+			}
 			helmOperation.Status.CurrentChartVersion = release.Chart.Metadata.Version
 			helmOperation.Status.ReleaseStatus = string(release.Info.Status)
+			markHealthProgressing(helmOperation, "the release upgraded, waiting for the health assessment")
+			setReadyCondition(helmOperation, release)
 			err = r.Client.Status().Update(ctx, helmOperation)
 			if err != nil {
 				// if repo not found ,do not process this operation
 				//todo update status for this helmOperation , the chart version not exist
 				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 			}
+		} else {
+			// the release is up to date, keep the release status follow the release
+			var oldStatus = helmOperation.Status.DeepCopy()
+			helmOperation.Status.CurrentChartVersion = release.Chart.Metadata.Version
+			helmOperation.Status.ReleaseStatus = string(release.Info.Status)
+			markReleaseApplied(helmOperation, release, fingerprint)
+			if !reflect.DeepEqual(oldStatus, &helmOperation.Status) {
+				if err = r.Client.Status().Update(ctx, helmOperation); err != nil {
+					return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+				}
+			}
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return r.assessReleaseHealth(ctx, log, kubeClient, helmOperation, release, requeueResult), nil
+}
+
+// releaseCheckSkipped check the release applied the spec and the chart version of the helm operation and
+// the next check of the release and the health not due, return the time until the next check.
+// the drift of the release is found at the next check
+func releaseCheckSkipped(ctx context.Context, c client.Client, operation *helmopsv1alpha1.HelmOperation) (time.Duration, bool) {
+	if operation.Status.ReleaseRevision == 0 || operation.Status.ObservedGeneration != operation.Generation ||
+		canaryInProgress(operation) {
+		return 0, false
+	}
+	wait := nextReleaseCheck(operation)
+	if wait <= 0 {
+		return 0, false
+	}
+	if rolledBack(operation) {
+		return wait, true
+	}
+	chartOptions, err := resolveChartOptions(ctx, c, operation)
+	if err != nil {
+		return 0, false
+	}
+	fingerprint, err := releaseFingerprint(operation, chartOptions)
+	if err != nil || fingerprint != operation.Status.Fingerprint {
+		return 0, false
+	}
+	return wait, true
+}
+
+// isAutoUpdate check the upgrade is the auto update to the newer chart version found in the repo
+func isAutoUpdate(operation *helmopsv1alpha1.HelmOperation, chartVersion, previousVersion string) bool {
+	return operation.Spec.AutoUpdate && chartVersion != previousVersion &&
+		chartVersion == operation.Status.AutoUpdateChartVersion && chartVersion != operation.Spec.ChartVersion
+}
+
+// chartErrorReason the reason of the Ready condition when the chart resolved or loaded failed
+func chartErrorReason(err error, reason string) string {
+	if errors.Cause(err) == actions.ChartDigestMismatchErr {
+		return "ChartDigestMismatch"
+	}
+	return reason
+}
+
+// newInstallOptions create the install options from the create config of the helm operation
+func newInstallOptions(operation *helmopsv1alpha1.HelmOperation, kubeClient *actions.KubernetesClient,
+	chartOptions *actions.ChartOpts) actions.InstallOptions {
+	var createInfo = operation.Spec.Create
+	return actions.InstallOptions{
+		KubernetesOptions:        kubeClient,
+		ReleaseName:              operation.GetReleaseName(),
+		Namespace:                operation.GetReleaseNamespace(),
+		CreateNamespace:          createInfo.CreateNamespace,
+		ChartOpts:                chartOptions,
+		Description:              createInfo.Description,
+		SkipCRDs:                 createInfo.SkipCRDs,
+		Timeout:                  createInfo.Timeout,
+		NoHook:                   createInfo.NoHook,
+		GenerateName:             createInfo.GenerateName,
+		DisableOpenAPIValidation: createInfo.DisableOpenAPIValidation,
+		IsUpgrade:                createInfo.IsUpgrade,
+		WaitForJobs:              createInfo.WaitForJobs,
+		Replace:                  createInfo.Replace,
+		Wait:                     createInfo.Wait,
+		Values:                   operation.Spec.Values.Object,
+	}
+}
+
+// newUpgradeOptions create the upgrade options from the upgrade config of the helm operation
+func newUpgradeOptions(operation *helmopsv1alpha1.HelmOperation, kubeClient *actions.KubernetesClient,
+	chartOptions *actions.ChartOpts) actions.UpgradeOptions {
+	updateConfig := operation.Spec.Upgrade
+	return actions.UpgradeOptions{
+		Values:                   operation.Spec.Values.Object,
+		Install:                  updateConfig.Install,
+		Devel:                    updateConfig.Devel,
+		Namespace:                operation.GetReleaseNamespace(),
+		SkipCRDs:                 updateConfig.SkipCRDs,
+		Timeout:                  updateConfig.Timeout,
+		Wait:                     updateConfig.Wait,
+		DisableHooks:             updateConfig.DisableHooks,
+		Force:                    updateConfig.Force,
+		ResetValues:              updateConfig.ResetValues,
+		ReuseValues:              updateConfig.ReuseValues,
+		Recreate:                 updateConfig.Recreate,
+		MaxHistory:               updateConfig.MaxHistory,
+		Atomic:                   updateConfig.Atomic,
+		CleanupOnFail:            updateConfig.CleanupOnFail,
+		SubNotes:                 updateConfig.SubNotes,
+		Description:              updateConfig.Description,
+		DisableOpenAPIValidation: updateConfig.DisableOpenAPIValidation,
+		WaitForJobs:              updateConfig.WaitForJobs,
+		ReleaseName:              operation.GetReleaseName(),
+		ChartOpts:                chartOptions,
+		KubernetesOptions:        kubeClient,
+		UpgradeCRDs:              updateConfig.UpgradeCRDs,
+	}
+}
+
+// adoptRelease take over the release which not installed by the helm operation if spec.adopt is set,
+// return false with the reason message if the release can not be adopted
+func (r *HelmOperationReconciler) adoptRelease(operation *helmopsv1alpha1.HelmOperation, rel *release.Release) (bool, string) {
+	var message string
+	switch {
+	case !operation.Spec.Adopt:
+		message = fmt.Sprintf("release %s/%s already exists, set spec.adopt to take over it", rel.Namespace, rel.Name)
+	case rel.Chart == nil || rel.Chart.Metadata == nil || rel.Chart.Metadata.Name != operation.Spec.ChartName:
+		message = fmt.Sprintf("release %s/%s is not installed from chart %s, can not adopt it",
+			rel.Namespace, rel.Name, operation.Spec.ChartName)
+	default:
+		markReleaseOwned(operation, "Adopted", fmt.Sprintf("the release adopted at revision %d", rel.Version))
+		return true, ""
+	}
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeReleaseOwned,
+		Status:  helmopsv1alpha1.ConditionStatusFalse,
+		Reason:  "ReleaseNotOwned",
+		Message: message,
+	})
+	return false, message
+}
+
+// markReleaseOwned record the release is managed by the helm operation
+func markReleaseOwned(operation *helmopsv1alpha1.HelmOperation, reason, message string) {
+	operation.Status.ReleaseName = operation.GetReleaseName()
+	operation.Status.ReleaseNamespace = operation.GetReleaseNamespace()
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeReleaseOwned,
+		Status:  helmopsv1alpha1.ConditionStatusTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// claimRelease record the release is owned by the helm operation before install it, the install is not atomic,
+// the failed release left by the install is upgraded by the next reconcile instead of treated as an existing release
+func (r *HelmOperationReconciler) claimRelease(ctx context.Context, operation *helmopsv1alpha1.HelmOperation) error {
+	if operation.IsReleaseOwned() && operation.Status.ReleaseName != "" {
+		return nil
+	}
+	markReleaseOwned(operation, "Installing", "the release is installing by the helm operation")
+	return r.Client.Status().Update(ctx, operation)
 }
-func (r *HelmOperationReconciler) removeFinalizer(ctx context.Context, operation *helmopsv1alpha1.HelmOperation) error {
-	if operation.Spec.Uninstall.DoNotDeleteRelease {
+
+//removeFinalizer uninstall the helm release before the helm operation deleted
+func (r *HelmOperationReconciler) removeFinalizer(ctx context.Context, operation *helmopsv1alpha1.HelmOperation,
+	kubeClient *actions.KubernetesClient) error {
+	if operation.Spec.Uninstall.DoNotDeleteRelease || !operation.IsReleaseOwned() {
+		// never uninstall the release which not installed or adopted by this operation
 		return nil
 	}
 	uninstallConfig := operation.Spec.Uninstall
@@ -249,21 +490,28 @@ func (r *HelmOperationReconciler) removeFinalizer(ctx context.Context, operation
 		KeepHistory:       uninstallConfig.KeepHistory,
 		Timeout:           uninstallConfig.Timeout,
 		DisableHooks:      uninstallConfig.DisableHooks,
-		Namespace:         operation.Namespace,
-		ReleaseName:       operation.Name,
-		KubernetesOptions: actions.NewKubernetesClient(actions.WithRestConfig(r.RestConfig)),
+		Namespace:         operation.GetReleaseNamespace(),
+		ReleaseName:       operation.GetReleaseName(),
+		KubernetesOptions: kubeClient,
 	}
-	_, err := uninstall.Run()
-	if err != nil {
+	_, err := runUninstall(uninstall)
+	if err != nil && errors.Cause(err) != driver.ErrReleaseNotFound {
 		return err
 	}
 
 	return nil
 }
 
+//deleteFinalizer remove the finalizer from the helm operation, then it can be deleted
+func (r *HelmOperationReconciler) deleteFinalizer(ctx context.Context, operation *helmopsv1alpha1.HelmOperation) error {
+	controllerutil.RemoveFinalizer(operation, helmOperationFinalizer)
+	return r.Client.Update(ctx, operation)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *HelmOperationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&helmopsv1alpha1.HelmOperation{}).
+		Watches(&source.Kind{Type: &helmopsv1alpha1.HelmOperation{}}, handler.EnqueueRequestsFromMapFunc(r.mapDependencyToOperations)).
 		Complete(r)
 }
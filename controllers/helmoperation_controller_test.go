@@ -0,0 +1,84 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+func Test_claimReleaseBeforeInstall(t *testing.T) {
+	ctx := context.Background()
+	operation := &helmopsv1alpha1.HelmOperation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web"},
+		Spec:       helmopsv1alpha1.HelmOperationSpec{ChartName: "nginx", ChartVersion: "1.0.0"},
+	}
+	c, scheme := newTestClient(t, operation)
+	r := &HelmOperationReconciler{Client: c, Log: logr.Discard(), Scheme: scheme}
+	if err := r.claimRelease(ctx, operation); err != nil {
+		t.Fatal(err)
+	}
+
+	// the install failed and left a failed release, the status not updated after the install
+	failed := &release.Release{
+		Name:      "web",
+		Namespace: "apps",
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusFailed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "nginx", Version: "1.0.0"}},
+	}
+	var next = &helmopsv1alpha1.HelmOperation{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "apps", Name: "web"}, next); err != nil {
+		t.Fatal(err)
+	}
+	// the next reconcile upgrade the failed release instead of asking to adopt it
+	if !next.IsReleaseOwned() {
+		owned, message := r.adoptRelease(next, failed)
+		t.Fatalf("expect the failed release owned by the helm operation, adopt %v: %s", owned, message)
+	}
+	condition := helmopsv1alpha1.GetCondition(next.Status.Conditions, helmopsv1alpha1.ConditionTypeReleaseOwned)
+	if condition == nil || condition.Status != helmopsv1alpha1.ConditionStatusTrue {
+		t.Errorf("expect the release owned condition, got %+v", condition)
+	}
+
+	// the claimed release is not claimed again
+	resourceVersion := next.ResourceVersion
+	if err := r.claimRelease(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if next.ResourceVersion != resourceVersion {
+		t.Errorf("expect the status not updated for the claimed release")
+	}
+
+	// the release not claimed before the install is an existing release
+	unclaimed := operation.DeepCopy()
+	unclaimed.Status = helmopsv1alpha1.HelmOperationStatus{}
+	if unclaimed.IsReleaseOwned() {
+		t.Fatal("expect the release not owned without the claim")
+	}
+	if owned, _ := r.adoptRelease(unclaimed, failed); owned {
+		t.Error("expect the release not adopted without spec.adopt")
+	}
+}
@@ -0,0 +1,386 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/yaml"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// HelmOperationSetReconciler reconciles a HelmOperationSet object
+type HelmOperationSetReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperationsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperationsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperationsets/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Reconcile generate the elements of the HelmOperationSet, create or update one HelmOperation
+// for each element, prune the HelmOperations which element removed and aggregate their status.
+func (r *HelmOperationSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("helmoperationset", req.NamespacedName)
+
+	operationSet := &helmopsv1alpha1.HelmOperationSet{}
+	err := r.Client.Get(ctx, req.NamespacedName, operationSet)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "find helm operation set resource from client error")
+		return ctrl.Result{}, err
+	}
+	// the helm operations will be deleted by the garbage collector with the owner reference
+	if !operationSet.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	elements, err := r.generateElements(ctx, operationSet)
+	if err != nil {
+		log.Error(err, "generate the helm operation set elements error")
+		return r.updateStatus(ctx, operationSet, errors.Wrap(err, "generate elements error"))
+	}
+
+	var syncErrors []string
+	var expected = map[string]bool{}
+	for _, element := range elements {
+		operationName := fmt.Sprintf("%s-%s", operationSet.Name, element.Name)
+		expected[operationName] = true
+		if err = r.syncHelmOperation(ctx, operationSet, operationName, element); err != nil {
+			log.Error(err, "sync helm operation error", "helmoperation", operationName)
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %s", operationName, err.Error()))
+		}
+	}
+
+	operations, err := r.listHelmOperations(ctx, operationSet)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range operations {
+		if expected[operations[i].Name] {
+			continue
+		}
+		log.Info("prune the helm operation which element removed", "helmoperation", operations[i].Name)
+		if err = r.Client.Delete(ctx, &operations[i]); err != nil && !k8serrors.IsNotFound(err) {
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %s", operations[i].Name, err.Error()))
+		}
+	}
+
+	var syncErr error
+	if len(syncErrors) > 0 {
+		sort.Strings(syncErrors)
+		syncErr = errors.New(strings.Join(syncErrors, "; "))
+	}
+	return r.updateStatus(ctx, operationSet, syncErr)
+}
+
+// generateElements get all the elements from the generators, the element name must unique in the set
+func (r *HelmOperationSetReconciler) generateElements(ctx context.Context,
+	operationSet *helmopsv1alpha1.HelmOperationSet) ([]helmopsv1alpha1.GeneratorElement, error) {
+	var result []helmopsv1alpha1.GeneratorElement
+	for _, generator := range operationSet.Spec.Generators {
+		var elements []helmopsv1alpha1.GeneratorElement
+		var err error
+		switch {
+		case generator.List != nil:
+			elements = generator.List.Elements
+		case generator.NamespaceSelector != nil:
+			elements, err = r.generateNamespaceElements(ctx, generator.NamespaceSelector)
+		case generator.ConfigMap != nil:
+			elements, err = r.generateConfigMapElements(ctx, operationSet.Namespace, generator.ConfigMap)
+		default:
+			err = errors.New("generator type not config")
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, elements...)
+	}
+	var names = map[string]bool{}
+	var releaseNamespaces = map[string]string{}
+	for _, item := range result {
+		if item.Name == "" {
+			return nil, errors.New("element name can not empty")
+		}
+		if names[item.Name] {
+			return nil, errors.Errorf("element name %s is duplicate", item.Name)
+		}
+		names[item.Name] = true
+		if operationSet.Spec.Template.Spec.ReleaseName == "" {
+			continue
+		}
+		// the same release name in the template, the elements must install to different namespaces
+		namespace := elementReleaseNamespace(operationSet, item)
+		if other, ok := releaseNamespaces[namespace]; ok {
+			return nil, errors.Errorf("element %s and %s install the release %s to the same namespace %s", other,
+				item.Name, operationSet.Spec.Template.Spec.ReleaseName, namespace)
+		}
+		releaseNamespaces[namespace] = item.Name
+	}
+	return result, nil
+}
+
+// elementReleaseNamespace the namespace which the release of the element installed to
+func elementReleaseNamespace(operationSet *helmopsv1alpha1.HelmOperationSet, element helmopsv1alpha1.GeneratorElement) string {
+	if element.Namespace != "" {
+		return element.Namespace
+	}
+	if operationSet.Spec.Template.Spec.TargetNamespace != "" {
+		return operationSet.Spec.Template.Spec.TargetNamespace
+	}
+	return operationSet.Namespace
+}
+
+func (r *HelmOperationSetReconciler) generateNamespaceElements(ctx context.Context,
+	generator *helmopsv1alpha1.NamespaceSelectorGenerator) ([]helmopsv1alpha1.GeneratorElement, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&generator.Selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "namespace selector is invalid")
+	}
+	var namespaceList = &corev1.NamespaceList{}
+	if err = r.Client.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	var result []helmopsv1alpha1.GeneratorElement
+	for _, item := range namespaceList.Items {
+		if !item.DeletionTimestamp.IsZero() {
+			continue
+		}
+		result = append(result, helmopsv1alpha1.GeneratorElement{
+			Name:      item.Name,
+			Namespace: item.Name,
+			Values:    *generator.Values.DeepCopy(),
+		})
+	}
+	return result, nil
+}
+
+func (r *HelmOperationSetReconciler) generateConfigMapElements(ctx context.Context, namespace string,
+	generator *helmopsv1alpha1.ConfigMapGenerator) ([]helmopsv1alpha1.GeneratorElement, error) {
+	var configMap = &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: generator.Name}, configMap); err != nil {
+		return nil, errors.Wrapf(err, "get config map %s error", generator.Name)
+	}
+	var result []helmopsv1alpha1.GeneratorElement
+	for key, value := range configMap.Data {
+		var element = helmopsv1alpha1.GeneratorElement{}
+		jsonData, err := yaml.YAMLToJSON([]byte(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse config map %s key %s error", generator.Name, key)
+		}
+		if err = json.Unmarshal(jsonData, &element); err != nil {
+			return nil, errors.Wrapf(err, "parse config map %s key %s error", generator.Name, key)
+		}
+		element.Name = key
+		result = append(result, element)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// syncHelmOperation create or update the helm operation for the element
+func (r *HelmOperationSetReconciler) syncHelmOperation(ctx context.Context, operationSet *helmopsv1alpha1.HelmOperationSet,
+	operationName string, element helmopsv1alpha1.GeneratorElement) error {
+	var template = operationSet.Spec.Template
+	var operation = &helmopsv1alpha1.HelmOperation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operationName,
+			Namespace: operationSet.Namespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, operation, func() error {
+		if operation.Labels == nil {
+			operation.Labels = map[string]string{}
+		}
+		for key, value := range template.Labels {
+			operation.Labels[key] = value
+		}
+		operation.Labels[helmopsv1alpha1.HelmOperationSetLabel] = operationSet.Name
+		operation.Labels[helmopsv1alpha1.HelmOperationSetElementLabel] = element.Name
+		if len(template.Annotations) > 0 && operation.Annotations == nil {
+			operation.Annotations = map[string]string{}
+		}
+		for key, value := range template.Annotations {
+			operation.Annotations[key] = value
+		}
+
+		spec := template.Spec.DeepCopy()
+		// the release name is the helm operation name by default, the releases of the elements not conflict
+		if spec.ReleaseName == "" {
+			spec.ReleaseName = operationName
+		}
+		if element.Namespace != "" {
+			spec.TargetNamespace = element.Namespace
+		}
+		spec.Values.Object = mergeValues(template.Spec.Values.Object, element.Values.Object)
+		operation.Spec = *spec
+		return controllerutil.SetControllerReference(operationSet, operation, r.Scheme)
+	})
+	return err
+}
+
+// mergeValues deep merge the override values over the base values, the input values not changed
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	var result = map[string]interface{}{}
+	if override != nil {
+		result = runtime.DeepCopyJSON(override)
+	}
+	if base == nil {
+		return result
+	}
+	return chartutil.CoalesceTables(result, runtime.DeepCopyJSON(base))
+}
+
+func (r *HelmOperationSetReconciler) listHelmOperations(ctx context.Context,
+	operationSet *helmopsv1alpha1.HelmOperationSet) ([]helmopsv1alpha1.HelmOperation, error) {
+	var operationList = &helmopsv1alpha1.HelmOperationList{}
+	err := r.Client.List(ctx, operationList, client.InNamespace(operationSet.Namespace),
+		client.MatchingLabels{helmopsv1alpha1.HelmOperationSetLabel: operationSet.Name})
+	if err != nil {
+		return nil, err
+	}
+	var result []helmopsv1alpha1.HelmOperation
+	for _, item := range operationList.Items {
+		if metav1.IsControlledBy(&item, operationSet) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// updateStatus aggregate the status of the helm operations created by the set
+func (r *HelmOperationSetReconciler) updateStatus(ctx context.Context, operationSet *helmopsv1alpha1.HelmOperationSet,
+	syncErr error) (ctrl.Result, error) {
+	operations, err := r.listHelmOperations(ctx, operationSet)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var oldStatus = operationSet.Status.DeepCopy()
+	var status = &operationSet.Status
+	status.Total, status.Ready, status.Failed, status.Upgrading = int32(len(operations)), 0, 0, 0
+	for _, item := range operations {
+		switch release.Status(item.Status.ReleaseStatus) {
+		case release.StatusDeployed:
+			status.Ready++
+		case release.StatusFailed:
+			status.Failed++
+		case release.StatusPendingInstall, release.StatusPendingUpgrade, release.StatusPendingRollback:
+			status.Upgrading++
+		}
+	}
+	var condition = helmopsv1alpha1.Condition{
+		Type:   helmopsv1alpha1.ConditionTypeGenerated,
+		Status: helmopsv1alpha1.ConditionStatusTrue,
+		Reason: "Synced",
+	}
+	if syncErr != nil {
+		condition.Status = helmopsv1alpha1.ConditionStatusFalse
+		condition.Reason = "SyncError"
+		condition.Message = syncErr.Error()
+	}
+	status.Conditions = helmopsv1alpha1.SetCondition(status.Conditions, condition)
+	// the status update trigger the reconcile again, only update the status when it changed
+	if !reflect.DeepEqual(oldStatus, status) {
+		now := metav1.Now()
+		status.LastUpdateTime = &now
+		if err = r.Client.Status().Update(ctx, operationSet); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if syncErr != nil {
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HelmOperationSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&helmopsv1alpha1.HelmOperationSet{}).
+		Owns(&helmopsv1alpha1.HelmOperation{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToSets)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.mapConfigMapToSets)).
+		Complete(r)
+}
+
+// mapNamespaceToSets enqueue all the sets with a namespace selector generator when a namespace changed
+func (r *HelmOperationSetReconciler) mapNamespaceToSets(obj client.Object) []reconcile.Request {
+	return r.findSets("", func(generator helmopsv1alpha1.HelmOperationSetGenerator) bool {
+		// namespace label changes may remove the namespace from the selector, so enqueue all namespace generators
+		return generator.NamespaceSelector != nil
+	})
+}
+
+// mapConfigMapToSets enqueue the sets which generate the elements from the config map
+func (r *HelmOperationSetReconciler) mapConfigMapToSets(obj client.Object) []reconcile.Request {
+	return r.findSets(obj.GetNamespace(), func(generator helmopsv1alpha1.HelmOperationSetGenerator) bool {
+		return generator.ConfigMap != nil && generator.ConfigMap.Name == obj.GetName()
+	})
+}
+
+func (r *HelmOperationSetReconciler) findSets(namespace string,
+	match func(generator helmopsv1alpha1.HelmOperationSetGenerator) bool) []reconcile.Request {
+	var setList = &helmopsv1alpha1.HelmOperationSetList{}
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := r.Client.List(context.Background(), setList, opts...); err != nil {
+		r.Log.Error(err, "list helm operation sets error")
+		return nil
+	}
+	var result []reconcile.Request
+	for _, item := range setList.Items {
+		for _, generator := range item.Spec.Generators {
+			if match(generator) {
+				result = append(result, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}})
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// newTestClient create the fake client with the helmops types registered
+func newTestClient(t *testing.T, objects ...client.Object) (client.Client, *runtime.Scheme) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := helmopsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(), scheme
+}
+
+func newTestOperationSet(releaseName string, elements ...helmopsv1alpha1.GeneratorElement) *helmopsv1alpha1.HelmOperationSet {
+	return &helmopsv1alpha1.HelmOperationSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: helmopsv1alpha1.HelmOperationSetSpec{
+			Template: helmopsv1alpha1.HelmOperationTemplate{
+				Spec: helmopsv1alpha1.HelmOperationSpec{
+					ChartName:    "nginx",
+					ChartVersion: "1.0.0",
+					ReleaseName:  releaseName,
+				},
+			},
+			Generators: []helmopsv1alpha1.HelmOperationSetGenerator{
+				{List: &helmopsv1alpha1.ListGenerator{Elements: elements}},
+			},
+		},
+	}
+}
+
+func Test_HelmOperationSetReconcile(t *testing.T) {
+	ctx := context.Background()
+	operationSet := newTestOperationSet("",
+		helmopsv1alpha1.GeneratorElement{Name: "blue"},
+		helmopsv1alpha1.GeneratorElement{Name: "green"},
+		helmopsv1alpha1.GeneratorElement{Name: "team-a", Namespace: "team-a"},
+	)
+	c, scheme := newTestClient(t, operationSet)
+	r := &HelmOperationSetReconciler{Client: c, Log: logr.Discard(), Scheme: scheme}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var operations = &helmopsv1alpha1.HelmOperationList{}
+	if err := c.List(ctx, operations, client.InNamespace("apps")); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations.Items) != 3 {
+		t.Fatalf("expect 3 helm operations, got %d", len(operations.Items))
+	}
+	var expectNamespaces = map[string]string{"web-blue": "apps", "web-green": "apps", "web-team-a": "team-a"}
+	var releases = map[string]bool{}
+	for _, item := range operations.Items {
+		// the release name default to the helm operation name, the releases in the same namespace not conflict
+		if item.GetReleaseName() != item.Name {
+			t.Errorf("expect the release name of %s is %s, got %s", item.Name, item.Name, item.GetReleaseName())
+		}
+		if item.GetReleaseNamespace() != expectNamespaces[item.Name] {
+			t.Errorf("expect the release namespace of %s is %s, got %s", item.Name, expectNamespaces[item.Name],
+				item.GetReleaseNamespace())
+		}
+		key := item.GetReleaseNamespace() + "/" + item.GetReleaseName()
+		if releases[key] {
+			t.Errorf("the release %s is duplicate", key)
+		}
+		releases[key] = true
+	}
+
+	var status = &helmopsv1alpha1.HelmOperationSet{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "apps", Name: "web"}, status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.Total != 3 {
+		t.Errorf("expect the total is 3, got %d", status.Status.Total)
+	}
+	condition := helmopsv1alpha1.GetCondition(status.Status.Conditions, helmopsv1alpha1.ConditionTypeGenerated)
+	if condition == nil || condition.Status != helmopsv1alpha1.ConditionStatusTrue {
+		t.Errorf("expect the set generated, got %+v", condition)
+	}
+
+	// the reconcile triggered by the status update not write the status again
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+	var unchanged = &helmopsv1alpha1.HelmOperationSet{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "apps", Name: "web"}, unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.ResourceVersion != status.ResourceVersion {
+		t.Errorf("expect the status not updated, the resource version changed from %s to %s",
+			status.ResourceVersion, unchanged.ResourceVersion)
+	}
+}
+
+func Test_HelmOperationSetReleaseNameConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []helmopsv1alpha1.GeneratorElement
+		wantErr  bool
+	}{
+		{
+			name:     "same namespace",
+			elements: []helmopsv1alpha1.GeneratorElement{{Name: "blue"}, {Name: "green"}},
+			wantErr:  true,
+		},
+		{
+			name:     "element namespace same as the set namespace",
+			elements: []helmopsv1alpha1.GeneratorElement{{Name: "blue"}, {Name: "green", Namespace: "apps"}},
+			wantErr:  true,
+		},
+		{
+			name:     "different namespaces",
+			elements: []helmopsv1alpha1.GeneratorElement{{Name: "blue"}, {Name: "green", Namespace: "green"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operationSet := newTestOperationSet("web", tt.elements...)
+			c, scheme := newTestClient(t)
+			r := &HelmOperationSetReconciler{Client: c, Log: logr.Discard(), Scheme: scheme}
+			_, err := r.generateElements(context.Background(), operationSet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("generateElements() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -20,11 +20,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/shijunLee/helmops/pkg/helm/actions"
-	"helm.sh/helm/v3/pkg/storage/driver"
 	"k8s.io/client-go/rest"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -44,9 +45,14 @@ import (
 	"github.com/go-logr/logr"
 	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/shijunLee/helmops/pkg/metrics"
 )
 
 const (
@@ -64,6 +70,8 @@ type HelmRepoReconciler struct {
 	MaxConcurrentReconciles int
 	JitterPeriod            time.Duration
 	RestConfig              *rest.Config
+	// Dispatcher post the auto update and new version events to the alerts, the events are not posted if nil
+	Dispatcher *EventDispatcher
 }
 
 type syncUpdateHelmRelease struct {
@@ -92,6 +100,7 @@ func NewHelmRepoReconciler(mgr ctrl.Manager, period, maxConcurrentReconciles int
 		MaxConcurrentReconciles: maxConcurrentReconciles,
 		JitterPeriod:            jitterPeriod,
 	}
+	dependencyCacheDir = filepath.Join(localCachePath, dependencyCacheDirName)
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "repo-job-queue")
 	result.queue = queue
 	return result
@@ -170,6 +179,8 @@ func (r *HelmRepoReconciler) processHelmReleaseUpgradeRreconcileHandler(ctx cont
 
 }
 
+// DoRepoSyncReconcile record the new chart version found in the repo as the auto update version of the helm operation,
+// the status change trigger the reconcile of the helm operation which upgrade the release with the upgrade strategy
 func (r *HelmRepoReconciler) DoRepoSyncReconcile(ctx context.Context, req syncUpdateHelmRelease) (ctrl.Result, error) {
 	helmOperation := &helmopsv1alpha1.HelmOperation{}
 	err := r.Client.Get(ctx, types.NamespacedName{Name: req.ReleaseName, Namespace: req.Namespace}, helmOperation)
@@ -180,118 +191,46 @@ func (r *HelmRepoReconciler) DoRepoSyncReconcile(ctx context.Context, req syncUp
 		r.Log.Error(err, "find helm operation resource from client error", "ResourceName", req.ReleaseName, "ResourceName", req.Namespace)
 		return ctrl.Result{}, err
 	}
-	// if is delete do nothing return
-	if !helmOperation.DeletionTimestamp.IsZero() {
+	// if is delete or suspended do nothing return
+	if !helmOperation.DeletionTimestamp.IsZero() || helmOperation.Spec.Suspend || !helmOperation.Spec.AutoUpdate {
 		return ctrl.Result{}, nil
 	}
-
-	var getOptions = actions.GetOptions{
-		ReleaseName:       helmOperation.Name,
-		Namespace:         helmOperation.Namespace,
-		KubernetesOptions: actions.NewKubernetesClient(actions.WithRestConfig(r.RestConfig)),
+	// the release not installed or adopted by the operation, do not auto update it
+	if !helmOperation.IsReleaseOwned() {
+		return ctrl.Result{}, nil
 	}
-
-	release, err := getOptions.Run()
-	if err != nil {
-		if err == driver.ErrReleaseNotFound {
-			return ctrl.Result{}, nil
-		}
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+	// the manual rollback is kept until the spec of the helm operation changed
+	if rolledBack(helmOperation) {
+		r.Log.Info("skip auto update of the rolled back release", "ResourceName", req.ReleaseName,
+			"version", req.ChartVersion)
+		return ctrl.Result{}, nil
 	}
-	repoInfo, ok := repoCache.Load(helmOperation.Spec.ChartRepoName)
-	if !ok {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	key, err := parseRepoKey(req.ChartRepo)
+	if err != nil || key != operationRepoKey(helmOperation) || req.ChartName != helmOperation.Spec.ChartName {
+		// the helm operation reference another repo or chart now
+		return ctrl.Result{}, nil
 	}
-
-	chartRepo, ok := repoInfo.(*charts.ChartRepo)
-	if !ok {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	if err = checkRepoAccess(ctx, r.Client, helmOperation.Namespace, key); err != nil {
+		r.Log.Error(err, "the helm operation can not use the repo", "repo", req.ChartRepo)
+		return ctrl.Result{}, nil
 	}
-	if !chartRepo.Operation.CheckChartExist(helmOperation.Spec.ChartName, req.ChartVersion) {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation , the chart version not exist
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	if !utils.GetVersionGreaterThan(req.ChartVersion, desiredChartVersion(helmOperation)) {
+		return ctrl.Result{}, nil
 	}
-	url, pathType, err := chartRepo.Operation.GetChartVersionUrl(helmOperation.Spec.ChartName, helmOperation.Spec.ChartVersion)
+	// the digest of the auto update version is checked and pinned by the reconcile of the helm operation,
+	// the version blocked by the digest change policy of the repo is not chosen
+	changed, err := blockedDigestChange(ctx, r.Client, key, req.ChartName, req.ChartVersion)
 	if err != nil {
-		// if repo not found ,do not process this operation
-		//todo update status for this helmOperation , the chart version not exist
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
-	}
-	chartOptions := &actions.ChartOpts{
-		ChartName:    req.ChartName,
-		ChartVersion: req.ChartVersion,
-	}
-	switch pathType {
-	case "file":
-		chartOptions.LocalPath = url
-	case "http":
-		chartOptions.ChartURL = url
-	}
-
-	var values = release.Config
-	var installChartVersion = release.Chart.Metadata.Version
-	// if version change or value changes do update process
-	if installChartVersion == req.ChartVersion {
-		if helmOperation.Status.CurrentChartVersion != installChartVersion {
-			helmOperation.Status.CurrentChartVersion = installChartVersion
-			helmOperation.Status.ReleaseStatus = string(release.Info.Status)
-			err = r.Client.Status().Update(ctx, helmOperation)
-			if err != nil {
-				// if repo not found ,do not process this operation
-				//todo update status for this helmOperation , the chart version not exist
-				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
-			}
-			return ctrl.Result{}, nil
-		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
-	if helmOperation.Spec.ChartVersion != req.ChartVersion &&
-		helmOperation.Status.CurrentChartVersion != req.ChartVersion &&
-		installChartVersion != req.ChartVersion {
-		updateConfig := helmOperation.Spec.Upgrade
-		chart := *chartOptions
-		chart.ChartVersion = req.ChartVersion
-		updateOption := actions.UpgradeOptions{
-			Values:                   values,
-			Install:                  updateConfig.Install,
-			Devel:                    updateConfig.Devel,
-			Namespace:                helmOperation.Namespace,
-			SkipCRDs:                 updateConfig.SkipCRDs,
-			Timeout:                  updateConfig.Timeout,
-			Wait:                     updateConfig.Wait,
-			DisableHooks:             updateConfig.DisableHooks,
-			Force:                    updateConfig.Force,
-			ResetValues:              updateConfig.ResetValues,
-			ReuseValues:              updateConfig.ReuseValues,
-			Recreate:                 updateConfig.Recreate,
-			MaxHistory:               updateConfig.MaxHistory,
-			Atomic:                   updateConfig.Atomic,
-			CleanupOnFail:            updateConfig.CleanupOnFail,
-			SubNotes:                 updateConfig.SubNotes,
-			Description:              updateConfig.Description,
-			DisableOpenAPIValidation: updateConfig.DisableOpenAPIValidation,
-			WaitForJobs:              updateConfig.WaitForJobs,
-			ChartOpts:                &chart,
-			KubernetesOptions:        actions.NewKubernetesClient(actions.WithRestConfig(r.RestConfig)),
-			UpgradeCRDs:              updateConfig.UpgradeCRDs,
-		}
-		release, err = updateOption.Run()
-		if err != nil {
-			r.Log.Error(err, "upgrade release user helm client error")
-			return ctrl.Result{RequeueAfter: 10 * time.Second}, err
-		}
-		helmOperation.Status.CurrentChartVersion = release.Chart.Metadata.Version
-		helmOperation.Status.ReleaseStatus = string(release.Info.Status)
-		err = r.Client.Status().Update(ctx, helmOperation)
-		if err != nil {
-			// if repo not found ,do not process this operation
-			//todo update status for this helmOperation , the chart version not exist
-			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
-		}
+	if changed != nil {
+		r.Log.Info("skip auto update to the chart version blocked by the digest change policy", "repo", req.ChartRepo,
+			"chart", req.ChartName, "version", req.ChartVersion, "digest", changed.Digest)
+		return ctrl.Result{}, nil
+	}
+	helmOperation.Status.AutoUpdateChartVersion = req.ChartVersion
+	if err = r.Client.Status().Update(ctx, helmOperation); err != nil {
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
 	return ctrl.Result{}, nil
 }
@@ -313,7 +252,7 @@ func (r *HelmRepoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	log.Info("Watch repo reconciler event ", "ResourceName", req.Name)
 	// your logic here
 	helmRepo := &helmopsv1alpha1.HelmRepo{}
-	err := r.Client.Get(ctx, types.NamespacedName{Name: req.Name}, helmRepo)
+	err := r.Client.Get(ctx, req.NamespacedName, helmRepo)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			return ctrl.Result{}, nil
@@ -321,30 +260,67 @@ func (r *HelmRepoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		log.Error(err, "find repo resource from client error", "ResourceName", req.Name)
 		return ctrl.Result{}, err
 	}
-	if !helmRepo.DeletionTimestamp.IsZero() {
-		if !controllerutil.ContainsFinalizer(helmRepo, helmRepoFinalizer) {
-			controllerutil.AddFinalizer(helmRepo, helmRepoFinalizer)
-			err = r.Client.Update(ctx, helmRepo)
+	key := repoKey{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: helmRepo.Namespace, Name: helmRepo.Name}
+	return r.reconcileRepo(ctx, log, helmRepo, &helmRepo.Spec, &helmRepo.Status, key)
+}
+
+// reconcileRepo start the sync job of the HelmRepo or ClusterHelmRepo, the spec and status are the fields of the obj
+func (r *HelmRepoReconciler) reconcileRepo(ctx context.Context, log logr.Logger, obj client.Object,
+	spec *helmopsv1alpha1.HelmRepoSpec, status *helmopsv1alpha1.HelmRepoStatus, key repoKey) (ctrl.Result, error) {
+	var err error
+	if obj.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(obj, helmRepoFinalizer) {
+			controllerutil.AddFinalizer(obj, helmRepoFinalizer)
+			err = r.Client.Update(ctx, obj)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 		}
 	} else {
-		if err = r.removeFinalizer(ctx, helmRepo); err != nil {
+		if !controllerutil.ContainsFinalizer(obj, helmRepoFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err = r.removeFinalizer(key); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(obj, helmRepoFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, obj)
+	}
+	var oldStatus = status.DeepCopy()
+	status.Suspension, status.Conditions = suspendStatus(obj, spec.Suspend, status.Conditions)
+	if !reflect.DeepEqual(oldStatus, status) {
+		if err = r.Client.Status().Update(ctx, obj); err != nil {
 			return ctrl.Result{}, err
 		}
-		controllerutil.RemoveFinalizer(helmRepo, helmRepoFinalizer)
 	}
-	repo, err := charts.NewChartRepo(helmRepo.Name,
-		string(helmRepo.Spec.RepoType), helmRepo.Spec.RepoURL, helmRepo.Spec.Username,
-		helmRepo.Spec.Password, helmRepo.Spec.GitAuthToken, helmRepo.Spec.GitBranch,
-		r.LocalCachePath, helmRepo.Spec.InsecureSkipTLS, r.Period)
+	if spec.Suspend {
+		// the sync job keep running for the chart cache, only the auto update is stopped
+		log.Info("the helm repo is suspended, the auto update is stopped")
+	}
+	repoOptions, err := newRepoOptions(ctx, r.Client, spec, key.Namespace)
 	if err != nil {
-		log.Error(err, "create repo error", "ResourceName", req.Name)
+		log.Error(err, "create repo options error", "repo", key.String())
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
-	repo.StartTimerJobs(r.repoCallBack)
-	repoCache.Store(helmRepo.Name, repo)
+	// the repo name is the key, so the repos with the same name in different namespaces not conflict
+	repo, err := charts.NewChartRepo(key.String(),
+		string(spec.RepoType), spec.RepoURL, spec.Username,
+		spec.Password, spec.GitAuthToken, spec.GitBranch,
+		r.LocalCachePath, spec.InsecureSkipTLS, r.Period, repoOptions...)
+	if err != nil {
+		log.Error(err, "create repo error", "repo", key.String())
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+	// stop the sync job of the old repo config before start the new one
+	if err = r.stopRepoJob(key); err != nil {
+		return ctrl.Result{}, err
+	}
+	repo.OnSync = func(chartVersions map[string]utils.CommonChartVersions, err error) {
+		chartDigests.Delete(key.String())
+		r.updateSyncStatus(key, repo, chartVersions, err)
+	}
+	go repo.StartTimerJobs(r.repoCallBack)
+	repoCache.Store(key.String(), repo)
 
 	return ctrl.Result{}, nil
 }
@@ -353,16 +329,35 @@ func (r *HelmRepoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 func (r *HelmRepoReconciler) repoCallBack(chart *utils.CommonChartVersion, err error) {
 	if err != nil {
 		r.Log.Error(err, "repo sybc call back return error")
+		return
+	}
+	key, err := parseRepoKey(chart.RepoName)
+	if err != nil {
+		r.Log.Error(err, "parse repo key error", "repo", chart.RepoName)
+		return
+	}
+	spec, _, err := getRepoSpec(context.Background(), r.Client, key)
+	if err != nil {
+		r.Log.Error(err, "get helm repo error", "repo", chart.RepoName)
+		return
+	}
+	if spec.Suspend {
+		return
 	}
 	var operationList = &helmopsv1alpha1.HelmOperationList{}
-	err = r.List(context.Background(), operationList)
+	// the HelmRepo is only visible to the helm operations in its namespace
+	err = r.List(context.Background(), operationList, client.InNamespace(key.Namespace))
 	if err != nil {
 		r.Log.Error(err, "list helm operation error")
 		return
 	}
 	for _, item := range operationList.Items {
+		if operationRepoKey(&item) == key && item.Spec.ChartName == chart.Name &&
+			utils.GetVersionGreaterThan(chart.Version, desiredChartVersion(&item)) {
+			r.Dispatcher.NotifyNewVersion(&item, chart.Version)
+		}
 		if item.Spec.AutoUpdate &&
-			item.Spec.ChartRepoName == chart.RepoName &&
+			operationRepoKey(&item) == key &&
 			item.Spec.ChartName == chart.Name &&
 			item.Spec.ChartVersion != chart.Version {
 			r.queue.Add(syncUpdateHelmRelease{
@@ -376,16 +371,137 @@ func (r *HelmRepoReconciler) repoCallBack(chart *utils.CommonChartVersion, err e
 	}
 }
 
+// updateSyncStatus report the circuit breaker state, the index refresh time, the chart catalog and the changed
+// digests of the repo after each sync, the catalog is kept if the sync failed, the status is only updated when changed
+func (r *HelmRepoReconciler) updateSyncStatus(key repoKey, repo *charts.ChartRepo,
+	chartVersions map[string]utils.CommonChartVersions, syncErr error) {
+	var breakerStatus *helmopsv1alpha1.CircuitBreakerStatus
+	if repo.Breaker != nil {
+		snapshot := repo.Breaker.Snapshot()
+		breakerStatus = &helmopsv1alpha1.CircuitBreakerStatus{
+			State:               string(snapshot.State),
+			ConsecutiveFailures: snapshot.ConsecutiveFailures,
+			LastError:           snapshot.LastError,
+		}
+		if !snapshot.TransitionTime.IsZero() {
+			transitionTime := metav1.NewTime(snapshot.TransitionTime.Truncate(time.Second))
+			breakerStatus.LastTransitionTime = &transitionTime
+		}
+	}
+	var indexRefreshTime *metav1.Time
+	if refresher, ok := repo.Operation.(charts.IndexRefresher); ok && !refresher.IndexRefreshTime().IsZero() {
+		refreshTime := metav1.NewTime(refresher.IndexRefreshTime().Truncate(time.Second))
+		indexRefreshTime = &refreshTime
+	}
+	ctx := context.Background()
+	obj, spec, status, err := getRepoObject(ctx, r.Client, key)
+	if err != nil {
+		r.Log.Error(err, "get helm repo error", "repo", key.String())
+		return
+	}
+	var catalog = status.Catalog
+	var changedDigests = status.ChangedDigests
+	if syncErr == nil {
+		now := metav1.NewTime(time.Now().Truncate(time.Second))
+		catalog = newChartCatalog(status.Catalog, chartVersions, now)
+		changedDigests = r.detectDigestChanges(ctx, key, spec.DigestChangePolicy, status.ChangedDigests, chartVersions, now)
+	}
+	if breakerStatusEqual(status.CircuitBreaker, breakerStatus) && status.IndexRefreshTime.Equal(indexRefreshTime) &&
+		reflect.DeepEqual(status.Catalog, catalog) && reflect.DeepEqual(status.ChangedDigests, changedDigests) {
+		return
+	}
+	status.CircuitBreaker = breakerStatus
+	status.IndexRefreshTime = indexRefreshTime
+	status.Catalog = catalog
+	status.ChangedDigests = changedDigests
+	if err := r.Client.Status().Update(ctx, obj); err != nil {
+		r.Log.Error(err, "update repo sync status error", "repo", key.String())
+	}
+}
+
+// newChartCatalog summarize the chart versions of the repo, the last changed time of the chart is kept from
+// the previous catalog if the latest version and the version count not changed
+func newChartCatalog(previous *helmopsv1alpha1.ChartCatalog, chartVersions map[string]utils.CommonChartVersions,
+	now metav1.Time) *helmopsv1alpha1.ChartCatalog {
+	var previousCharts = map[string]helmopsv1alpha1.CatalogChart{}
+	if previous != nil {
+		for _, item := range previous.Charts {
+			previousCharts[item.Name] = item
+		}
+	}
+	var names []string
+	for name, versions := range chartVersions {
+		if len(versions) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var catalog = &helmopsv1alpha1.ChartCatalog{ChartCount: int32(len(names))}
+	for _, name := range names {
+		versions := chartVersions[name]
+		catalog.VersionCount += int32(len(versions))
+		if len(catalog.Charts) >= helmopsv1alpha1.MaxCatalogCharts {
+			catalog.Truncated = true
+			continue
+		}
+		sort.Sort(versions)
+		item := helmopsv1alpha1.CatalogChart{
+			Name:          name,
+			LatestVersion: versions[len(versions)-1].Version,
+			VersionCount:  int32(len(versions)),
+			LastChanged:   now.DeepCopy(),
+		}
+		if old, ok := previousCharts[name]; ok && old.LatestVersion == item.LatestVersion &&
+			old.VersionCount == item.VersionCount && old.LastChanged != nil {
+			item.LastChanged = old.LastChanged
+		}
+		catalog.Charts = append(catalog.Charts, item)
+	}
+	return catalog
+}
+
+// breakerStatusEqual compare the breaker status, the time is compared in seconds like it is stored
+func breakerStatusEqual(a, b *helmopsv1alpha1.CircuitBreakerStatus) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.State == b.State && a.ConsecutiveFailures == b.ConsecutiveFailures && a.LastError == b.LastError &&
+		a.LastTransitionTime.Equal(b.LastTransitionTime)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *HelmRepoReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// the workers process the auto update jobs from the repo sync call back
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		r.startUpdateProcess(ctx)
+		<-ctx.Done()
+		r.queue.ShutDown()
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+	// the status updated by the sync jobs must not restart the sync jobs
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&helmopsv1alpha1.HelmRepo{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
 		Complete(r)
 }
 
 //removeFinalizer Stop the job ,do not delete installed helm release
-func (r *HelmRepoReconciler) removeFinalizer(ctx context.Context, helmRepo *helmopsv1alpha1.HelmRepo) error {
-	repoInfo, ok := repoCache.Load(helmRepo.Name)
+func (r *HelmRepoReconciler) removeFinalizer(key repoKey) error {
+	if err := r.stopRepoJob(key); err != nil {
+		return err
+	}
+	metrics.DeleteRepo(key.String())
+	repoDigests.Delete(key.String())
+	chartDigests.Delete(key.String())
+	return nil
+}
+
+//stopRepoJob stop the sync job of the repo and remove it from the cache
+func (r *HelmRepoReconciler) stopRepoJob(key repoKey) error {
+	repoInfo, ok := repoCache.Load(key.String())
 	if !ok {
 		return nil
 	}
@@ -396,5 +512,6 @@ func (r *HelmRepoReconciler) removeFinalizer(ctx context.Context, helmRepo *helm
 		return errors.New("convert repo item to cache")
 	}
 	chartRepo.Close()
+	repoCache.Delete(key.String())
 	return nil
 }
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;impersonate
+
+// newKubernetesClient create the kubernetes client for the helm actions of the operation,
+// if the operation reference a kubeconfig secret the client connect to the remote cluster,
+// otherwise use the rest config of the manager.
+// if the operation set the service account, the client impersonate it
+func newKubernetesClient(ctx context.Context, c client.Client, restConfig *rest.Config,
+	operation *helmopsv1alpha1.HelmOperation) (*actions.KubernetesClient, error) {
+	var opts []actions.Option
+	if username := operation.GetServiceAccountUsername(); username != "" {
+		if operation.Spec.KubeConfigSecretRef == nil {
+			// the service account in the remote cluster can not be checked before the request
+			serviceAccount := &corev1.ServiceAccount{}
+			err := c.Get(ctx, types.NamespacedName{Namespace: operation.Namespace, Name: operation.Spec.ServiceAccountName}, serviceAccount)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// the api server add the groups of the service account when impersonate it
+		opts = append(opts, actions.WithImpersonate(rest.ImpersonationConfig{UserName: username}))
+	}
+	secretRef := operation.Spec.KubeConfigSecretRef
+	if secretRef == nil {
+		opts = append(opts, actions.WithRestConfig(restConfig))
+		return actions.NewKubernetesClient(opts...), nil
+	}
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: operation.Namespace, Name: secretRef.Name}, secret)
+	if err != nil {
+		return nil, err
+	}
+	var key = secretRef.Key
+	if key == "" {
+		key = helmopsv1alpha1.DefaultKubeConfigSecretKey
+	}
+	data, ok := secret.Data[key]
+	if !ok || len(data) == 0 {
+		return nil, fmt.Errorf("kubeconfig key %s not found in secret %s/%s", key, secret.Namespace, secret.Name)
+	}
+	// ToRawKubeConfigLoader falls back to the in cluster config when the kubeconfig is invalid,
+	// check it here so a broken secret never installs the release to the local cluster
+	if _, err = clientcmd.Load(data); err != nil {
+		return nil, errors.Wrapf(err, "load kubeconfig from secret %s/%s error", secret.Namespace, secret.Name)
+	}
+	opts = append(opts, actions.WithConfigString(string(data)),
+		actions.WithClientName(fmt.Sprintf("%s-%s", secret.Namespace, secret.Name)))
+	return actions.NewKubernetesClient(opts...), nil
+}
+
+// checkClusterHealth check the target cluster is reachable and set the ClusterReady condition
+func checkClusterHealth(kubeClient *actions.KubernetesClient, operation *helmopsv1alpha1.HelmOperation) error {
+	versionInfo, err := kubeClient.ServerVersion()
+	if err != nil {
+		operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+			Type:    helmopsv1alpha1.ConditionTypeClusterReady,
+			Status:  helmopsv1alpha1.ConditionStatusFalse,
+			Reason:  "ClusterUnreachable",
+			Message: err.Error(),
+		})
+		return err
+	}
+	operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeClusterReady,
+		Status:  helmopsv1alpha1.ConditionStatusTrue,
+		Reason:  "ClusterReachable",
+		Message: fmt.Sprintf("kubernetes version %s", versionInfo.GitVersion),
+	})
+	return nil
+}
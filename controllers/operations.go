@@ -0,0 +1,332 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/metrics"
+)
+
+// manualOperation the operation triggered by an annotation, handled once for each annotation value
+type manualOperation struct {
+	annotation string
+	name       string
+	event      string
+	handled    func(status *helmopsv1alpha1.HelmOperationStatus) *string
+	run        func(r *HelmOperationReconciler, ctx context.Context, operation *helmopsv1alpha1.HelmOperation,
+		kubeClient *actions.KubernetesClient, value string) (*release.Release, error)
+}
+
+// manualOperations the operations in priority order, only one operation is handled in a reconcile
+var manualOperations = []manualOperation{
+	{
+		annotation: helmopsv1alpha1.ReinstallAnnotation,
+		name:       "Reinstall",
+		event:      helmopsv1alpha1.AlertEventInstalled,
+		handled: func(status *helmopsv1alpha1.HelmOperationStatus) *string {
+			return &status.LastHandledReinstall
+		},
+		run: (*HelmOperationReconciler).reinstallRelease,
+	},
+	{
+		annotation: helmopsv1alpha1.RollbackToRevisionAnnotation,
+		name:       "Rollback",
+		event:      helmopsv1alpha1.AlertEventRolledBack,
+		handled: func(status *helmopsv1alpha1.HelmOperationStatus) *string {
+			return &status.LastHandledRollbackTo
+		},
+		run: (*HelmOperationReconciler).rollbackRelease,
+	},
+	{
+		annotation: helmopsv1alpha1.ForceUpgradeAnnotation,
+		name:       "ForceUpgrade",
+		event:      helmopsv1alpha1.AlertEventUpgraded,
+		handled: func(status *helmopsv1alpha1.HelmOperationStatus) *string {
+			return &status.LastHandledForceUpgrade
+		},
+		run: (*HelmOperationReconciler).forceUpgradeRelease,
+	},
+}
+
+// handleManualOperations run the operation of the first annotation which value not handled,
+// the value is echoed to the status after the operation succeeded so the operation runs exactly once,
+// the failed operation is retried with the backoff of the reconcile.
+// the manual operations are not handled while the helm operation is suspended, see suspendManualOperations.
+// return true if an operation handled and the reconcile should return with the result
+func (r *HelmOperationReconciler) handleManualOperations(ctx context.Context, log logr.Logger,
+	kubeClient *actions.KubernetesClient, operation *helmopsv1alpha1.HelmOperation) (bool, ctrl.Result, error) {
+	annotations := operation.GetAnnotations()
+	if value, ok := annotations[helmopsv1alpha1.ReconcileAtAnnotation]; ok && value != operation.Status.LastHandledReconcileAt {
+		// the reconcile is already triggered by the annotation change, only record it
+		operation.Status.LastHandledReconcileAt = value
+		if err := r.Client.Status().Update(ctx, operation); err != nil {
+			return true, ctrl.Result{}, err
+		}
+	}
+	for _, item := range manualOperations {
+		value, pending := pendingManualOperation(operation, item)
+		if !pending {
+			continue
+		}
+		log.Info("run manual operation", "operation", item.name, "value", value)
+		rel, err := item.run(r, ctx, operation, kubeClient, value)
+		var previousMessage string
+		if previous := helmopsv1alpha1.GetCondition(operation.Status.Conditions,
+			helmopsv1alpha1.ConditionTypeManualOperation); previous != nil {
+			previousMessage = previous.Message
+		}
+		var condition = helmopsv1alpha1.Condition{
+			Type:    helmopsv1alpha1.ConditionTypeManualOperation,
+			Status:  helmopsv1alpha1.ConditionStatusTrue,
+			Reason:  item.name + "Succeeded",
+			Message: fmt.Sprintf("%s %s succeeded", item.annotation, value),
+		}
+		if err != nil {
+			log.Error(err, "run manual operation error", "operation", item.name, "value", value)
+			condition.Status = helmopsv1alpha1.ConditionStatusFalse
+			condition.Reason = item.name + "Failed"
+			condition.Message = fmt.Sprintf("%s %s failed: %v", item.annotation, value, err)
+		}
+		operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, condition)
+		if err == nil {
+			*item.handled(&operation.Status) = value
+		}
+		if err == nil && rel != nil {
+			recordRevision(ctx, r.Client, r.Scheme, log, operation, rel, item.name, appliedBy(operation))
+			r.Dispatcher.Notify(operation, item.event, rel, condition.Message)
+		} else if err != nil && previousMessage != condition.Message {
+			// the failed operation is retried, alert once for the same failure
+			r.Dispatcher.Notify(operation, helmopsv1alpha1.AlertEventFailed, rel, condition.Message)
+		}
+		if rel != nil {
+			// the fingerprint is kept, the release not upgraded back until the spec changed
+			markReleaseApplied(operation, rel, "")
+			operation.Status.CurrentChartVersion = rel.Chart.Metadata.Version
+			operation.Status.ReleaseStatus = string(rel.Info.Status)
+			markHealthProgressing(operation, fmt.Sprintf("the release %s, waiting for the health assessment", item.name))
+			setReadyCondition(operation, rel)
+		}
+		if updateErr := r.Client.Status().Update(ctx, operation); updateErr != nil {
+			return true, ctrl.Result{}, updateErr
+		}
+		if err != nil {
+			return true, ctrl.Result{}, err
+		}
+		return true, ctrl.Result{RequeueAfter: healthCheckPeriod}, nil
+	}
+	return false, ctrl.Result{}, nil
+}
+
+// pendingManualOperation get the annotation value of the manual operation which not handled
+func pendingManualOperation(operation *helmopsv1alpha1.HelmOperation, item manualOperation) (string, bool) {
+	value, ok := operation.GetAnnotations()[item.annotation]
+	if !ok || value == "" || value == *item.handled(&operation.Status) {
+		return "", false
+	}
+	return value, true
+}
+
+// suspendManualOperations show the manual operation which not handled because the helm operation is suspended,
+// the operation runs after the helm operation resumed
+func suspendManualOperations(operation *helmopsv1alpha1.HelmOperation) {
+	for _, item := range manualOperations {
+		value, pending := pendingManualOperation(operation, item)
+		if !pending {
+			continue
+		}
+		operation.Status.Conditions = helmopsv1alpha1.SetCondition(operation.Status.Conditions, helmopsv1alpha1.Condition{
+			Type:    helmopsv1alpha1.ConditionTypeManualOperation,
+			Status:  helmopsv1alpha1.ConditionStatusFalse,
+			Reason:  item.name + "Suspended",
+			Message: fmt.Sprintf("%s %s is pending until the helm operation resumed", item.annotation, value),
+		})
+		return
+	}
+}
+
+// getOwnedRelease get the release which owned by the helm operation, return nil if the release not found
+func getOwnedRelease(operation *helmopsv1alpha1.HelmOperation, kubeClient *actions.KubernetesClient) (*release.Release, error) {
+	var getOptions = actions.GetOptions{
+		ReleaseName:       operation.GetReleaseName(),
+		Namespace:         operation.GetReleaseNamespace(),
+		KubernetesOptions: kubeClient,
+	}
+	rel, err := getOptions.Run()
+	if err != nil {
+		if err == driver.ErrReleaseNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !operation.IsReleaseOwned() {
+		return nil, errors.Errorf("release %s/%s is not owned by the helm operation", rel.Namespace, rel.Name)
+	}
+	return rel, nil
+}
+
+// reinstallRelease uninstall the release if it exists, then install it again
+func (r *HelmOperationReconciler) reinstallRelease(ctx context.Context, operation *helmopsv1alpha1.HelmOperation,
+	kubeClient *actions.KubernetesClient, value string) (*release.Release, error) {
+	chartOptions, err := resolveChartOptions(ctx, r.Client, operation)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := getOwnedRelease(operation, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if rel != nil {
+		uninstallConfig := operation.Spec.Uninstall
+		uninstall := actions.UninstallOptions{
+			Description:       uninstallConfig.Description,
+			Timeout:           uninstallConfig.Timeout,
+			DisableHooks:      uninstallConfig.DisableHooks,
+			Namespace:         operation.GetReleaseNamespace(),
+			ReleaseName:       operation.GetReleaseName(),
+			KubernetesOptions: kubeClient,
+		}
+		if _, err = runUninstall(uninstall); err != nil {
+			return nil, errors.Wrap(err, "uninstall release error")
+		}
+	}
+	// the release is still owned if the install failed, the status is updated even the operation failed
+	markReleaseOwned(operation, "Installing", fmt.Sprintf("the release is reinstalling by %s", helmopsv1alpha1.ReinstallAnnotation))
+	installOptions := newInstallOptions(operation, kubeClient, chartOptions)
+	rel, err = runInstall(installOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "install release error")
+	}
+	markReleaseOwned(operation, "Installed", fmt.Sprintf("the release reinstalled by %s", helmopsv1alpha1.ReinstallAnnotation))
+	operation.Status.RolledBackGeneration = 0
+	return rel, nil
+}
+
+// rollbackRelease rollback the release to the revision in the annotation value
+func (r *HelmOperationReconciler) rollbackRelease(ctx context.Context, operation *helmopsv1alpha1.HelmOperation,
+	kubeClient *actions.KubernetesClient, value string) (*release.Release, error) {
+	revision, err := helmopsv1alpha1.ParseRollbackToRevision(value)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := getOwnedRelease(operation, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if rel == nil {
+		return nil, driver.ErrReleaseNotFound
+	}
+	rel, err = rollbackToRevision(operation, kubeClient, revision)
+	if err != nil {
+		return nil, err
+	}
+	// keep the rolled back chart version, the auto update version is found again after the spec changed
+	operation.Status.RolledBackGeneration = operation.Generation
+	operation.Status.AutoUpdateChartVersion = ""
+	return rel, nil
+}
+
+// rolledBack check the release is manually rolled back and the spec not changed after the rollback
+func rolledBack(operation *helmopsv1alpha1.HelmOperation) bool {
+	return operation.Status.RolledBackGeneration != 0 && operation.Status.RolledBackGeneration == operation.Generation
+}
+
+// rollbackToRevision rollback the release to the revision with the upgrade options, return the rolled back release
+func rollbackToRevision(operation *helmopsv1alpha1.HelmOperation, kubeClient *actions.KubernetesClient,
+	revision int) (*release.Release, error) {
+	var updateConfig = operation.Spec.Upgrade
+	rollback := actions.RollBackOptions{
+		Namespace:         operation.GetReleaseNamespace(),
+		ReleaseName:       operation.GetReleaseName(),
+		KubernetesOptions: kubeClient,
+		Version:           revision,
+		Timeout:           updateConfig.Timeout,
+		Wait:              updateConfig.Wait,
+		Recreate:          updateConfig.Recreate,
+		CleanupOnFail:     updateConfig.CleanupOnFail,
+	}
+	if err := runRollback(rollback); err != nil {
+		return nil, errors.Wrapf(err, "rollback release to revision %d error", revision)
+	}
+	return getOwnedRelease(operation, kubeClient)
+}
+
+// runInstall install the release and record the release action metrics
+func runInstall(options actions.InstallOptions) (*release.Release, error) {
+	start := time.Now()
+	rel, err := options.Run()
+	metrics.ObserveReleaseAction(metrics.ActionInstall, start, err)
+	return rel, err
+}
+
+// runUpgrade upgrade the release and record the release action metrics
+func runUpgrade(options actions.UpgradeOptions) (*release.Release, error) {
+	start := time.Now()
+	rel, err := options.Run()
+	metrics.ObserveReleaseAction(metrics.ActionUpgrade, start, err)
+	return rel, err
+}
+
+// runRollback rollback the release and record the release action metrics
+func runRollback(options actions.RollBackOptions) error {
+	start := time.Now()
+	err := options.Run()
+	metrics.ObserveReleaseAction(metrics.ActionRollback, start, err)
+	return err
+}
+
+// runUninstall uninstall the release and record the release action metrics
+func runUninstall(options actions.UninstallOptions) (*release.UninstallReleaseResponse, error) {
+	start := time.Now()
+	resp, err := options.Run()
+	metrics.ObserveReleaseAction(metrics.ActionUninstall, start, err)
+	return resp, err
+}
+
+// forceUpgradeRelease upgrade the release with force even the chart version and values not changed
+func (r *HelmOperationReconciler) forceUpgradeRelease(ctx context.Context, operation *helmopsv1alpha1.HelmOperation,
+	kubeClient *actions.KubernetesClient, value string) (*release.Release, error) {
+	chartOptions, err := resolveChartOptions(ctx, r.Client, operation)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := getOwnedRelease(operation, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if rel == nil {
+		return nil, driver.ErrReleaseNotFound
+	}
+	upgradeOptions := newUpgradeOptions(operation, kubeClient, chartOptions)
+	upgradeOptions.Force = true
+	rel, err = runUpgrade(upgradeOptions)
+	if err != nil {
+		return nil, err
+	}
+	operation.Status.RolledBackGeneration = 0
+	return rel, nil
+}
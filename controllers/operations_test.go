@@ -0,0 +1,91 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+func Test_pendingManualOperation(t *testing.T) {
+	var rollback manualOperation
+	for _, item := range manualOperations {
+		if item.annotation == helmopsv1alpha1.RollbackToRevisionAnnotation {
+			rollback = item
+		}
+	}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		handled     string
+		// handledReinstall the same value handled by the reinstall not affect the rollback
+		handledReinstall string
+		want             string
+		wantPending      bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:        "empty annotation",
+			annotations: map[string]string{helmopsv1alpha1.RollbackToRevisionAnnotation: ""},
+		},
+		{
+			name:        "new request",
+			annotations: map[string]string{helmopsv1alpha1.RollbackToRevisionAnnotation: "2@2021-06-01T08:30:00Z"},
+			want:        "2@2021-06-01T08:30:00Z",
+			wantPending: true,
+		},
+		{
+			name:        "already handled",
+			annotations: map[string]string{helmopsv1alpha1.RollbackToRevisionAnnotation: "2@2021-06-01T08:30:00Z"},
+			handled:     "2@2021-06-01T08:30:00Z",
+		},
+		{
+			name:        "same revision requested again",
+			annotations: map[string]string{helmopsv1alpha1.RollbackToRevisionAnnotation: "2@2021-06-01T09:00:00Z"},
+			handled:     "2@2021-06-01T08:30:00Z",
+			want:        "2@2021-06-01T09:00:00Z",
+			wantPending: true,
+		},
+		{
+			name:             "other operation handled",
+			annotations:      map[string]string{helmopsv1alpha1.RollbackToRevisionAnnotation: "3"},
+			handledReinstall: "3",
+			want:             "3",
+			wantPending:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation := &helmopsv1alpha1.HelmOperation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", Annotations: tt.annotations},
+				Status: helmopsv1alpha1.HelmOperationStatus{
+					LastHandledRollbackTo: tt.handled,
+					LastHandledReinstall:  tt.handledReinstall,
+				},
+			}
+			got, pending := pendingManualOperation(operation, rollback)
+			if got != tt.want || pending != tt.wantPending {
+				t.Errorf("pendingManualOperation() = %s, %v, want %s, %v", got, pending, tt.want, tt.wantPending)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// repoKey the key of the chart repo in the repo cache, the namespace is empty for the ClusterHelmRepo,
+// the string format `{kind}/{namespace}/{name}` is also the name of the chart repo and the git cache dir
+type repoKey struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (k repoKey) String() string {
+	return path.Join(k.Kind, k.Namespace, k.Name)
+}
+
+// NamespacedName the name to get the repo resource
+func (k repoKey) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Namespace: k.Namespace, Name: k.Name}
+}
+
+// parseRepoKey parse the repo key from the name of the chart repo
+func parseRepoKey(value string) (repoKey, error) {
+	parts := strings.Split(value, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == helmopsv1alpha1.ClusterHelmRepoKind:
+		return repoKey{Kind: parts[0], Name: parts[1]}, nil
+	case len(parts) == 3 && parts[0] == helmopsv1alpha1.HelmRepoKind:
+		return repoKey{Kind: parts[0], Namespace: parts[1], Name: parts[2]}, nil
+	}
+	return repoKey{}, errors.Errorf("repo key %s is invalid", value)
+}
+
+// operationRepoKey the key of the chart repo which the helm operation reference
+func operationRepoKey(operation *helmopsv1alpha1.HelmOperation) repoKey {
+	return chartRepoKey(operation.GetChartRepoRef())
+}
+
+// chartRepoKey the key of the chart repo of the resolved reference
+func chartRepoKey(ref helmopsv1alpha1.ChartRepoReference) repoKey {
+	if ref.Kind == helmopsv1alpha1.ClusterHelmRepoKind {
+		return repoKey{Kind: ref.Kind, Name: ref.Name}
+	}
+	return repoKey{Kind: ref.Kind, Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// getRepoSpec get the spec of the HelmRepo or ClusterHelmRepo, the ClusterHelmRepo is returned for the allowed namespaces
+func getRepoSpec(ctx context.Context, c client.Client, key repoKey) (*helmopsv1alpha1.HelmRepoSpec, *helmopsv1alpha1.ClusterHelmRepo, error) {
+	if key.Kind == helmopsv1alpha1.ClusterHelmRepoKind {
+		clusterHelmRepo := &helmopsv1alpha1.ClusterHelmRepo{}
+		if err := c.Get(ctx, key.NamespacedName(), clusterHelmRepo); err != nil {
+			return nil, nil, err
+		}
+		return &clusterHelmRepo.Spec.HelmRepoSpec, clusterHelmRepo, nil
+	}
+	helmRepo := &helmopsv1alpha1.HelmRepo{}
+	if err := c.Get(ctx, key.NamespacedName(), helmRepo); err != nil {
+		return nil, nil, err
+	}
+	return &helmRepo.Spec, nil, nil
+}
+
+// getRepoObject get the HelmRepo or ClusterHelmRepo with the spec and the status of it
+func getRepoObject(ctx context.Context, c client.Client, key repoKey) (client.Object, *helmopsv1alpha1.HelmRepoSpec,
+	*helmopsv1alpha1.HelmRepoStatus, error) {
+	if key.Kind == helmopsv1alpha1.ClusterHelmRepoKind {
+		clusterHelmRepo := &helmopsv1alpha1.ClusterHelmRepo{}
+		if err := c.Get(ctx, key.NamespacedName(), clusterHelmRepo); err != nil {
+			return nil, nil, nil, err
+		}
+		return clusterHelmRepo, &clusterHelmRepo.Spec.HelmRepoSpec, &clusterHelmRepo.Status, nil
+	}
+	helmRepo := &helmopsv1alpha1.HelmRepo{}
+	if err := c.Get(ctx, key.NamespacedName(), helmRepo); err != nil {
+		return nil, nil, nil, err
+	}
+	return helmRepo, &helmRepo.Spec, &helmRepo.Status, nil
+}
+
+// checkRepoAccess check the helm operations in the namespace can use the chart repo,
+// the HelmRepo is only visible in its namespace and the ClusterHelmRepo in the allowed namespaces
+func checkRepoAccess(ctx context.Context, c client.Client, namespace string, key repoKey) error {
+	if key.Kind != helmopsv1alpha1.ClusterHelmRepoKind {
+		if key.Namespace != namespace {
+			return errors.Errorf("the %s is not visible to the namespace %s", key, namespace)
+		}
+		return nil
+	}
+	_, clusterHelmRepo, err := getRepoSpec(ctx, c, key)
+	if err != nil {
+		return err
+	}
+	if clusterHelmRepo.Spec.AllowedNamespaces == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(clusterHelmRepo.Spec.AllowedNamespaces)
+	if err != nil {
+		return err
+	}
+	ns := &corev1.Namespace{}
+	if err = c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return err
+	}
+	if !selector.Matches(labels.Set(ns.Labels)) {
+		return errors.Errorf("the namespace %s is not allowed to use the %s", namespace, key)
+	}
+	return nil
+}
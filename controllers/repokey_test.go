@@ -0,0 +1,202 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/charts"
+)
+
+// newTestRepoObjects the namespaces and the cluster helm repos for the repo access tests,
+// the `shared` repo is visible to all the namespaces and the `team` repo to the namespaces with label team=a
+func newTestRepoObjects() []client.Object {
+	return []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+		&helmopsv1alpha1.ClusterHelmRepo{ObjectMeta: metav1.ObjectMeta{Name: "shared"}},
+		&helmopsv1alpha1.ClusterHelmRepo{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: helmopsv1alpha1.ClusterHelmRepoSpec{
+				AllowedNamespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			},
+		},
+	}
+}
+
+func Test_parseRepoKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    repoKey
+		wantErr bool
+	}{
+		{
+			name:  "helm repo",
+			value: "HelmRepo/team-a/charts",
+			want:  repoKey{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "team-a", Name: "charts"},
+		},
+		{
+			name:  "cluster helm repo",
+			value: "ClusterHelmRepo/shared",
+			want:  repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "shared"},
+		},
+		{name: "helm repo without namespace", value: "HelmRepo/charts", wantErr: true},
+		{name: "cluster helm repo with namespace", value: "ClusterHelmRepo/team-a/shared", wantErr: true},
+		{name: "unknown kind", value: "ConfigMap/team-a/charts", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRepoKey(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRepoKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseRepoKey() = %+v, want %+v", got, tt.want)
+			}
+			// the key is the name of the chart repo, it must parse back from the string
+			if got.String() != tt.value {
+				t.Errorf("repoKey.String() = %s, want %s", got.String(), tt.value)
+			}
+		})
+	}
+}
+
+func Test_repoKeyRoundTrip(t *testing.T) {
+	keys := []repoKey{
+		{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "default", Name: "stable"},
+		{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "team-a", Name: "charts-v2"},
+		{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "shared"},
+		chartRepoKey(helmopsv1alpha1.ChartRepoReference{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Namespace: "team-a", Name: "shared"}),
+	}
+	for _, key := range keys {
+		got, err := parseRepoKey(key.String())
+		if err != nil {
+			t.Errorf("parseRepoKey(%s) error %v", key, err)
+			continue
+		}
+		if got != key {
+			t.Errorf("parseRepoKey(%s) = %+v, want %+v", key, got, key)
+		}
+	}
+}
+
+func Test_checkRepoAccess(t *testing.T) {
+	c, _ := newTestClient(t, newTestRepoObjects()...)
+	tests := []struct {
+		name      string
+		namespace string
+		key       repoKey
+		wantErr   bool
+	}{
+		{
+			name:      "helm repo in the same namespace",
+			namespace: "team-a",
+			key:       repoKey{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "team-a", Name: "charts"},
+		},
+		{
+			name:      "helm repo in another namespace",
+			namespace: "team-b",
+			key:       repoKey{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "team-a", Name: "charts"},
+			wantErr:   true,
+		},
+		{
+			name:      "cluster helm repo without allowed namespaces",
+			namespace: "team-b",
+			key:       repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "shared"},
+		},
+		{
+			name:      "cluster helm repo in allowed namespaces",
+			namespace: "team-a",
+			key:       repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "team"},
+		},
+		{
+			name:      "cluster helm repo outside allowed namespaces",
+			namespace: "team-b",
+			key:       repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "team"},
+			wantErr:   true,
+		},
+		{
+			name:      "cluster helm repo not found",
+			namespace: "team-a",
+			key:       repoKey{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "missing"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRepoAccess(context.Background(), c, tt.namespace, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkRepoAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_repoCacheResolver_findChartRepo(t *testing.T) {
+	c, _ := newTestClient(t, newTestRepoObjects()...)
+	var keys = []repoKey{
+		{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "team-a", Name: "charts"},
+		{Kind: helmopsv1alpha1.HelmRepoKind, Namespace: "team-a", Name: "shared"},
+		{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "shared"},
+		{Kind: helmopsv1alpha1.ClusterHelmRepoKind, Name: "team"},
+	}
+	for _, key := range keys {
+		repoCache.Store(key.String(), &charts.ChartRepo{Name: key.String()})
+	}
+	t.Cleanup(func() {
+		for _, key := range keys {
+			repoCache.Delete(key.String())
+		}
+	})
+	tests := []struct {
+		name      string
+		namespace string
+		repoName  string
+		want      string
+	}{
+		{name: "helm repo in the namespace", namespace: "team-a", repoName: "charts", want: "HelmRepo/team-a/charts"},
+		{name: "helm repo in another namespace", namespace: "team-b", repoName: "charts"},
+		{name: "helm repo before cluster helm repo", namespace: "team-a", repoName: "shared", want: "HelmRepo/team-a/shared"},
+		{name: "cluster helm repo", namespace: "team-b", repoName: "shared", want: "ClusterHelmRepo/shared"},
+		{name: "cluster helm repo in allowed namespaces", namespace: "team-a", repoName: "team", want: "ClusterHelmRepo/team"},
+		{name: "cluster helm repo outside allowed namespaces", namespace: "team-b", repoName: "team"},
+		{name: "repo not found", namespace: "team-a", repoName: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := repoCacheResolver{ctx: context.Background(), client: c, namespace: tt.namespace}
+			chartRepo, ok := resolver.findChartRepo(tt.repoName)
+			if ok != (tt.want != "") {
+				t.Fatalf("findChartRepo() found = %v, want %s", ok, tt.want)
+			}
+			if ok && chartRepo.Name != tt.want {
+				t.Errorf("findChartRepo() = %s, want %s", chartRepo.Name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+	"github.com/shijunLee/helmops/pkg/charts"
+	"github.com/shijunLee/helmops/pkg/charts/archive"
+	"github.com/shijunLee/helmops/pkg/charts/s3"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// newRepoOptions create the options of the repo types from the repo spec, the secrets and the chart configmaps
+// are read from the namespace of the repo, which is empty for the ClusterHelmRepo
+func newRepoOptions(ctx context.Context, c client.Client, spec *helmopsv1alpha1.HelmRepoSpec, namespace string) ([]charts.RepoOption, error) {
+	var opts []charts.RepoOption
+	var transport utils.TransportOptions
+	if spec.Timeout != nil {
+		transport.Timeout = spec.Timeout.Duration
+	}
+	if ref := spec.GetTLSSecretRef(); ref != nil {
+		secret, err := getRepoSecret(ctx, c, ref, namespace)
+		if err != nil {
+			return nil, err
+		}
+		transport.CAData = secret.Data[helmopsv1alpha1.TLSCASecretKey]
+		transport.CertData = secret.Data[helmopsv1alpha1.TLSCertSecretKey]
+		transport.KeyData = secret.Data[helmopsv1alpha1.TLSKeySecretKey]
+	}
+	if spec.Auth != nil {
+		config := utils.AuthConfig{
+			Type:     string(spec.Auth.Type),
+			Username: spec.Username,
+			Password: spec.Password,
+			TokenURL: spec.Auth.TokenURL,
+			Scopes:   spec.Auth.Scopes,
+		}
+		if ref := spec.Auth.SecretRef; ref != nil {
+			secret, err := getRepoSecret(ctx, c, ref, namespace)
+			if err != nil {
+				return nil, err
+			}
+			config.Username = string(secret.Data[helmopsv1alpha1.AuthUsernameSecretKey])
+			config.Password = string(secret.Data[helmopsv1alpha1.AuthPasswordSecretKey])
+			config.Token = string(secret.Data[helmopsv1alpha1.AuthTokenSecretKey])
+			config.ClientID = string(secret.Data[helmopsv1alpha1.AuthClientIDSecretKey])
+			config.ClientSecret = string(secret.Data[helmopsv1alpha1.AuthClientSecretSecretKey])
+		}
+		// the token requests share the tls and the retry of the repo but are not authorized by the provider
+		tokenClient, err := utils.NewHTTPClient(transport)
+		if err != nil {
+			return nil, errors.Wrap(err, "create the token client of the repo error")
+		}
+		if transport.Auth, err = utils.NewAuthProvider(config, tokenClient); err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, charts.WithTransport(transport))
+	if spec.S3 != nil {
+		config := s3.Config{
+			Endpoint:  spec.S3.Endpoint,
+			Bucket:    spec.S3.Bucket,
+			Prefix:    spec.S3.Prefix,
+			Region:    spec.S3.Region,
+			PathStyle: spec.S3.PathStyle,
+		}
+		if spec.S3.SecretRef != nil {
+			secret, err := getRepoSecret(ctx, c, spec.S3.SecretRef, namespace)
+			if err != nil {
+				return nil, err
+			}
+			config.AccessKeyID = string(secret.Data[helmopsv1alpha1.S3AccessKeyIDSecretKey])
+			config.SecretAccessKey = string(secret.Data[helmopsv1alpha1.S3SecretAccessKeySecretKey])
+			config.SessionToken = string(secret.Data[helmopsv1alpha1.S3SessionTokenSecretKey])
+		}
+		opts = append(opts, charts.WithS3Config(config))
+	}
+	switch spec.RepoType {
+	case helmopsv1alpha1.RepoTypeConfigMap:
+		if spec.ConfigMap == nil {
+			return nil, errors.New("the configmap selector of the ConfigMap repo is not set")
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&spec.ConfigMap.Selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "the configmap selector of the ConfigMap repo is invalid")
+		}
+		// the loader is called by the sync jobs of the repo, it is not bound to the reconcile
+		opts = append(opts, charts.WithArchiveLoader(archive.ConfigMapLoader(context.Background(), c,
+			defaultNamespace(spec.ConfigMap.Namespace, namespace), selector)))
+	case helmopsv1alpha1.RepoTypeEmbedded:
+		if spec.Embedded == nil {
+			return nil, errors.New("the configmap of the Embedded repo is not set")
+		}
+		opts = append(opts, charts.WithArchiveLoader(archive.EmbeddedLoader(context.Background(), c,
+			defaultNamespace(spec.Embedded.Namespace, namespace), spec.Embedded.Name)))
+	case helmopsv1alpha1.RepoTypeLocalPath:
+		// the directory is on the controller, the tenants can not read it with the HelmRepo
+		if namespace != "" {
+			return nil, errors.New("the LocalPath repo is only supported by the cluster helm repo")
+		}
+		if spec.LocalPath == nil {
+			return nil, errors.New("the path of the LocalPath repo is not set")
+		}
+		opts = append(opts, charts.WithLocalPath(spec.LocalPath.Path))
+	}
+	return opts, nil
+}
+
+// defaultNamespace return the namespace of the reference, or the repo namespace if it is empty
+func defaultNamespace(namespace, repoNamespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return repoNamespace
+}
+
+// getRepoSecret get the secret referenced by the repo, the namespace of the reference is default to the repo namespace
+func getRepoSecret(ctx context.Context, c client.Client, ref *helmopsv1alpha1.SecretReference, namespace string) (*corev1.Secret, error) {
+	namespace = defaultNamespace(ref.Namespace, namespace)
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "get repo secret %s/%s error", namespace, ref.Name)
+	}
+	return secret, nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+const (
+	revisionActionInstall    = "Install"
+	revisionActionUpgrade    = "Upgrade"
+	revisionActionAutoUpdate = "AutoUpdate"
+	revisionActionCanary     = "Canary"
+	// revisionActionCanaryRollback the release rolled back after the canary analysis failed
+	revisionActionCanaryRollback = "CanaryRollback"
+	// revisionCreateRetries retry with the next revision number when the revision already exist,
+	// the cached revision list may not contain the latest created revision
+	revisionCreateRetries = 3
+)
+
+//+kubebuilder:rbac:groups=helmops.shijunlee.net,resources=helmoperationcontrollerrevisions,verbs=get;list;watch;create;delete
+
+// recordRevision create the controller revision for the successful apply of the release and prune the old revisions,
+// the release is already applied so the error is only logged
+func recordRevision(ctx context.Context, c client.Client, scheme *runtime.Scheme, log logr.Logger,
+	operation *helmopsv1alpha1.HelmOperation, rel *release.Release, action, triggeredBy string) {
+	if rel == nil || rel.Chart == nil || rel.Chart.Metadata == nil {
+		return
+	}
+	revisions, err := listRevisions(ctx, c, operation)
+	if err != nil {
+		log.Error(err, "list helm operation controller revisions error")
+		return
+	}
+	var next int64 = 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+	revision, err := newRevision(operation, rel, action, triggeredBy)
+	if err != nil {
+		log.Error(err, "create helm operation controller revision error")
+		return
+	}
+	if err = controllerutil.SetControllerReference(operation, revision, scheme); err != nil {
+		log.Error(err, "set owner of the helm operation controller revision error")
+		return
+	}
+	for i := 0; i < revisionCreateRetries; i++ {
+		revision.Name = fmt.Sprintf("%s-%d", operation.Name, next)
+		revision.Revision = next
+		err = c.Create(ctx, revision)
+		if !k8serrors.IsAlreadyExists(err) {
+			break
+		}
+		next++
+	}
+	if err != nil {
+		log.Error(err, "create helm operation controller revision error", "revision", next)
+		return
+	}
+	revisions = append(revisions, *revision)
+	var limit = helmopsv1alpha1.DefaultRevisionHistoryLimit
+	if operation.Spec.RevisionHistoryLimit != nil {
+		limit = int(*operation.Spec.RevisionHistoryLimit)
+	}
+	for i := 0; i < len(revisions)-limit; i++ {
+		if err = c.Delete(ctx, &revisions[i]); err != nil && !k8serrors.IsNotFound(err) {
+			log.Error(err, "prune helm operation controller revision error", "revision", revisions[i].Name)
+		}
+	}
+}
+
+// listRevisions list the controller revisions owned by the helm operation, sorted by the revision number
+func listRevisions(ctx context.Context, c client.Client,
+	operation *helmopsv1alpha1.HelmOperation) ([]helmopsv1alpha1.HelmOperationControllerRevision, error) {
+	var revisionList = &helmopsv1alpha1.HelmOperationControllerRevisionList{}
+	if err := c.List(ctx, revisionList, client.InNamespace(operation.Namespace)); err != nil {
+		return nil, err
+	}
+	var revisions []helmopsv1alpha1.HelmOperationControllerRevision
+	for _, item := range revisionList.Items {
+		if metav1.IsControlledBy(&item, operation) {
+			revisions = append(revisions, item)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// newRevision create the revision record from the applied release, the spec is resolved with the
+// chart version and the values of the release
+func newRevision(operation *helmopsv1alpha1.HelmOperation, rel *release.Release,
+	action, triggeredBy string) (*helmopsv1alpha1.HelmOperationControllerRevision, error) {
+	spec := *operation.Spec.DeepCopy()
+	spec.ChartVersion = rel.Chart.Metadata.Version
+	spec.ReleaseName = operation.GetReleaseName()
+	spec.TargetNamespace = operation.GetReleaseNamespace()
+	spec.Values.Object = runtime.DeepCopyJSON(rel.Config)
+	mergedValues, err := chartutil.CoalesceValues(rel.Chart, rel.Config)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := chartDigest(rel.Chart)
+	if err != nil {
+		return nil, err
+	}
+	revision := &helmopsv1alpha1.HelmOperationControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operation.Namespace,
+		},
+		Action:          action,
+		Spec:            spec,
+		ChartDigest:     digest,
+		ReleaseRevision: rel.Version,
+		TriggeredBy:     triggeredBy,
+	}
+	revision.Values.Object = runtime.DeepCopyJSON(mergedValues)
+	return revision, nil
+}
+
+// chartDigest the digest of the chart parts stored in the release, so the digest of the chart loaded
+// from the repo and the chart decoded from the release are the same
+func chartDigest(c *chart.Chart) (string, error) {
+	data, err := json.Marshal(struct {
+		Metadata  *chart.Metadata        `json:"metadata"`
+		Lock      *chart.Lock            `json:"lock"`
+		Templates []*chart.File          `json:"templates"`
+		Values    map[string]interface{} `json:"values"`
+		Schema    []byte                 `json:"schema"`
+		Files     []*chart.File          `json:"files"`
+	}{c.Metadata, c.Lock, sortedFiles(c.Templates), c.Values, c.Schema, sortedFiles(c.Files)})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func sortedFiles(files []*chart.File) []*chart.File {
+	var result = append([]*chart.File{}, files...)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// appliedBy the user who last changed the helm operation, recorded by the webhook
+func appliedBy(operation *helmopsv1alpha1.HelmOperation) string {
+	return operation.GetAnnotations()[helmopsv1alpha1.LastAppliedByAnnotation]
+}
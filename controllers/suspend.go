@@ -0,0 +1,56 @@
+/*
+Copyright 2021 lishjun01@hotmail.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
+)
+
+// suspendStatus compute the suspension and the Suspended condition from spec.suspend and the annotations
+// recorded by the webhook, return the new suspension and conditions
+func suspendStatus(obj metav1.Object, suspend bool, conditions []helmopsv1alpha1.Condition) (
+	*helmopsv1alpha1.Suspension, []helmopsv1alpha1.Condition) {
+	if !suspend {
+		if helmopsv1alpha1.GetCondition(conditions, helmopsv1alpha1.ConditionTypeSuspended) == nil {
+			return nil, conditions
+		}
+		return nil, helmopsv1alpha1.SetCondition(conditions, helmopsv1alpha1.Condition{
+			Type:    helmopsv1alpha1.ConditionTypeSuspended,
+			Status:  helmopsv1alpha1.ConditionStatusFalse,
+			Reason:  "Resumed",
+			Message: "the reconciliation is resumed",
+		})
+	}
+	suspension := helmopsv1alpha1.GetSuspension(obj)
+	var message = "the reconciliation is suspended"
+	if suspension.SuspendedBy != "" {
+		message = fmt.Sprintf("%s by %s", message, suspension.SuspendedBy)
+	}
+	if suspension.SuspendedAt != nil {
+		message = fmt.Sprintf("%s at %s", message, suspension.SuspendedAt.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	return suspension, helmopsv1alpha1.SetCondition(conditions, helmopsv1alpha1.Condition{
+		Type:    helmopsv1alpha1.ConditionTypeSuspended,
+		Status:  helmopsv1alpha1.ConditionStatusTrue,
+		Reason:  "Suspended",
+		Message: message,
+	})
+}
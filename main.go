@@ -34,6 +34,8 @@ import (
 
 	helmopsv1alpha1 "github.com/shijunLee/helmops/api/v1alpha1"
 	"github.com/shijunLee/helmops/controllers"
+	"github.com/shijunLee/helmops/pkg/catalog"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -57,10 +59,23 @@ func main() {
 	var localCachePath string
 	var maxConcurrentReconciles int
 	var jitterPeriod int
+	var notificationRateLimit time.Duration
+	var repoRequestQPS float64
+	var repoRequestBurst int
+	var catalogAddr, catalogCertFile, catalogKeyFile string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.IntVar(&period, "repo-period", 30, "the period for helm repo sync")
 	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-sync-reconciles", 1, "the max concurrent sync reconciles")
 	flag.IntVar(&jitterPeriod, "jitter-period", 0, "the jitter period for helm release update process")
+	flag.DurationVar(&notificationRateLimit, "notification-rate-limit", 5*time.Minute,
+		"the interval in which the same alert message is posted once")
+	flag.Float64Var(&repoRequestQPS, "repo-request-qps", 10, "the requests per second to each helm repo host")
+	flag.IntVar(&repoRequestBurst, "repo-request-burst", 20, "the burst of the requests to each helm repo host")
+	flag.StringVar(&catalogAddr, "catalog-bind-address", "0",
+		"The address the read-only chart catalog api binds to, default 0 disable the api. "+
+			"The api requires the --catalog-cert-file and --catalog-key-file.")
+	flag.StringVar(&catalogCertFile, "catalog-cert-file", "", "the tls certificate file of the chart catalog api")
+	flag.StringVar(&catalogKeyFile, "catalog-key-file", "", "the tls key file of the chart catalog api")
 	flag.StringVar(&localCachePath, "local-cache-path", "/tmp", "the git cache local path for helm repo.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -73,6 +88,7 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	utils.SetHostRateLimit(repoRequestQPS, repoRequestBurst)
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -86,29 +102,89 @@ func main() {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	var eventDispatcher = controllers.NewEventDispatcher(mgr.GetClient(),
+		ctrl.Log.WithName("controllers").WithName("EventDispatcher"), notificationRateLimit)
+	if err = mgr.Add(eventDispatcher); err != nil {
+		setupLog.Error(err, "unable to add the event dispatcher")
+		os.Exit(1)
+	}
 	var helmRepoReconciler = controllers.NewHelmRepoReconciler(mgr, period, maxConcurrentReconciles, time.Duration(jitterPeriod), localCachePath)
+	helmRepoReconciler.Dispatcher = eventDispatcher
 	if err = helmRepoReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HelmRepo")
 		os.Exit(1)
 	}
+	if catalogAddr != "0" {
+		if catalogCertFile == "" || catalogKeyFile == "" {
+			setupLog.Error(catalog.TLSRequiredErr, "unable to add the catalog api")
+			os.Exit(1)
+		}
+		if err = mgr.Add(controllers.NewCatalogServer(mgr, catalogAddr, catalogCertFile, catalogKeyFile)); err != nil {
+			setupLog.Error(err, "unable to add the catalog api")
+			os.Exit(1)
+		}
+	}
+	if err = (&controllers.ClusterHelmRepoReconciler{HelmRepoReconciler: helmRepoReconciler}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterHelmRepo")
+		os.Exit(1)
+	}
 	if err = (&controllers.HelmOperationReconciler{
 		Client:     mgr.GetClient(),
 		Log:        ctrl.Log.WithName("controllers").WithName("HelmOperation"),
 		Scheme:     mgr.GetScheme(),
 		RestConfig: mgr.GetConfig(),
+		Dispatcher: eventDispatcher,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HelmOperation")
 		os.Exit(1)
 	}
+	if err = (&controllers.HelmOperationSetReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("HelmOperationSet"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HelmOperationSet")
+		os.Exit(1)
+	}
+	if err = (&controllers.ChartPublisherReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ChartPublisher"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ChartPublisher")
+		os.Exit(1)
+	}
 
 	if err = (&helmopsv1alpha1.HelmRepo{}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "HelmRepo")
 		os.Exit(1)
 	}
+	if err = (&helmopsv1alpha1.ClusterHelmRepo{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ClusterHelmRepo")
+		os.Exit(1)
+	}
 	if err = (&helmopsv1alpha1.HelmOperation{}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "HelmOperation")
 		os.Exit(1)
 	}
+	if err = (&helmopsv1alpha1.NotificationProvider{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "NotificationProvider")
+		os.Exit(1)
+	}
+	if err = (&helmopsv1alpha1.Alert{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Alert")
+		os.Exit(1)
+	}
+	if err = (&helmopsv1alpha1.HelmOperationControllerRevision{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "HelmOperationControllerRevision")
+		os.Exit(1)
+	}
+	if err = (&helmopsv1alpha1.ChartPublisher{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ChartPublisher")
+		os.Exit(1)
+	}
+	helmopsv1alpha1.SetupSuspendWebhookWithManager(mgr)
+	helmopsv1alpha1.SetupAppliedByWebhookWithManager(mgr)
 
 	//+kubebuilder:scaffold:builder
 
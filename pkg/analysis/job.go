@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Job the analysis which run a job to completion, the analysis succeeded if the job succeeded
+type Job struct {
+	Client    kubernetes.Interface
+	Namespace string
+	// Name the job name, the job is created once for the name
+	Name   string
+	Labels map[string]string
+	Spec   batchv1.JobSpec
+}
+
+// Start create the job if it not exists, the restart policy of the pods is default to Never
+func (j *Job) Start(ctx context.Context) error {
+	var job = &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: j.Name, Namespace: j.Namespace, Labels: j.Labels},
+		Spec:       *j.Spec.DeepCopy(),
+	}
+	if job.Spec.Template.Spec.RestartPolicy == "" {
+		job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	_, err := j.Client.BatchV1().Jobs(j.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "create analysis job %s/%s error", j.Namespace, j.Name)
+	}
+	return nil
+}
+
+// Check get the result of the job, return nil if the job is still running
+func (j *Job) Check(ctx context.Context) (*Result, error) {
+	job, err := j.Client.BatchV1().Jobs(j.Namespace).Get(ctx, j.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "get analysis job %s/%s error", j.Namespace, j.Name)
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return &Result{Succeeded: true, Message: fmt.Sprintf("job %s succeeded", j.Name)}, nil
+		case batchv1.JobFailed:
+			return &Result{Message: fmt.Sprintf("job %s failed: %s", j.Name, condition.Message)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete remove the job and the pods of the job
+func (j *Job) Delete(ctx context.Context) error {
+	var propagation = metav1.DeletePropagationBackground
+	err := j.Client.BatchV1().Jobs(j.Namespace).Delete(ctx, j.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete analysis job %s/%s error", j.Namespace, j.Name)
+	}
+	return nil
+}
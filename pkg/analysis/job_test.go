@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Job(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	job := &Job{Client: client, Namespace: "default", Name: "web-canary-2", Spec: batchv1.JobSpec{
+		Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "smoke", Image: "busybox"}}}},
+	}}
+	if err := job.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// the job is created once
+	if err := job.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.BatchV1().Jobs("default").Get(ctx, "web-canary-2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("expect the restart policy default to Never, got %s", created.Spec.Template.Spec.RestartPolicy)
+	}
+	if result, err := job.Check(ctx); err != nil || result != nil {
+		t.Errorf("expect the running job has no result, got %+v %v", result, err)
+	}
+
+	created.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+	if _, err = client.BatchV1().Jobs("default").UpdateStatus(ctx, created, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := job.Check(ctx); err != nil || result == nil || result.Succeeded {
+		t.Errorf("expect the job failed, got %+v %v", result, err)
+	}
+
+	if err = job.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = job.Delete(ctx); err != nil {
+		t.Errorf("expect delete the job not found succeeded, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Operators compare the sample value with the threshold, the same as the operators of the PrometheusAnalysis
+const (
+	OperatorLessThan           = "LessThan"
+	OperatorLessThanOrEqual    = "LessThanOrEqual"
+	OperatorGreaterThan        = "GreaterThan"
+	OperatorGreaterThanOrEqual = "GreaterThanOrEqual"
+)
+
+const queryTimeout = 30 * time.Second
+
+// Result the result of the analysis
+type Result struct {
+	// Succeeded the analysis passed
+	Succeeded bool
+	Message   string
+}
+
+// Prometheus evaluate an instant query with the prometheus http api, the analysis succeeded if
+// every sample value of the result satisfy the threshold
+type Prometheus struct {
+	// Address the address of the prometheus server
+	Address string
+	// Query the promql instant query
+	Query string
+	// Operator compare the sample value with the threshold, default is LessThan
+	Operator string
+	// Threshold the float number which the sample values compared with
+	Threshold string
+	// Client the http client, the default client with a timeout is used if not set
+	Client *http.Client
+}
+
+// queryResponse the response of the prometheus instant query api
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// sample the sample in the vector result, the value is [timestamp, "value"]
+type sample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// Run query the prometheus and compare the sample values with the threshold, the error is returned only if
+// the query can not be evaluated
+func (p *Prometheus) Run(ctx context.Context) (*Result, error) {
+	threshold, err := strconv.ParseFloat(p.Threshold, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "threshold %s is not a number", p.Threshold)
+	}
+	values, err := p.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return &Result{Message: fmt.Sprintf("query %s returned no data", p.Query)}, nil
+	}
+	for _, value := range values {
+		ok, err := compare(p.Operator, value, threshold)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &Result{Message: fmt.Sprintf("query %s returned %v, not %s %s", p.Query, value,
+				operatorOrDefault(p.Operator), p.Threshold)}, nil
+		}
+	}
+	return &Result{Succeeded: true, Message: fmt.Sprintf("query %s returned %v, %s %s", p.Query, values,
+		operatorOrDefault(p.Operator), p.Threshold)}, nil
+}
+
+// query run the instant query, return the values of the vector or scalar result
+func (p *Prometheus) query(ctx context.Context) ([]float64, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: queryTimeout}
+	}
+	form := url.Values{"query": []string{p.Query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.Address, "/")+"/api/v1/query",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "query prometheus error")
+	}
+	defer resp.Body.Close()
+	var response queryResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, errors.Wrapf(err, "decode prometheus response error, status %d", resp.StatusCode)
+	}
+	if response.Status != "success" {
+		return nil, errors.Errorf("query prometheus error %s: %s", response.ErrorType, response.Error)
+	}
+	switch response.Data.ResultType {
+	case "vector":
+		var samples []sample
+		if err = json.Unmarshal(response.Data.Result, &samples); err != nil {
+			return nil, errors.Wrap(err, "decode prometheus vector error")
+		}
+		var values []float64
+		for _, item := range samples {
+			value, err := sampleValue(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case "scalar":
+		var item []interface{}
+		if err = json.Unmarshal(response.Data.Result, &item); err != nil {
+			return nil, errors.Wrap(err, "decode prometheus scalar error")
+		}
+		value, err := sampleValue(item)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{value}, nil
+	}
+	return nil, errors.Errorf("prometheus result type %s is not supported", response.Data.ResultType)
+}
+
+// sampleValue parse the value of [timestamp, "value"]
+func sampleValue(value []interface{}) (float64, error) {
+	if len(value) != 2 {
+		return 0, errors.Errorf("prometheus sample value %v is invalid", value)
+	}
+	text, ok := value[1].(string)
+	if !ok {
+		return 0, errors.Errorf("prometheus sample value %v is invalid", value)
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func operatorOrDefault(operator string) string {
+	if operator == "" {
+		return OperatorLessThan
+	}
+	return operator
+}
+
+func compare(operator string, value, threshold float64) (bool, error) {
+	switch operatorOrDefault(operator) {
+	case OperatorLessThan:
+		return value < threshold, nil
+	case OperatorLessThanOrEqual:
+		return value <= threshold, nil
+	case OperatorGreaterThan:
+		return value > threshold, nil
+	case OperatorGreaterThanOrEqual:
+		return value >= threshold, nil
+	}
+	return false, errors.Errorf("operator %s is not supported", operator)
+}
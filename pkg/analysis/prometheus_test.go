@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Prometheus(t *testing.T) {
+	var results = map[string]string{
+		"errors":  `{"resultType":"vector","result":[{"metric":{"pod":"a"},"value":[1,"0.01"]},{"metric":{"pod":"b"},"value":[1,"0.2"]}]}`,
+		"latency": `{"resultType":"scalar","result":[1,"120"]}`,
+		"missing": `{"resultType":"vector","result":[]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		result, ok := results[r.FormValue("query")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":%s}`, result)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		query     string
+		operator  string
+		threshold string
+		succeeded bool
+		wantErr   bool
+	}{
+		{query: "errors", threshold: "0.5", succeeded: true},
+		{query: "errors", threshold: "0.1"},
+		{query: "errors", operator: OperatorGreaterThanOrEqual, threshold: "0.01", succeeded: true},
+		{query: "latency", operator: OperatorLessThanOrEqual, threshold: "120", succeeded: true},
+		{query: "latency", operator: OperatorGreaterThan, threshold: "120"},
+		{query: "missing", threshold: "1"},
+		{query: "invalid(", threshold: "1", wantErr: true},
+		{query: "errors", threshold: "high", wantErr: true},
+	}
+	for _, tt := range tests {
+		p := &Prometheus{Address: server.URL + "/", Query: tt.query, Operator: tt.operator, Threshold: tt.threshold}
+		result, err := p.Run(context.Background())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("query %s %s %s: error %v, wantErr %v", tt.query, tt.operator, tt.threshold, err, tt.wantErr)
+			continue
+		}
+		if err == nil && result.Succeeded != tt.succeeded {
+			t.Errorf("query %s %s %s: expect succeeded %v, got %+v", tt.query, tt.operator, tt.threshold, tt.succeeded, result)
+		}
+	}
+}
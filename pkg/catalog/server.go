@@ -0,0 +1,278 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	helmactions "helm.sh/helm/v3/pkg/action"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+const (
+	// KindHelmRepo the namespaced chart repo
+	KindHelmRepo = "HelmRepo"
+	// KindClusterHelmRepo the cluster chart repo
+	KindClusterHelmRepo = "ClusterHelmRepo"
+	// LatestVersion the version in the path resolved to the latest version of the chart
+	LatestVersion = "latest"
+
+	apiPrefix = "/api/v1/"
+)
+
+var (
+	// UnauthenticatedErr the request has no valid credentials
+	UnauthenticatedErr = errors.New("the request is not authenticated")
+	// ForbiddenErr the user of the request can not read the repo
+	ForbiddenErr = errors.New("the request is forbidden")
+	// RepoNotFoundErr the repo not found or not synced by the manager
+	RepoNotFoundErr = errors.New("the repo not found or not synced")
+	// TLSRequiredErr the api is started without the tls certificate, the bearer tokens must not be sent in cleartext
+	TLSRequiredErr = errors.New("the catalog api requires the tls certificate and key file")
+)
+
+// RepoRef the chart repo served by the catalog, the namespace is empty for the ClusterHelmRepo
+type RepoRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Source the chart repos synced by the manager
+type Source interface {
+	// ListCharts list all the chart versions of the repo
+	ListCharts(ref RepoRef) (map[string]utils.CommonChartVersions, error)
+	// ChartOptions the options to load the chart version of the repo
+	ChartOptions(ref RepoRef, chartName, chartVersion string) (*actions.ChartOpts, error)
+}
+
+// Authorizer check the user of the request can read the repo, return UnauthenticatedErr or ForbiddenErr
+// if the request is not allowed
+type Authorizer interface {
+	Authorize(req *http.Request, ref RepoRef) error
+}
+
+// ChartVersion the chart version in the response of the charts list
+type ChartVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Digest  string `json:"digest,omitempty"`
+	// URL the download url of the chart, only set for the http repos
+	URL string `json:"url,omitempty"`
+}
+
+// Server the read-only http api of the charts in the repos, the paths are
+// `/api/v1/namespaces/{namespace}/helmrepos/{name}/charts[/{chart}[/{version}[/chart|readme|values]]]` and
+// `/api/v1/clusterhelmrepos/{name}/charts[/{chart}[/{version}[/chart|readme|values]]]`
+type Server struct {
+	// Addr the address the api binds to
+	Addr string
+	// CertFile and KeyFile the tls certificate of the api, both are required
+	CertFile   string
+	KeyFile    string
+	Source     Source
+	Authorizer Authorizer
+	Log        logr.Logger
+}
+
+// Start serve the api until the context done, implement manager.Runnable
+func (s *Server) Start(ctx context.Context) error {
+	if s.CertFile == "" || s.KeyFile == "" {
+		return TLSRequiredErr
+	}
+	server := &http.Server{Addr: s.Addr, Handler: s}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	s.Log.Info("starting the catalog api", "addr", s.Addr)
+	err := server.ListenAndServeTLS(s.CertFile, s.KeyFile)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("the catalog api is read-only"))
+		return
+	}
+	ref, parts, ok := parsePath(req.URL.Path)
+	if !ok {
+		s.writeError(w, http.StatusNotFound, errors.Errorf("path %s not found", req.URL.Path))
+		return
+	}
+	if err := s.Authorizer.Authorize(req, ref); err != nil {
+		switch errors.Cause(err) {
+		case UnauthenticatedErr:
+			s.writeError(w, http.StatusUnauthorized, err)
+		case ForbiddenErr:
+			s.writeError(w, http.StatusForbidden, err)
+		default:
+			s.writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	chartVersions, err := s.Source.ListCharts(ref)
+	if err != nil {
+		s.writeSourceError(w, err)
+		return
+	}
+	if len(parts) == 0 {
+		var result = map[string][]ChartVersion{}
+		for name, versions := range chartVersions {
+			result[name] = newChartVersions(versions)
+		}
+		s.writeJSON(w, map[string]interface{}{"charts": result})
+		return
+	}
+	versions, ok := chartVersions[parts[0]]
+	if !ok || len(versions) == 0 {
+		s.writeError(w, http.StatusNotFound, errors.Errorf("chart %s not found", parts[0]))
+		return
+	}
+	if len(parts) == 1 {
+		s.writeJSON(w, map[string]interface{}{"name": parts[0], "versions": newChartVersions(versions)})
+		return
+	}
+	chartVersion := resolveVersion(versions, parts[1])
+	for _, item := range versions {
+		if item.Version == chartVersion {
+			s.showChart(w, ref, parts[0], chartVersion, parts[2:])
+			return
+		}
+	}
+	s.writeError(w, http.StatusNotFound, errors.Errorf("chart %s version %s not found", parts[0], chartVersion))
+}
+
+// showChart write the chart metadata, the readme or the default values of the chart version
+func (s *Server) showChart(w http.ResponseWriter, ref RepoRef, chartName, chartVersion string, parts []string) {
+	var format = helmactions.ShowChart
+	if len(parts) == 1 {
+		switch parts[0] {
+		case "chart":
+		case "readme":
+			format = helmactions.ShowReadme
+		case "values":
+			format = helmactions.ShowValues
+		default:
+			s.writeError(w, http.StatusNotFound, errors.Errorf("%s of the chart not found", parts[0]))
+			return
+		}
+	}
+	chartOpts, err := s.Source.ChartOptions(ref, chartName, chartVersion)
+	if err != nil {
+		s.writeSourceError(w, err)
+		return
+	}
+	showOptions := &actions.ShowOptions{OutputFormat: format, ChartOpts: chartOpts}
+	out, err := showOptions.Run()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch format {
+	case helmactions.ShowChart:
+		data, err := yaml.YAMLToJSON([]byte(out))
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	case helmactions.ShowReadme:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_, _ = w.Write([]byte(out))
+	default:
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write([]byte(out))
+	}
+}
+
+// parsePath parse the repo and the chart path parts after `charts` from the request path
+func parsePath(requestPath string) (RepoRef, []string, bool) {
+	if !strings.HasPrefix(requestPath, apiPrefix) {
+		return RepoRef{}, nil, false
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(requestPath, apiPrefix), "/"), "/")
+	var ref RepoRef
+	switch {
+	case len(parts) >= 5 && parts[0] == "namespaces" && parts[2] == "helmrepos" && parts[4] == "charts":
+		ref = RepoRef{Kind: KindHelmRepo, Namespace: parts[1], Name: parts[3]}
+		parts = parts[5:]
+	case len(parts) >= 3 && parts[0] == "clusterhelmrepos" && parts[2] == "charts":
+		ref = RepoRef{Kind: KindClusterHelmRepo, Name: parts[1]}
+		parts = parts[3:]
+	default:
+		return RepoRef{}, nil, false
+	}
+	if len(parts) > 3 {
+		return RepoRef{}, nil, false
+	}
+	for _, item := range parts {
+		if item == "" {
+			return RepoRef{}, nil, false
+		}
+	}
+	return ref, parts, true
+}
+
+// newChartVersions convert the chart versions to the response, the latest version first
+func newChartVersions(versions utils.CommonChartVersions) []ChartVersion {
+	sorted := append(utils.CommonChartVersions{}, versions...)
+	sort.Sort(sort.Reverse(sorted))
+	var result = make([]ChartVersion, 0, len(sorted))
+	for _, item := range sorted {
+		version := ChartVersion{Name: item.Name, Version: item.Version, Digest: item.Digest}
+		if item.URLType == "http" {
+			version.URL = item.URL
+		}
+		result = append(result, version)
+	}
+	return result
+}
+
+// resolveVersion resolve the `latest` version to the latest version of the chart
+func resolveVersion(versions utils.CommonChartVersions, version string) string {
+	if version != LatestVersion {
+		return version
+	}
+	sorted := append(utils.CommonChartVersions{}, versions...)
+	sort.Sort(sorted)
+	return sorted[len(sorted)-1].Version
+}
+
+func (s *Server) writeSourceError(w http.ResponseWriter, err error) {
+	if errors.Cause(err) == RepoNotFoundErr {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.writeError(w, http.StatusInternalServerError, err)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		s.Log.Error(err, "write catalog response error")
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusInternalServerError {
+		s.Log.Error(err, "catalog request error")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
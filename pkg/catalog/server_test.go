@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) ListCharts(ref RepoRef) (map[string]utils.CommonChartVersions, error) {
+	if ref != (RepoRef{Kind: KindHelmRepo, Namespace: "team-a", Name: "stable"}) {
+		return nil, errors.Wrapf(RepoNotFoundErr, "the %s", ref.Name)
+	}
+	return map[string]utils.CommonChartVersions{
+		"nginx": {
+			{Name: "nginx", Version: "1.0.0", URLType: "http", URL: "http://museum/charts/nginx-1.0.0.tgz"},
+			{Name: "nginx", Version: "1.2.0", URLType: "http", URL: "http://museum/charts/nginx-1.2.0.tgz"},
+		},
+	}, nil
+}
+
+func (fakeSource) ChartOptions(ref RepoRef, chartName, chartVersion string) (*actions.ChartOpts, error) {
+	return &actions.ChartOpts{Chart: &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: chartName, Version: chartVersion},
+		Values:   map[string]interface{}{"replicaCount": 1},
+		Raw:      []*chart.File{{Name: "values.yaml", Data: []byte("replicaCount: 1")}},
+		Files:    []*chart.File{{Name: "README.md", Data: []byte("# nginx " + chartVersion)}},
+	}}, nil
+}
+
+type fakeAuthorizer struct{}
+
+func (fakeAuthorizer) Authorize(req *http.Request, ref RepoRef) error {
+	switch req.Header.Get("Authorization") {
+	case "Bearer reader":
+		return nil
+	case "":
+		return UnauthenticatedErr
+	}
+	return errors.Wrap(ForbiddenErr, "user can not get the repo")
+}
+
+func Test_Server(t *testing.T) {
+	server := httptest.NewServer(&Server{Source: fakeSource{}, Authorizer: fakeAuthorizer{}, Log: logr.Discard()})
+	defer server.Close()
+	get := func(path, token string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	const repoPath = "/api/v1/namespaces/team-a/helmrepos/stable/charts"
+
+	if status, _ := get(repoPath, ""); status != http.StatusUnauthorized {
+		t.Errorf("expect 401 without token, got %d", status)
+	}
+	if status, _ := get(repoPath, "other"); status != http.StatusForbidden {
+		t.Errorf("expect 403 for the forbidden user, got %d", status)
+	}
+	if status, _ := get("/api/v1/clusterhelmrepos/stable/charts", "reader"); status != http.StatusNotFound {
+		t.Errorf("expect 404 for the repo not synced, got %d", status)
+	}
+
+	status, body := get(repoPath, "reader")
+	var list struct {
+		Charts map[string][]ChartVersion `json:"charts"`
+	}
+	if err := json.Unmarshal([]byte(body), &list); err != nil || status != http.StatusOK {
+		t.Fatalf("list charts return %d %s", status, body)
+	}
+	if versions := list.Charts["nginx"]; len(versions) != 2 || versions[0].Version != "1.2.0" || versions[0].URL == "" {
+		t.Errorf("expect the nginx versions latest first, got %+v", versions)
+	}
+
+	status, body = get(repoPath+"/nginx/latest", "reader")
+	var metadata chart.Metadata
+	if err := json.Unmarshal([]byte(body), &metadata); err != nil || status != http.StatusOK || metadata.Version != "1.2.0" {
+		t.Errorf("expect the metadata of the latest version, got %d %s", status, body)
+	}
+	if status, body = get(repoPath+"/nginx/1.0.0/readme", "reader"); status != http.StatusOK || !strings.Contains(body, "# nginx 1.0.0") {
+		t.Errorf("expect the readme of nginx 1.0.0, got %d %s", status, body)
+	}
+	if status, body = get(repoPath+"/nginx/1.0.0/values", "reader"); status != http.StatusOK || !strings.Contains(body, "replicaCount: 1") {
+		t.Errorf("expect the default values, got %d %s", status, body)
+	}
+	if status, _ = get(repoPath+"/nginx/2.0.0", "reader"); status != http.StatusNotFound {
+		t.Errorf("expect 404 for the version not exist, got %d", status)
+	}
+	if status, _ = get(repoPath+"/redis", "reader"); status != http.StatusNotFound {
+		t.Errorf("expect 404 for the chart not exist, got %d", status)
+	}
+
+	resp, err := http.Post(server.URL+repoPath, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expect the api read-only, got %d", resp.StatusCode)
+	}
+}
+
+func Test_ServerStartRequiresTLS(t *testing.T) {
+	server := &Server{Addr: "127.0.0.1:0", Source: fakeSource{}, Authorizer: fakeAuthorizer{}, Log: logr.Discard()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.Start(ctx); err != TLSRequiredErr {
+		t.Errorf("expect the api not started without the tls certificate, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+const (
+	// IndexFileName the key of the index in the source
+	IndexFileName = "index.yaml"
+	// PathType the path type of the chart versions, the chart is loaded from the archive bytes of the repo
+	PathType = "archive"
+)
+
+var (
+	ChartNotExistErr        = errors.New("chart not exit for archive repo")
+	ChartVersionNotExistErr = errors.New("chart version not exit for archive repo")
+)
+
+// Source the chart archives keyed by the name, and the optional index.yaml which urls are the archive names
+type Source struct {
+	Index    []byte
+	Archives map[string][]byte
+}
+
+// Loader load the source of the repo, it is called each time the repo is listed
+type Loader func() (*Source, error)
+
+// Archives the chart repo of the chart archives in memory, like the archives in the configmaps,
+// the index is generated from the chart metadata of the archives if the source has no index
+type Archives struct {
+	RepoName string
+	load     Loader
+}
+
+func NewArchives(repoName string, load Loader) (*Archives, error) {
+	if load == nil {
+		return nil, errors.New("the loader of the archive repo is not set")
+	}
+	c := &Archives{RepoName: repoName, load: load}
+	if _, _, err := c.loadIndex(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadIndex load the source and the index of the archives
+func (c *Archives) loadIndex() (map[string]repo.ChartVersions, *Source, error) {
+	source, err := c.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	indexFile := repo.NewIndexFile()
+	if len(source.Index) > 0 {
+		if err = yaml.Unmarshal(source.Index, indexFile); err != nil {
+			return nil, nil, errors.Wrapf(err, "parse index.yaml of repo %s error", c.RepoName)
+		}
+	} else {
+		var names []string
+		for name := range source.Archives {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			data := source.Archives[name]
+			chart, err := loader.LoadArchive(bytes.NewReader(data))
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "load chart archive %s of repo %s error", name, c.RepoName)
+			}
+			sum := sha256.Sum256(data)
+			version := &repo.ChartVersion{Metadata: chart.Metadata, URLs: []string{name}, Digest: hex.EncodeToString(sum[:])}
+			indexFile.Entries[chart.Name()] = append(indexFile.Entries[chart.Name()], version)
+		}
+	}
+	indexFile.SortEntries()
+	return indexFile.Entries, source, nil
+}
+
+// archiveName the name of the chart archive in the source, the urls in the index may be relative like `./name.tgz`
+func archiveName(chartURL string) string {
+	return strings.TrimPrefix(chartURL, "./")
+}
+
+func (c *Archives) GetChartLastVersion(chartName string) (string, error) {
+	vers, err := c.getChartVersions(chartName)
+	if err != nil {
+		return "", err
+	}
+	return utils.GetLatestSemver(vers)
+}
+
+// GetChartVersionUrl return the archive name of the chart version, the archive is got by ChartArchive
+func (c *Archives) GetChartVersionUrl(chartName, chartVersion string) (url, pathType string, err error) {
+	chartVersions, _, err := c.loadIndex()
+	if err != nil {
+		return "", "", err
+	}
+	versions, ok := chartVersions[chartName]
+	if !ok {
+		return "", "", ChartNotExistErr
+	}
+	for _, item := range versions {
+		if item.Version == chartVersion && len(item.URLs) > 0 {
+			return archiveName(item.URLs[0]), PathType, nil
+		}
+	}
+	return "", "", ChartVersionNotExistErr
+}
+
+// ChartArchive return a copy of the chart archive of the chart version
+func (c *Archives) ChartArchive(chartName, chartVersion string) (*bytes.Buffer, error) {
+	chartVersions, source, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	versions, ok := chartVersions[chartName]
+	if !ok {
+		return nil, ChartNotExistErr
+	}
+	for _, item := range versions {
+		if item.Version != chartVersion || len(item.URLs) == 0 {
+			continue
+		}
+		name := archiveName(item.URLs[0])
+		data, ok := source.Archives[name]
+		if !ok {
+			return nil, errors.Errorf("chart archive %s not found in repo %s", name, c.RepoName)
+		}
+		return bytes.NewBuffer(append([]byte(nil), data...)), nil
+	}
+	return nil, ChartVersionNotExistErr
+}
+
+func (c *Archives) CheckChartExist(chartName, version string) bool {
+	vers, err := c.getChartVersions(chartName)
+	if err != nil {
+		return false
+	}
+	for _, item := range vers {
+		if item == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Archives) getChartVersions(chartName string) ([]string, error) {
+	chartVersions, _, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	versions, ok := chartVersions[chartName]
+	if !ok {
+		return nil, ChartNotExistErr
+	}
+	var vers []string
+	for _, item := range versions {
+		vers = append(vers, item.Version)
+	}
+	return vers, nil
+}
+
+func (c *Archives) ListCharts() (map[string]utils.CommonChartVersions, error) {
+	index, _, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	var result = map[string]utils.CommonChartVersions{}
+	for key, versions := range index {
+		for _, item := range versions {
+			if len(item.URLs) == 0 {
+				continue
+			}
+			result[key] = append(result[key], utils.CommonChartVersion{Name: key, Version: item.Version, URLType: PathType,
+				URL: archiveName(item.URLs[0]), Digest: item.Digest, RepoName: c.RepoName})
+		}
+	}
+	return result, nil
+}
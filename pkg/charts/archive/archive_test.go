@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// chartArchive package a chart with the name and version
+func chartArchive(t *testing.T, name, version string) []byte {
+	dir, err := ioutil.TempDir("", "chart-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version}}
+	file, err := chartutil.Save(c, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Clean(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func Test_ArchivesGeneratedIndex(t *testing.T) {
+	source := &Source{Archives: map[string][]byte{
+		"nginx-1.0.0.tgz": chartArchive(t, "nginx", "1.0.0"),
+		"nginx-1.1.0.tgz": chartArchive(t, "nginx", "1.1.0"),
+	}}
+	repo, err := NewArchives("offline", func() (*Source, error) { return source, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := repo.GetChartLastVersion("nginx")
+	if err != nil || version != "1.1.0" {
+		t.Fatalf("expect the latest version 1.1.0, got %s %v", version, err)
+	}
+	url, pathType, err := repo.GetChartVersionUrl("nginx", "1.0.0")
+	if err != nil || url != "nginx-1.0.0.tgz" || pathType != PathType {
+		t.Fatalf("unexpected chart url %s %s %v", url, pathType, err)
+	}
+	chartVersions, err := repo.ListCharts()
+	if err != nil || len(chartVersions["nginx"]) != 2 || chartVersions["nginx"][0].Digest == "" {
+		t.Fatalf("unexpected chart versions %v %v", chartVersions, err)
+	}
+	buffer, err := repo.ChartArchive("nginx", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := loader.LoadArchive(buffer)
+	if err != nil || c.Metadata.Version != "1.0.0" {
+		t.Fatalf("unexpected chart %v %v", c, err)
+	}
+	if repo.CheckChartExist("nginx", "2.0.0") {
+		t.Error("expect chart version 2.0.0 not exist")
+	}
+}
+
+func Test_EmbeddedLoader(t *testing.T) {
+	index := `apiVersion: v1
+entries:
+  redis:
+  - apiVersion: v2
+    name: redis
+    version: 6.0.0
+    urls:
+    - ./redis-6.0.0.tgz
+`
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "charts", Name: "offline"},
+		Data:       map[string]string{IndexFileName: index},
+		BinaryData: map[string][]byte{"redis-6.0.0.tgz": chartArchive(t, "redis", "6.0.0")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(configMap).Build()
+	repo, err := NewArchives("offline", EmbeddedLoader(context.Background(), c, "charts", "offline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err := repo.ChartArchive("redis", "6.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loader.LoadArchive(buffer); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ConfigMapLoader(t *testing.T) {
+	var chartLabels = map[string]string{"helmops.shijunlee.net/charts": "true"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "charts", Name: "nginx", Labels: chartLabels},
+		Data: map[string]string{
+			"chart.tgz":  base64.StdEncoding.EncodeToString(chartArchive(t, "nginx", "1.0.0")),
+			"README.txt": "not a chart",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "charts", Name: "nginx", Labels: chartLabels},
+		Data:       map[string][]byte{"chart.tgz": chartArchive(t, "nginx", "1.1.0")},
+	}
+	other := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "charts", Name: "other"},
+		Data:       map[string]string{"chart.tgz": "invalid"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(configMap, secret, other).Build()
+	repo, err := NewArchives("offline", ConfigMapLoader(context.Background(), c, "charts", labels.SelectorFromSet(chartLabels)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartVersions, err := repo.ListCharts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls = map[string]string{}
+	for _, item := range chartVersions["nginx"] {
+		urls[item.Version] = item.URL
+	}
+	if urls["1.0.0"] != "configmap/nginx/chart.tgz" || urls["1.1.0"] != "secret/nginx/chart.tgz" {
+		t.Errorf("unexpected chart urls %v", urls)
+	}
+}
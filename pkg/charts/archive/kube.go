@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const archiveSuffix = ".tgz"
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeArchive decode the base64 chart archive, the archive in the binary data or the secret data is raw
+func decodeArchive(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+}
+
+// configMapFiles the data and the binary data of the configmap
+func configMapFiles(configMap *corev1.ConfigMap) map[string][]byte {
+	var files = map[string][]byte{}
+	for key, value := range configMap.Data {
+		files[key] = []byte(value)
+	}
+	for key, value := range configMap.BinaryData {
+		files[key] = value
+	}
+	return files
+}
+
+// addArchives add the `.tgz` files to the source, the archive name is prefixed with the object kind and name
+// so the same key in different objects not conflict
+func addArchives(source *Source, prefix string, files map[string][]byte) error {
+	for key, value := range files {
+		if !strings.HasSuffix(key, archiveSuffix) {
+			continue
+		}
+		data, err := decodeArchive(value)
+		if err != nil {
+			return errors.Wrapf(err, "decode chart archive %s error", path.Join(prefix, key))
+		}
+		source.Archives[path.Join(prefix, key)] = data
+	}
+	return nil
+}
+
+// ConfigMapLoader load the `.tgz` chart archives of the configmaps and secrets match the selector in the namespace,
+// the archives are base64 encoded in the configmap data or raw in the binary data and the secret data
+func ConfigMapLoader(ctx context.Context, c client.Reader, namespace string, selector labels.Selector) Loader {
+	return func() (*Source, error) {
+		var source = &Source{Archives: map[string][]byte{}}
+		var listOptions = []client.ListOption{client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}}
+		configMaps := &corev1.ConfigMapList{}
+		if err := c.List(ctx, configMaps, listOptions...); err != nil {
+			return nil, errors.Wrapf(err, "list chart configmaps in namespace %s error", namespace)
+		}
+		for i := range configMaps.Items {
+			if err := addArchives(source, path.Join("configmap", configMaps.Items[i].Name), configMapFiles(&configMaps.Items[i])); err != nil {
+				return nil, err
+			}
+		}
+		secrets := &corev1.SecretList{}
+		if err := c.List(ctx, secrets, listOptions...); err != nil {
+			return nil, errors.Wrapf(err, "list chart secrets in namespace %s error", namespace)
+		}
+		for _, item := range secrets.Items {
+			if err := addArchives(source, path.Join("secret", item.Name), item.Data); err != nil {
+				return nil, err
+			}
+		}
+		return source, nil
+	}
+}
+
+// EmbeddedLoader load the index.yaml and the chart archives of the configmap, the urls of the index
+// are the keys of the archives in the configmap
+func EmbeddedLoader(ctx context.Context, c client.Reader, namespace, name string) Loader {
+	return func() (*Source, error) {
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+			return nil, errors.Wrapf(err, "get chart configmap %s/%s error", namespace, name)
+		}
+		files := configMapFiles(configMap)
+		var source = &Source{Index: files[IndexFileName], Archives: map[string][]byte{}}
+		if err := addArchives(source, "", files); err != nil {
+			return nil, err
+		}
+		return source, nil
+	}
+}
@@ -1,53 +1,138 @@
 package chartmuseum
 
 import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/pkg/errors"
-	"github.com/shijunLee/helmops/pkg/helm/actions"
 	"github.com/shijunLee/helmops/pkg/helm/utils"
 	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
 )
 
 var (
 	ChartNotExistErr        = errors.New("chart not exit for chartMuseum")
 	ChartVersionNotExistErr = errors.New("chart version not exit for chartMuseum")
+	// ChartVersionExistErr the pushed chart version already exist in the chartMuseum
+	ChartVersionExistErr = errors.New("chart version already exist in chartMuseum")
 )
 
 type ChartMuseum struct {
-	URL             string
-	Username        string
-	Password        string
-	RepoName        string
-	InsecureSkipTLS bool
+	URL       string
+	Username  string
+	Password  string
+	RepoName  string
+	Transport utils.TransportOptions
+
+	mu           sync.RWMutex
+	index        map[string]repo.ChartVersions
+	etag         string
+	lastModified string
+	refreshTime  time.Time
 }
 
-func NewChartMuseum(url, username, password, repoName string, insecureSkipTLS bool) (*ChartMuseum, error) {
+func NewChartMuseum(url, username, password, repoName string, transport utils.TransportOptions) (*ChartMuseum, error) {
 	c := &ChartMuseum{
-		URL:             url,
-		Username:        username,
-		Password:        password,
-		RepoName:        repoName,
-		InsecureSkipTLS: insecureSkipTLS,
+		URL:       url,
+		Username:  username,
+		Password:  password,
+		RepoName:  repoName,
+		Transport: transport,
 	}
-	_, err := c.loadIndex()
+	err := c.Refresh()
 	if err != nil {
 		return nil, err
 	}
 	return c, nil
 }
 
-func (c *ChartMuseum) loadIndex() (map[string]repo.ChartVersions, error) {
-	repoOptions := actions.RepoOptions{
-		RepoURL:               c.URL,
-		Username:              c.Username,
-		Password:              c.Password,
-		InsecureSkipTLSVerify: c.InsecureSkipTLS,
-		RepoName:              c.RepoName,
-	}
-	repoIndex, err := repoOptions.GetLatestRepoIndex()
+// Refresh download the index.yaml with the conditional get, the cached index is kept if the index not modified.
+// it is called by the sync jobs, the lookups of the charts are served from the cached index
+func (c *ChartMuseum) Refresh() error {
+	c.mu.RLock()
+	var headers = map[string]string{}
+	if c.etag != "" {
+		headers["If-None-Match"] = c.etag
+	}
+	if c.lastModified != "" {
+		headers["If-Modified-Since"] = c.lastModified
+	}
+	c.mu.RUnlock()
+	indexURL := fmt.Sprintf("%s/index.yaml", strings.TrimSuffix(c.URL, "/"))
+	data, status, header, err := utils.HttpGet(indexURL, headers,
+		utils.WithBasicAuth(c.Username, c.Password),
+		utils.WithTransportOptions(c.Transport))
 	if err != nil {
+		return err
+	}
+	if status == http.StatusNotModified {
+		c.mu.Lock()
+		c.refreshTime = time.Now()
+		c.mu.Unlock()
+		return nil
+	}
+	if status < 200 || status >= 300 {
+		return errors.Errorf("get index.yaml of repo %s return %d", c.URL, status)
+	}
+	indexFile := repo.NewIndexFile()
+	if err = yaml.Unmarshal(data, indexFile); err != nil {
+		return errors.Wrapf(err, "parse index.yaml of repo %s error", c.URL)
+	}
+	indexFile.SortEntries()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index = indexFile.Entries
+	c.etag = http.Header(header).Get("ETag")
+	c.lastModified = http.Header(header).Get("Last-Modified")
+	c.refreshTime = time.Now()
+	return nil
+}
+
+// IndexRefreshTime the last time the index refreshed
+func (c *ChartMuseum) IndexRefreshTime() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.refreshTime
+}
+
+// loadIndex return the cached index, the index is not modified after it cached
+func (c *ChartMuseum) loadIndex() (map[string]repo.ChartVersions, error) {
+	c.mu.RLock()
+	index := c.index
+	c.mu.RUnlock()
+	if index != nil {
+		return index, nil
+	}
+	if err := c.Refresh(); err != nil {
 		return nil, err
 	}
-	return repoIndex.Entries, nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.index, nil
+}
+
+// Push upload the chart archive with the upload api `/api/charts` of the chartMuseum, the cached index
+// is refreshed after the chart uploaded
+func (c *ChartMuseum) Push(archive []byte) error {
+	uploadURL := fmt.Sprintf("%s/api/charts", strings.TrimSuffix(c.URL, "/"))
+	data, status, _, err := utils.HttpPost(uploadURL, map[string]string{"Content-Type": "application/octet-stream"},
+		bytes.NewReader(archive),
+		utils.WithBasicAuth(c.Username, c.Password),
+		utils.WithTransportOptions(c.Transport))
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		return ChartVersionExistErr
+	}
+	if status < 200 || status >= 300 {
+		return errors.Errorf("push chart to repo %s return %d: %s", c.URL, status, strings.TrimSpace(string(data)))
+	}
+	return c.Refresh()
 }
 
 func (c *ChartMuseum) GetChartLastVersion(chartName string) (string, error) {
@@ -114,7 +199,7 @@ func (c *ChartMuseum) ListCharts() (map[string]utils.CommonChartVersions, error)
 		for _, item := range versions {
 			commonCharts, ok := result[key]
 			if ok {
-				commonCharts = append(commonCharts, utils.CommonChartVersion{Name: key, Version: item.Version, URLType: "http", URL: item.URLs[0], RepoName: c.RepoName})
+				commonCharts = append(commonCharts, utils.CommonChartVersion{Name: key, Version: item.Version, URLType: "http", URL: item.URLs[0], Digest: item.Digest, RepoName: c.RepoName})
 				result[key] = commonCharts
 			} else {
 				result[key] = utils.CommonChartVersions{{Name: key, Version: item.Version, URLType: "http", URL: item.URLs[0], Digest: item.Digest, RepoName: c.RepoName}}
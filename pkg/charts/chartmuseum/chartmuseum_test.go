@@ -0,0 +1,72 @@
+package chartmuseum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+const index = `apiVersion: v1
+entries:
+  nginx:
+  - apiVersion: v2
+    name: nginx
+    version: 1.0.0
+    digest: sha256:1234
+    urls:
+    - charts/nginx-1.0.0.tgz
+`
+
+func Test_ChartMuseumIndexCache(t *testing.T) {
+	var requests, downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/index.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&downloads, 1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(index))
+	}))
+	defer server.Close()
+	c, err := NewChartMuseum(server.URL, "", "", "museum", utils.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstRefresh := c.IndexRefreshTime()
+	if firstRefresh.IsZero() {
+		t.Error("expect the index refresh time set")
+	}
+	if !c.CheckChartExist("nginx", "1.0.0") {
+		t.Error("expect chart nginx 1.0.0 exist")
+	}
+	if _, _, err = c.GetChartVersionUrl("nginx", "1.0.0"); err != nil {
+		t.Error(err)
+	}
+	if _, err = c.ListCharts(); err != nil {
+		t.Error(err)
+	}
+	if requests != 1 {
+		t.Errorf("expect the lookups served from the cache, got %d requests", requests)
+	}
+	if err = c.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || downloads != 1 {
+		t.Errorf("expect the not modified index not downloaded, got %d requests and %d downloads", requests, downloads)
+	}
+	if c.IndexRefreshTime().Before(firstRefresh) {
+		t.Error("expect the index refresh time updated by the not modified response")
+	}
+	if version, err := c.GetChartLastVersion("nginx"); err != nil || version != "1.0.0" {
+		t.Errorf("expect the cached index kept, got %s %v", version, err)
+	}
+}
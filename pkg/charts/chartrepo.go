@@ -1,14 +1,20 @@
 package charts
 
 import (
+	"bytes"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/shijunLee/helmops/pkg/helm/utils"
 
 	"github.com/pkg/errors"
+	"github.com/shijunLee/helmops/pkg/charts/archive"
 	"github.com/shijunLee/helmops/pkg/charts/chartmuseum"
 	git "github.com/shijunLee/helmops/pkg/charts/git"
+	"github.com/shijunLee/helmops/pkg/charts/localpath"
+	"github.com/shijunLee/helmops/pkg/charts/s3"
+	"github.com/shijunLee/helmops/pkg/metrics"
 )
 
 var (
@@ -18,9 +24,55 @@ var (
 const (
 	repoTypeGit         = "Git"
 	repoTypeChartMuseum = "ChartMuseum"
+	repoTypeS3          = "S3"
+	repoTypeConfigMap   = "ConfigMap"
+	repoTypeLocalPath   = "LocalPath"
+	repoTypeEmbedded    = "Embedded"
 	defaultBranch       = "master"
+	// breakerThreshold the consecutive failed requests which open the circuit breaker of the repo
+	breakerThreshold = 5
+	// breakerCooldown the time the requests rejected before probe the repo again
+	breakerCooldown = time.Minute
 )
 
+// RepoOption the options of the repo types which not in the common arguments
+type RepoOption func(c *repoOptions)
+
+type repoOptions struct {
+	transport     utils.TransportOptions
+	s3            s3.Config
+	archiveLoader archive.Loader
+	localPath     string
+}
+
+// WithS3Config set the bucket and the credentials of the S3 repo
+func WithS3Config(config s3.Config) RepoOption {
+	return func(c *repoOptions) {
+		c.s3 = config
+	}
+}
+
+// WithTransport set the tls data and the timeout of the requests to the repo
+func WithTransport(transport utils.TransportOptions) RepoOption {
+	return func(c *repoOptions) {
+		c.transport = transport
+	}
+}
+
+// WithArchiveLoader set the loader of the chart archives of the ConfigMap and the Embedded repo
+func WithArchiveLoader(loader archive.Loader) RepoOption {
+	return func(c *repoOptions) {
+		c.archiveLoader = loader
+	}
+}
+
+// WithLocalPath set the directory of the LocalPath repo
+func WithLocalPath(path string) RepoOption {
+	return func(c *repoOptions) {
+		c.localPath = path
+	}
+}
+
 type ChartRepoInterface interface {
 	GetChartLastVersion(chartName string) (string, error)
 	GetChartVersionUrl(chartName, chartVersion string) (url, pathType string, err error)
@@ -28,6 +80,22 @@ type ChartRepoInterface interface {
 	ListCharts() (map[string]utils.CommonChartVersions, error)
 }
 
+// IndexRefresher the chart repo which cache the index in memory, the index is refreshed by the sync jobs
+type IndexRefresher interface {
+	Refresh() error
+	IndexRefreshTime() time.Time
+}
+
+// ArchiveRepo the chart repo which hold the chart archives in memory, the path type of the chart versions is `archive`
+type ArchiveRepo interface {
+	ChartArchive(chartName, chartVersion string) (*bytes.Buffer, error)
+}
+
+// LocalRepo the repo checkout to a local directory, the chart directories are in the RootPath
+type LocalRepo interface {
+	RootPath() string
+}
+
 type ChartRepo struct {
 	Name            string
 	Type            string
@@ -37,31 +105,59 @@ type ChartRepo struct {
 	Token           string
 	Branch          string
 	InsecureSkipTLS bool
-	Period          int
+	// Transport the tls data and the timeout of the requests to the repo, it is used by the chart download too
+	Transport utils.TransportOptions
+	Period    int
 	// the default local cache , all repo will same
 	LocalCache string
-	// the cert not support current
-	Cert       []byte
-	RootCA     []byte
-	PrivateKey []byte
 	Operation  ChartRepoInterface
+	// CancelChan closed by Close to stop the sync jobs
 	CancelChan chan int
+	// Breaker the circuit breaker of the requests to the http repos, nil for the other repo types
+	Breaker *utils.CircuitBreaker
+	// OnSync called after each sync of the charts with the listed chart versions or the error of the sync
+	OnSync func(chartVersions map[string]utils.CommonChartVersions, err error)
+
+	closeOnce sync.Once
 }
 
-func NewChartRepo(name, repoType, url, username, password, token, branch, localCache string, insecureSkipTLS bool, period int) (*ChartRepo, error) {
-	if repoType != "Git" && repoType != "ChartMuseum" {
+func NewChartRepo(name, repoType, url, username, password, token, branch, localCache string, insecureSkipTLS bool, period int,
+	opts ...RepoOption) (*ChartRepo, error) {
+	switch repoType {
+	case repoTypeGit, repoTypeChartMuseum, repoTypeS3, repoTypeConfigMap, repoTypeLocalPath, repoTypeEmbedded:
+	default:
 		return nil, RepoTypeNotSupportErr
 	}
+	var options = &repoOptions{}
+	for _, fn := range opts {
+		fn(options)
+	}
 	if branch == "" {
 		branch = defaultBranch
 	}
+	var transport = options.transport
+	transport.InsecureSkipVerify = transport.InsecureSkipVerify || insecureSkipTLS
+	if repoType == repoTypeChartMuseum || repoType == repoTypeS3 {
+		transport.Breaker = utils.NewCircuitBreaker(breakerThreshold, breakerCooldown)
+	}
 	var operation ChartRepoInterface
 	var err error
 	switch repoType {
 	case repoTypeGit:
-		operation, err = git.NewRepo(url, username, password, token, branch, localCache, name, insecureSkipTLS)
+		operation, err = git.NewRepo(url, username, password, token, branch, localCache, name, transport)
 	case repoTypeChartMuseum:
-		operation, err = chartmuseum.NewChartMuseum(url, username, password, name, insecureSkipTLS)
+		operation, err = chartmuseum.NewChartMuseum(url, username, password, name, transport)
+	case repoTypeS3:
+		var config = options.s3
+		config.Transport = transport
+		if config.Endpoint == "" {
+			config.Endpoint = url
+		}
+		operation, err = s3.NewS3(config, name)
+	case repoTypeConfigMap, repoTypeEmbedded:
+		operation, err = archive.NewArchives(name, options.archiveLoader)
+	case repoTypeLocalPath:
+		operation, err = localpath.NewLocalPath(options.localPath, name)
 	}
 	if err != nil {
 		return nil, err
@@ -75,37 +171,66 @@ func NewChartRepo(name, repoType, url, username, password, token, branch, localC
 		Token:           token,
 		Branch:          branch,
 		InsecureSkipTLS: insecureSkipTLS,
+		Transport:       transport,
 		Period:          period,
 		LocalCache:      localCache,
 		Operation:       operation,
 		CancelChan:      make(chan int),
+		Breaker:         transport.Breaker,
 	}
 	return c, nil
 }
 
+// Close stop the sync jobs of the repo, it not block when the jobs not started or syncing the charts,
+// and it can be called more than once
 func (c *ChartRepo) Close() {
-	c.CancelChan <- 1
+	c.closeOnce.Do(func() {
+		if c.CancelChan != nil {
+			close(c.CancelChan)
+		}
+	})
 }
 
 func (c *ChartRepo) StartTimerJobs(callbackFunc func(chart *utils.CommonChartVersion, err error)) {
 	timeTicker := time.NewTicker(time.Duration(c.Period) * time.Second)
 	defer timeTicker.Stop()
+	c.syncCharts(callbackFunc)
 	for {
 		select {
 		case <-timeTicker.C:
-			chartVersions, err := c.Operation.ListCharts()
-			if err != nil {
-				callbackFunc(nil, err)
-				continue
-			}
-			for _, versions := range chartVersions {
-				sort.Sort(versions)
-				var item = versions[0]
-				callbackFunc(&item, nil)
-			}
+			c.syncCharts(callbackFunc)
 		case <-c.CancelChan:
 			return
 		}
 	}
 
 }
+
+// syncCharts refresh the cached index and list the charts of the repo, then call back with the latest version of each chart
+func (c *ChartRepo) syncCharts(callbackFunc func(chart *utils.CommonChartVersion, err error)) {
+	var chartVersions map[string]utils.CommonChartVersions
+	var err error
+	if refresher, ok := c.Operation.(IndexRefresher); ok {
+		err = refresher.Refresh()
+	}
+	if err == nil {
+		chartVersions, err = c.Operation.ListCharts()
+	}
+	metrics.ObserveRepoSync(c.Name, chartVersions, err)
+	if c.OnSync != nil {
+		c.OnSync(chartVersions, err)
+	}
+	if err != nil {
+		callbackFunc(nil, err)
+		return
+	}
+	for _, versions := range chartVersions {
+		if len(versions) == 0 {
+			continue
+		}
+		// the versions sort in ascending order, the latest version is the last one
+		sort.Sort(versions)
+		var item = versions[len(versions)-1]
+		callbackFunc(&item, nil)
+	}
+}
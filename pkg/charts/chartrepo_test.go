@@ -0,0 +1,45 @@
+package charts
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+func Test_ChartRepoClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chart-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	repo, err := NewChartRepo("offline", repoTypeLocalPath, "", "", "", "", "", "", false, 3600, WithLocalPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the repo is closed before the sync jobs started, e.g. the repo config updated quickly
+	closed := make(chan struct{})
+	go func() {
+		repo.Close()
+		repo.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close the repo blocked")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		repo.StartTimerJobs(func(chart *utils.CommonChartVersion, err error) {})
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the sync jobs not stopped after the repo closed")
+	}
+}
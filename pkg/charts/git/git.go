@@ -36,9 +36,17 @@ type Repo struct {
 	authMethod      transport.AuthMethod
 	RepoName        string
 	InsecureSkipTLS bool
+	// CABundle the ca bundle added to the system roots for the https git repo
+	CABundle []byte
 }
 
-func NewRepo(url, username, password, token, branch, localPath, repoName string, insecureSkipTLS bool) (*Repo, error) {
+// NewRepo clone or fetch the git repo, the client certificate and the timeout of the transport are not
+// supported by go-git, only the ca bundle and the insecure option are used
+func NewRepo(url, username, password, token, branch, localPath, repoName string, transport utils.TransportOptions) (*Repo, error) {
+	transport, err := transport.Resolve()
+	if err != nil {
+		return nil, err
+	}
 	g := &Repo{
 		URL:             url,
 		Username:        username,
@@ -47,7 +55,8 @@ func NewRepo(url, username, password, token, branch, localPath, repoName string,
 		LocalPath:       localPath,
 		Branch:          branch,
 		RepoName:        repoName,
-		InsecureSkipTLS: insecureSkipTLS,
+		InsecureSkipTLS: transport.InsecureSkipVerify,
+		CABundle:        transport.CAData,
 	}
 
 	if g.Username != "" && g.Password != "" {
@@ -60,28 +69,40 @@ func NewRepo(url, username, password, token, branch, localPath, repoName string,
 	if g.Token != "" {
 		g.authMethod = &githttp.TokenAuth{Token: g.Token}
 	}
-	err := g.Clone()
+	err = g.Clone()
 	if err != nil {
-		if err == GitPathExistErr {
-			err = g.Pull()
-			if err != nil {
-				return nil, err
-			}
+		if err != GitPathExistErr {
+			return nil, err
+		}
+		if err = g.Pull(); err != nil {
+			return nil, err
 		}
-		return nil, err
 	}
 	return g, nil
 }
 
+// repoPath the local path of the git repo, each repo clone to a sub dir of the local path by the repo name
+func (g *Repo) repoPath() string {
+	return path.Join(g.LocalPath, g.RepoName)
+}
+
+// RootPath the root of the git checkout, implement charts.LocalRepo
+func (g *Repo) RootPath() string {
+	return g.repoPath()
+}
+
 func (g *Repo) checkPathCanClone() error {
-	fileInfo, err := os.Stat(g.LocalPath)
+	if err := os.MkdirAll(g.repoPath(), 0755); err != nil {
+		return err
+	}
+	fileInfo, err := os.Stat(g.repoPath())
 	if err != nil {
 		return err
 	}
 	if !fileInfo.IsDir() {
 		return errors.New("local path not a dir")
 	}
-	var gitCachePath = path.Join(g.LocalPath, ".git")
+	var gitCachePath = path.Join(g.repoPath(), ".git")
 	_, err = os.Stat(gitCachePath)
 	if err == nil {
 		return GitPathExistErr
@@ -91,14 +112,14 @@ func (g *Repo) checkPathCanClone() error {
 
 func (g *Repo) checkPathCanPull() error {
 
-	fileInfo, err := os.Stat(g.LocalPath)
+	fileInfo, err := os.Stat(g.repoPath())
 	if err != nil {
 		return err
 	}
 	if !fileInfo.IsDir() {
 		return errors.New("local path not a dir")
 	}
-	var gitCachePath = path.Join(g.LocalPath, ".git")
+	var gitCachePath = path.Join(g.repoPath(), ".git")
 	fileInfo, err = os.Stat(gitCachePath)
 	if err != nil {
 		return err
@@ -118,10 +139,11 @@ func (g *Repo) Clone() error {
 		URL:             g.URL,
 		Progress:        os.Stdout,
 		InsecureSkipTLS: g.InsecureSkipTLS,
+		CABundle:        g.CABundle,
 		ReferenceName:   plumbing.NewBranchReferenceName(g.Branch),
 	}
 	cloneOptions.Auth = g.authMethod
-	_, err = git.PlainClone(g.LocalPath, false, cloneOptions)
+	_, err = git.PlainClone(g.repoPath(), false, cloneOptions)
 	if err != nil {
 		return err
 	}
@@ -133,17 +155,18 @@ func (g *Repo) Pull() error {
 	if err != nil {
 		return err
 	}
-	fileInfo, err := os.Stat(g.LocalPath)
+	fileInfo, err := os.Stat(g.repoPath())
 	if err != nil {
 		return nil
 	}
 	if fileInfo.IsDir() {
-		r, err := git.PlainOpen(g.LocalPath)
+		r, err := git.PlainOpen(g.repoPath())
 		if err != nil {
 			return err
 		}
 		var fetchOptions = &git.FetchOptions{
 			InsecureSkipTLS: g.InsecureSkipTLS,
+			CABundle:        g.CABundle,
 		}
 		fetchOptions.Auth = g.authMethod
 		err = r.Fetch(fetchOptions)
@@ -194,7 +217,7 @@ func (g *Repo) CheckChartExist(chartName, version string) bool {
 }
 
 func (g *Repo) GetChartVersionUrl(chartName, chartVersion string) (url, pathType string, err error) {
-	var chartPath = path.Join(g.LocalPath, g.RepoName, "charts", chartName, chartVersion)
+	var chartPath = path.Join(g.repoPath(), "charts", chartName, chartVersion)
 	_, err = os.Stat(chartPath)
 	if err != nil {
 		return "", "", err
@@ -202,12 +225,28 @@ func (g *Repo) GetChartVersionUrl(chartName, chartVersion string) (url, pathType
 	return chartPath, "file", nil
 }
 
+// ChartPath pull the git repo and return the local path of the chart dir, the dir must be in the git repo
+func (g *Repo) ChartPath(dir string) (string, error) {
+	if err := g.Pull(); err != nil {
+		return "", err
+	}
+	var chartPath = filepath.Join(g.repoPath(), filepath.Clean("/"+dir))
+	fileInfo, err := os.Stat(chartPath)
+	if err != nil {
+		return "", err
+	}
+	if !fileInfo.IsDir() {
+		return "", errors.Errorf("chart path %s not a dir", dir)
+	}
+	return chartPath, nil
+}
+
 func (g *Repo) getChartVersions(chartName string) ([]string, error) {
 	err := g.Pull()
 	if err != nil {
 		return nil, err
 	}
-	var chartPath = path.Join(g.LocalPath, g.RepoName, "charts", chartName)
+	var chartPath = path.Join(g.repoPath(), "charts", chartName)
 	fileInfo, err := os.Stat(chartPath)
 	if err != nil {
 		return nil, err
@@ -242,7 +281,7 @@ func (g *Repo) ListCharts() (map[string]utils.CommonChartVersions, error) {
 		return nil, err
 	}
 	var result = map[string]utils.CommonChartVersions{}
-	var chartPath = path.Join(g.LocalPath, g.RepoName, "charts")
+	var chartPath = path.Join(g.repoPath(), "charts")
 	fileInfo, err := os.Stat(chartPath)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,148 @@
+package localpath
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+var (
+	ChartNotExistErr        = errors.New("chart not exit for local path")
+	ChartVersionNotExistErr = errors.New("chart version not exit for local path")
+)
+
+// LocalPath the chart repo of a directory, like a volume mounted to the controller,
+// the `.tgz` chart archives and the chart directories under the directory are the chart versions
+type LocalPath struct {
+	Path     string
+	RepoName string
+}
+
+func NewLocalPath(path, repoName string) (*LocalPath, error) {
+	if !filepath.IsAbs(path) {
+		return nil, errors.Errorf("the path %s of the local path repo must be absolute", path)
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fileInfo.IsDir() {
+		return nil, errors.Errorf("the path %s of the local path repo is not a dir", path)
+	}
+	return &LocalPath{Path: filepath.Clean(path), RepoName: repoName}, nil
+}
+
+// RootPath the directory of the repo, implement charts.LocalRepo
+func (c *LocalPath) RootPath() string {
+	return c.Path
+}
+
+// chartVersion the chart version of the `.tgz` file or the chart directory, the directories are not
+// chart versions return false
+func (c *LocalPath) chartVersion(path string, d fs.DirEntry) (*utils.CommonChartVersion, bool, error) {
+	if d.IsDir() {
+		chartFile := filepath.Join(path, chartutil.ChartfileName)
+		if _, err := os.Stat(chartFile); err != nil {
+			return nil, false, nil
+		}
+		metadata, err := chartutil.LoadChartfile(chartFile)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "load chart file %s error", chartFile)
+		}
+		return &utils.CommonChartVersion{Name: metadata.Name, Version: metadata.Version, URLType: "file",
+			URL: path, RepoName: c.RepoName}, true, nil
+	}
+	if !strings.HasSuffix(path, ".tgz") {
+		return nil, false, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	chart, err := loader.LoadFile(path)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "load chart archive %s error", path)
+	}
+	sum := sha256.Sum256(data)
+	return &utils.CommonChartVersion{Name: chart.Name(), Version: chart.Metadata.Version, URLType: "file",
+		URL: path, Digest: hex.EncodeToString(sum[:]), RepoName: c.RepoName}, true, nil
+}
+
+func (c *LocalPath) ListCharts() (map[string]utils.CommonChartVersions, error) {
+	var result = map[string]utils.CommonChartVersions{}
+	err := filepath.WalkDir(c.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != c.Path {
+			return filepath.SkipDir
+		}
+		version, ok, err := c.chartVersion(path, d)
+		if err != nil || !ok {
+			return err
+		}
+		result[version.Name] = append(result[version.Name], *version)
+		if d.IsDir() {
+			// the sub charts of the chart directory are not chart versions
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *LocalPath) getChartVersions(chartName string) (utils.CommonChartVersions, error) {
+	chartVersions, err := c.ListCharts()
+	if err != nil {
+		return nil, err
+	}
+	versions, ok := chartVersions[chartName]
+	if !ok {
+		return nil, ChartNotExistErr
+	}
+	return versions, nil
+}
+
+func (c *LocalPath) GetChartLastVersion(chartName string) (string, error) {
+	versions, err := c.getChartVersions(chartName)
+	if err != nil {
+		return "", err
+	}
+	var vers []string
+	for _, item := range versions {
+		vers = append(vers, item.Version)
+	}
+	return utils.GetLatestSemver(vers)
+}
+
+// GetChartVersionUrl return the path of the chart archive or the chart directory
+func (c *LocalPath) GetChartVersionUrl(chartName, chartVersion string) (url, pathType string, err error) {
+	versions, err := c.getChartVersions(chartName)
+	if err != nil {
+		return "", "", err
+	}
+	for _, item := range versions {
+		if item.Version == chartVersion {
+			return item.URL, "file", nil
+		}
+	}
+	return "", "", ChartVersionNotExistErr
+}
+
+func (c *LocalPath) CheckChartExist(chartName, version string) bool {
+	_, _, err := c.GetChartVersionUrl(chartName, version)
+	return err == nil
+}
@@ -0,0 +1,60 @@
+package localpath
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func Test_LocalPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "nginx", Version: "1.0.0"}}
+	archive, err := chartutil.Save(c, filepath.Join(dir, "archives"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Metadata.Version = "1.1.0"
+	if err = chartutil.SaveDir(c, dir); err != nil {
+		t.Fatal(err)
+	}
+	// the sub chart of the chart directory is not a chart version
+	sub := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "child", Version: "0.1.0"}}
+	if err = chartutil.SaveDir(sub, filepath.Join(dir, "nginx", "charts")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewLocalPath("charts", "offline"); err == nil {
+		t.Error("expect error for the relative path")
+	}
+	repo, err := NewLocalPath(dir, "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartVersions, err := repo.ListCharts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chartVersions) != 1 || len(chartVersions["nginx"]) != 2 {
+		t.Fatalf("unexpected chart versions %v", chartVersions)
+	}
+	version, err := repo.GetChartLastVersion("nginx")
+	if err != nil || version != "1.1.0" {
+		t.Fatalf("expect the latest version 1.1.0, got %s %v", version, err)
+	}
+	url, pathType, err := repo.GetChartVersionUrl("nginx", "1.0.0")
+	if err != nil || url != archive || pathType != "file" {
+		t.Fatalf("unexpected chart url %s %s %v", url, pathType, err)
+	}
+	url, _, err = repo.GetChartVersionUrl("nginx", "1.1.0")
+	if err != nil || url != filepath.Join(dir, "nginx") {
+		t.Fatalf("unexpected chart url %s %v", url, err)
+	}
+}
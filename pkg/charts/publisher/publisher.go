@@ -0,0 +1,126 @@
+package publisher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/shijunLee/helmops/pkg/charts/chartmuseum"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+)
+
+// Target the chart repo which the packaged charts pushed to
+type Target interface {
+	CheckChartExist(chartName, version string) bool
+	Push(archive []byte) error
+}
+
+var _ Target = &chartmuseum.ChartMuseum{}
+
+// LintError the lint of the chart failed, the chart is not pushed
+type LintError struct {
+	Chart    string
+	Messages []string
+}
+
+func (e *LintError) Error() string {
+	return fmt.Sprintf("lint chart %s failed: %s", e.Chart, strings.Join(e.Messages, "; "))
+}
+
+// Result the result of the publish
+type Result struct {
+	Name    string
+	Version string
+	// Digest the sha256 digest of the pushed chart archive, empty if the version is not pushed
+	Digest string
+	// Published the chart version is pushed, false if the version already exist in the target
+	Published bool
+}
+
+// Publisher package the chart dir and push the chart to the target if the version in Chart.yaml is new
+type Publisher struct {
+	// Lint the lint options of the packaged chart, the lint is skipped if nil
+	Lint *actions.LintOptions
+	// Namespace the namespace to render the templates in the lint
+	Namespace string
+	// DependencyCacheDir the cache dir of the dependency charts which not vendored
+	DependencyCacheDir string
+	// Resolver resolve the named repositories of the dependencies
+	Resolver actions.NamedRepoResolver
+}
+
+// Publish build the dependencies of the chart dir, then package, lint and push the chart if the
+// version not exist in the target
+func (p *Publisher) Publish(chartPath string, target Target) (*Result, error) {
+	chartOptions := &actions.ChartOpts{
+		LocalPath:          chartPath,
+		DependencyCacheDir: p.DependencyCacheDir,
+		DependencyResolver: p.Resolver,
+	}
+	c, err := chartOptions.LoadChart()
+	if err != nil {
+		return nil, errors.Wrapf(err, "load chart %s error", chartPath)
+	}
+	result := &Result{Name: c.Name(), Version: c.Metadata.Version}
+	if target.CheckChartExist(result.Name, result.Version) {
+		return result, nil
+	}
+	archive, err := Package(c)
+	if err != nil {
+		return nil, err
+	}
+	if p.Lint != nil {
+		if err = p.lint(result, archive); err != nil {
+			return nil, err
+		}
+	}
+	err = target.Push(archive)
+	if err == chartmuseum.ChartVersionExistErr {
+		return result, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "push chart %s-%s error", result.Name, result.Version)
+	}
+	digest := sha256.Sum256(archive)
+	result.Digest = hex.EncodeToString(digest[:])
+	result.Published = true
+	return result, nil
+}
+
+// lint the chart archive, return LintError if there is any message reach the tolerance
+func (p *Publisher) lint(result *Result, archive []byte) error {
+	lintResult, err := p.Lint.Run(p.Namespace, &actions.ChartOpts{ChartArchive: bytes.NewBuffer(archive)})
+	if err != nil {
+		return errors.Wrapf(err, "lint chart %s-%s error", result.Name, result.Version)
+	}
+	if len(lintResult.Errors) == 0 {
+		return nil
+	}
+	lintErr := &LintError{Chart: fmt.Sprintf("%s-%s", result.Name, result.Version)}
+	for _, item := range lintResult.Errors {
+		lintErr.Messages = append(lintErr.Messages, item.Error())
+	}
+	return lintErr
+}
+
+// Package save the loaded chart with the dependencies to a chart archive like `helm package`
+func Package(c *chart.Chart) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "helmops-package-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file, err := chartutil.Save(c, dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "package chart %s error", c.Name())
+	}
+	return ioutil.ReadFile(file)
+}
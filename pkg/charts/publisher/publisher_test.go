@@ -0,0 +1,112 @@
+package publisher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shijunLee/helmops/pkg/charts/chartmuseum"
+	"github.com/shijunLee/helmops/pkg/helm/actions"
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+// museum a chartMuseum stand-in which serve the index of the uploaded charts
+type museum struct {
+	mu      sync.Mutex
+	index   *repo.IndexFile
+	uploads int
+}
+
+func (m *museum) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/index.yaml":
+		data, _ := yaml.Marshal(m.index)
+		_, _ = w.Write(data)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/charts":
+		user, pass, _ := r.BasicAuth()
+		if user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		c, err := loader.LoadArchive(bytes.NewReader(data))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if m.index.Has(c.Name(), c.Metadata.Version) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		m.index.MustAdd(c.Metadata, c.Name()+"-"+c.Metadata.Version+".tgz", "charts", "sha256:0")
+		m.uploads++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func Test_Publish(t *testing.T) {
+	stub := &museum{index: repo.NewIndexFile()}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	target, err := chartmuseum.NewChartMuseum(server.URL, "admin", "secret", "museum", utils.TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	chartPath, err := chartutil.Create("nginx", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Publisher{Lint: &actions.LintOptions{}, Namespace: "default"}
+
+	result, err := p.Publish(chartPath, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Published || result.Name != "nginx" || result.Version != "0.1.0" || result.Digest == "" {
+		t.Errorf("expect nginx 0.1.0 published, got %+v", result)
+	}
+	if !target.CheckChartExist("nginx", "0.1.0") {
+		t.Error("expect the index refreshed after the chart pushed")
+	}
+	// the version already exist in the target
+	if result, err = p.Publish(chartPath, target); err != nil {
+		t.Fatal(err)
+	}
+	if result.Published || stub.uploads != 1 {
+		t.Errorf("expect the existing version not pushed again, got %+v after %d uploads", result, stub.uploads)
+	}
+
+	// the chart is not pushed if the lint failed
+	c, err := loader.LoadDir(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Metadata.Version = "0.2.0"
+	if err = chartutil.SaveChartfile(filepath.Join(chartPath, chartutil.ChartfileName), c.Metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(chartPath, "templates", "broken.yaml"), []byte("{{ .Values.missing.name }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Publish(chartPath, target)
+	if _, ok := err.(*LintError); !ok {
+		t.Errorf("expect lint error, got %v", err)
+	}
+	if stub.uploads != 1 {
+		t.Errorf("expect the chart not pushed after the lint failed, got %d uploads", stub.uploads)
+	}
+}
@@ -0,0 +1,241 @@
+package s3
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+const (
+	// presignExpires the expiration of the signed chart urls
+	presignExpires = 15 * time.Minute
+	requestTimeout = 30 * time.Second
+	defaultRegion  = "us-east-1"
+)
+
+var (
+	ChartNotExistErr        = errors.New("chart not exit for s3")
+	ChartVersionNotExistErr = errors.New("chart version not exit for s3")
+)
+
+// Config the bucket and the credentials of the s3 repo
+type Config struct {
+	// Endpoint the s3 endpoint like `https://s3.us-east-1.amazonaws.com` or `http://minio:9000`,
+	// default is the aws endpoint of the region
+	Endpoint string
+	// Bucket the bucket name
+	Bucket string
+	// Prefix the key prefix of the index.yaml and the chart archives
+	Prefix string
+	// Region the region of the bucket, default is us-east-1
+	Region string
+	// PathStyle use the url like `<endpoint>/<bucket>/<key>` instead of the virtual hosted bucket
+	PathStyle bool
+	// AccessKeyID the access key, the requests are not signed if it is empty
+	AccessKeyID string
+	// SecretAccessKey the secret key
+	SecretAccessKey string
+	// SessionToken the session token of the temporary credentials
+	SessionToken string
+	// Transport the tls options and the timeout of the requests to the endpoint
+	Transport utils.TransportOptions
+}
+
+// S3 the chart repo of the index.yaml and chart archives in a s3 compatible bucket
+type S3 struct {
+	Config
+	RepoName string
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3(config Config, repoName string) (*S3, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("the bucket of the s3 repo is empty")
+	}
+	if config.Region == "" {
+		config.Region = defaultRegion
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	if config.Transport.Timeout == 0 {
+		config.Transport.Timeout = requestTimeout
+	}
+	client, err := utils.NewHTTPClient(config.Transport)
+	if err != nil {
+		return nil, err
+	}
+	c := &S3{
+		Config:   config,
+		RepoName: repoName,
+		client:   client,
+		now:      time.Now,
+	}
+	_, err = c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// objectURL the url of the object key in the bucket
+func (c *S3) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "s3 endpoint %s is invalid", c.Endpoint)
+	}
+	key = strings.TrimPrefix(key, "/")
+	if c.PathStyle {
+		u.Path = "/" + path.Join(c.Bucket, key)
+	} else {
+		u.Host = c.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+// signedURL the url which can be downloaded without the credentials before it expired
+func (c *S3) signedURL(key string) (string, error) {
+	u, err := c.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	if c.AccessKeyID == "" {
+		return u.String(), nil
+	}
+	return presign(http.MethodGet, u, c.AccessKeyID, c.SecretAccessKey, c.SessionToken, c.Region,
+		c.now(), presignExpires).String(), nil
+}
+
+// chartURL the signed url of the chart archive, the url in the index is relative to the prefix,
+// or like `s3://<bucket>/<key>` which written by the helm s3 plugin
+func (c *S3) chartURL(chartURL string) (string, error) {
+	if strings.HasPrefix(chartURL, "http://") || strings.HasPrefix(chartURL, "https://") {
+		return chartURL, nil
+	}
+	if strings.HasPrefix(chartURL, "s3://") {
+		u, err := url.Parse(chartURL)
+		if err != nil {
+			return "", err
+		}
+		if u.Host != c.Bucket {
+			return "", errors.Errorf("chart url %s is not in the bucket %s", chartURL, c.Bucket)
+		}
+		return c.signedURL(u.Path)
+	}
+	return c.signedURL(path.Join(c.Prefix, chartURL))
+}
+
+func (c *S3) loadIndex() (map[string]repo.ChartVersions, error) {
+	indexURL, err := c.signedURL(path.Join(c.Prefix, "index.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Get(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("get index.yaml of bucket %s failed with status %d", c.Bucket, resp.StatusCode)
+	}
+	indexFile := repo.NewIndexFile()
+	if err = yaml.Unmarshal(data, indexFile); err != nil {
+		return nil, errors.Wrapf(err, "parse index.yaml of bucket %s error", c.Bucket)
+	}
+	indexFile.SortEntries()
+	return indexFile.Entries, nil
+}
+
+func (c *S3) GetChartLastVersion(chartName string) (string, error) {
+	vers, err := c.getChartVersions(chartName)
+	if err != nil {
+		return "", err
+	}
+	return utils.GetLatestSemver(vers)
+}
+
+// GetChartVersionUrl return the signed url of the chart archive
+func (c *S3) GetChartVersionUrl(chartName, chartVersion string) (url, pathType string, err error) {
+	chartVersions, err := c.loadIndex()
+	if err != nil {
+		return "", "", err
+	}
+	versions, ok := chartVersions[chartName]
+	if !ok {
+		return "", "", ChartNotExistErr
+	}
+	for _, item := range versions {
+		if item.Version == chartVersion && len(item.URLs) > 0 {
+			url, err = c.chartURL(item.URLs[0])
+			return url, "http", err
+		}
+	}
+	return "", "", ChartVersionNotExistErr
+}
+
+func (c *S3) CheckChartExist(chartName, version string) bool {
+	vers, err := c.getChartVersions(chartName)
+	if err != nil {
+		return false
+	}
+	for _, item := range vers {
+		if item == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *S3) getChartVersions(chartName string) ([]string, error) {
+	chartVersions, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	versions, ok := chartVersions[chartName]
+	if !ok {
+		return nil, ChartNotExistErr
+	}
+	var vers []string
+	for _, item := range versions {
+		vers = append(vers, item.Version)
+	}
+	return vers, nil
+}
+
+func (c *S3) ListCharts() (map[string]utils.CommonChartVersions, error) {
+	index, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	var result = map[string]utils.CommonChartVersions{}
+	for key, versions := range index {
+		for _, item := range versions {
+			if len(item.URLs) == 0 {
+				continue
+			}
+			chartURL, err := c.chartURL(item.URLs[0])
+			if err != nil {
+				return nil, err
+			}
+			result[key] = append(result[key], utils.CommonChartVersion{Name: key, Version: item.Version, URLType: "http",
+				URL: chartURL, Digest: item.Digest, RepoName: c.RepoName})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  redis:
+  - name: redis
+    version: 1.0.0
+    digest: sha256:1111
+    urls:
+    - redis-1.0.0.tgz
+  - name: redis
+    version: 1.1.0
+    digest: sha256:2222
+    urls:
+    - s3://charts/stable/redis-1.1.0.tgz
+`
+
+// newBucketServer the stand-in of a minio server with path style urls, the requests must be signed
+func newBucketServer(t *testing.T, objects map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("X-Amz-Signature") == "" || !strings.HasPrefix(query.Get("X-Amz-Credential"), "minio/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		content, ok := objects[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+}
+
+func Test_S3Repo(t *testing.T) {
+	server := newBucketServer(t, map[string]string{
+		"/charts/stable/index.yaml":      testIndex,
+		"/charts/stable/redis-1.0.0.tgz": "chart",
+		"/charts/stable/redis-1.1.0.tgz": "chart",
+	})
+	defer server.Close()
+	repo, err := NewS3(Config{
+		Endpoint:        server.URL,
+		Bucket:          "charts",
+		Prefix:          "stable",
+		PathStyle:       true,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := repo.GetChartLastVersion("redis")
+	if err != nil || version != "1.1.0" {
+		t.Fatalf("expect the last version 1.1.0, got %s %v", version, err)
+	}
+	if !repo.CheckChartExist("redis", "1.0.0") || repo.CheckChartExist("redis", "2.0.0") {
+		t.Error("unexpected chart exist result")
+	}
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		chartURL, pathType, err := repo.GetChartVersionUrl("redis", version)
+		if err != nil || pathType != "http" {
+			t.Fatalf("get chart url error %v", err)
+		}
+		resp, err := http.Get(chartURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("download chart %s from the signed url failed with status %d", version, resp.StatusCode)
+		}
+	}
+	charts, err := repo.ListCharts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts["redis"]) != 2 || charts["redis"][0].Digest == "" {
+		t.Errorf("unexpected charts %v", charts)
+	}
+
+	if _, err = NewS3(Config{Endpoint: server.URL, Bucket: "charts", Prefix: "stable", PathStyle: true}, "test"); err == nil {
+		t.Error("expect the unsigned request forbidden")
+	}
+}
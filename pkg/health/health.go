@@ -0,0 +1,202 @@
+package health
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Status the health status of the release resources
+type Status string
+
+const (
+	StatusHealthy     Status = "Healthy"
+	StatusProgressing Status = "Progressing"
+	StatusDegraded    Status = "Degraded"
+)
+
+// Result the health assessment result, message show the first unhealthy resource
+type Result struct {
+	Status  Status
+	Message string
+}
+
+// severity the worse status has the greater severity
+func (s Status) severity() int {
+	switch s {
+	case StatusDegraded:
+		return 2
+	case StatusProgressing:
+		return 1
+	}
+	return 0
+}
+
+// Assess aggregate the health of all the objects, the result is the worst status of the objects,
+// the kinds not supported are treated as healthy
+func Assess(objects []*unstructured.Unstructured) Result {
+	var result = Result{Status: StatusHealthy, Message: fmt.Sprintf("%d resources are healthy", len(objects))}
+	for _, obj := range objects {
+		item := assessObject(obj)
+		if item.Status.severity() > result.Status.severity() {
+			result = item
+		}
+	}
+	return result
+}
+
+func assessObject(obj *unstructured.Unstructured) Result {
+	var result Result
+	var err error
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Deployment.apps":
+		var deployment = &appsv1.Deployment{}
+		if err = fromUnstructured(obj, deployment); err == nil {
+			result = assessDeployment(deployment)
+		}
+	case "StatefulSet.apps":
+		var statefulSet = &appsv1.StatefulSet{}
+		if err = fromUnstructured(obj, statefulSet); err == nil {
+			result = assessStatefulSet(statefulSet)
+		}
+	case "DaemonSet.apps":
+		var daemonSet = &appsv1.DaemonSet{}
+		if err = fromUnstructured(obj, daemonSet); err == nil {
+			result = assessDaemonSet(daemonSet)
+		}
+	case "Job.batch":
+		var job = &batchv1.Job{}
+		if err = fromUnstructured(obj, job); err == nil {
+			result = assessJob(job)
+		}
+	case "PersistentVolumeClaim":
+		var pvc = &corev1.PersistentVolumeClaim{}
+		if err = fromUnstructured(obj, pvc); err == nil {
+			result = assessPVC(pvc)
+		}
+	case "Service":
+		var service = &corev1.Service{}
+		if err = fromUnstructured(obj, service); err == nil {
+			result = assessService(service)
+		}
+	default:
+		return Result{Status: StatusHealthy}
+	}
+	if err != nil {
+		return Result{Status: StatusDegraded, Message: fmt.Sprintf("%s %s/%s can not be parsed: %v",
+			obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)}
+	}
+	if result.Message != "" {
+		result.Message = fmt.Sprintf("%s %s/%s %s", obj.GetKind(), obj.GetNamespace(), obj.GetName(), result.Message)
+	}
+	return result
+}
+
+func fromUnstructured(obj *unstructured.Unstructured, target interface{}) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, target)
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func assessDeployment(deployment *appsv1.Deployment) Result {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return Result{Status: StatusProgressing, Message: "waiting for the spec to be observed"}
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == "ProgressDeadlineExceeded" {
+			return Result{Status: StatusDegraded, Message: "exceeded its progress deadline"}
+		}
+		if condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue {
+			return Result{Status: StatusDegraded, Message: condition.Message}
+		}
+	}
+	var replicas = replicasOrDefault(deployment.Spec.Replicas)
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)}
+	case status.Replicas > status.UpdatedReplicas:
+		return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas)}
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas)}
+	}
+	return Result{Status: StatusHealthy}
+}
+
+func assessStatefulSet(statefulSet *appsv1.StatefulSet) Result {
+	if statefulSet.Generation > statefulSet.Status.ObservedGeneration {
+		return Result{Status: StatusProgressing, Message: "waiting for the spec to be observed"}
+	}
+	var replicas = replicasOrDefault(statefulSet.Spec.Replicas)
+	status := statefulSet.Status
+	if status.ReadyReplicas < replicas {
+		return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, replicas)}
+	}
+	if statefulSet.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType {
+		var partition int32
+		if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+			partition = *rollingUpdate.Partition
+		}
+		if status.UpdatedReplicas < replicas-partition {
+			return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas-partition)}
+		}
+	}
+	return Result{Status: StatusHealthy}
+}
+
+func assessDaemonSet(daemonSet *appsv1.DaemonSet) Result {
+	if daemonSet.Generation > daemonSet.Status.ObservedGeneration {
+		return Result{Status: StatusProgressing, Message: "waiting for the spec to be observed"}
+	}
+	status := daemonSet.Status
+	if daemonSet.Spec.UpdateStrategy.Type == appsv1.RollingUpdateDaemonSetStrategyType &&
+		status.UpdatedNumberScheduled < status.DesiredNumberScheduled {
+		return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d of %d pods updated", status.UpdatedNumberScheduled, status.DesiredNumberScheduled)}
+	}
+	if status.NumberAvailable < status.DesiredNumberScheduled {
+		return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d of %d pods available", status.NumberAvailable, status.DesiredNumberScheduled)}
+	}
+	return Result{Status: StatusHealthy}
+}
+
+func assessJob(job *batchv1.Job) Result {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobFailed:
+			return Result{Status: StatusDegraded, Message: fmt.Sprintf("failed: %s", condition.Message)}
+		case batchv1.JobComplete:
+			return Result{Status: StatusHealthy}
+		}
+	}
+	return Result{Status: StatusProgressing, Message: fmt.Sprintf("%d pods active", job.Status.Active)}
+}
+
+func assessPVC(pvc *corev1.PersistentVolumeClaim) Result {
+	switch pvc.Status.Phase {
+	case corev1.ClaimBound:
+		return Result{Status: StatusHealthy}
+	case corev1.ClaimLost:
+		return Result{Status: StatusDegraded, Message: "lost its volume"}
+	}
+	return Result{Status: StatusProgressing, Message: "waiting for the volume bound"}
+}
+
+func assessService(service *corev1.Service) Result {
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(service.Status.LoadBalancer.Ingress) == 0 {
+		return Result{Status: StatusProgressing, Message: "waiting for the load balancer ingress"}
+	}
+	return Result{Status: StatusHealthy}
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newObject(apiVersion, kind string, spec, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": "test", "namespace": "default", "generation": int64(1)},
+		"spec":       spec,
+		"status":     status,
+	}}
+}
+
+func Test_Assess(t *testing.T) {
+	healthyDeployment := newObject("apps/v1", "Deployment", map[string]interface{}{"replicas": int64(2)},
+		map[string]interface{}{"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2)})
+	crashingDeployment := newObject("apps/v1", "Deployment", map[string]interface{}{"replicas": int64(2)},
+		map[string]interface{}{"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(0)})
+	stuckDeployment := newObject("apps/v1", "Deployment", map[string]interface{}{"replicas": int64(2)},
+		map[string]interface{}{"observedGeneration": int64(1), "conditions": []interface{}{
+			map[string]interface{}{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded"},
+		}})
+	failedJob := newObject("batch/v1", "Job", map[string]interface{}{},
+		map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"},
+		}})
+	pendingPVC := newObject("v1", "PersistentVolumeClaim", map[string]interface{}{}, map[string]interface{}{"phase": "Pending"})
+	configMap := newObject("v1", "ConfigMap", nil, nil)
+
+	var cases = []struct {
+		name    string
+		objects []*unstructured.Unstructured
+		expect  Status
+	}{
+		{"empty", nil, StatusHealthy},
+		{"healthy", []*unstructured.Unstructured{healthyDeployment, configMap}, StatusHealthy},
+		{"unavailable replicas", []*unstructured.Unstructured{healthyDeployment, crashingDeployment}, StatusProgressing},
+		{"pending pvc", []*unstructured.Unstructured{pendingPVC}, StatusProgressing},
+		{"progress deadline exceeded", []*unstructured.Unstructured{crashingDeployment, stuckDeployment}, StatusDegraded},
+		{"failed job", []*unstructured.Unstructured{pendingPVC, failedJob}, StatusDegraded},
+	}
+	for _, item := range cases {
+		result := Assess(item.objects)
+		if result.Status != item.expect {
+			t.Errorf("%s: expect %s, got %s (%s)", item.name, item.expect, result.Status, result.Message)
+		}
+	}
+}
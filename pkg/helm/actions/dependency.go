@@ -0,0 +1,263 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+// NamedRepoResolver resolve the dependency repository referenced by name, like `@name` or `alias:name`
+type NamedRepoResolver interface {
+	// RepoURL return the url of the named repo
+	RepoURL(repoName string) (string, bool)
+	// ResolveChart find the chart version match the version constraint in the named repo
+	ResolveChart(repoName, chartName, versionConstraint string) (*ChartOpts, error)
+}
+
+// DependencyBuildOptions build the dependencies of the chart directory which not vendored in the charts dir,
+// like `helm dependency build` but the charts are not written to the chart directory
+type DependencyBuildOptions struct {
+	// ChartPath the chart directory
+	ChartPath string
+	// RootPath the root of the repo checkout, the `file://` dependencies out of it are rejected,
+	// default to the ChartPath
+	RootPath string
+	// CacheDir the cache dir of the downloaded dependency chart archives
+	CacheDir string
+	// Resolver resolve the named repositories, named repositories are not support if not set
+	Resolver NamedRepoResolver
+	// InsecureSkipTLSVerify skip tls certificate checks for the dependency download
+	InsecureSkipTLSVerify bool
+}
+
+// Run load the chart directory and add the missing dependencies to it
+func (d *DependencyBuildOptions) Run() (*chart.Chart, error) {
+	c, err := loader.LoadDir(d.ChartPath)
+	if err != nil {
+		return nil, err
+	}
+	if c.Metadata == nil || len(c.Metadata.Dependencies) == 0 {
+		return c, nil
+	}
+	var vendored = map[string]bool{}
+	for _, item := range c.Dependencies() {
+		vendored[item.Name()] = true
+	}
+	var missing []*chart.Dependency
+	for _, dependency := range c.Metadata.Dependencies {
+		if !vendored[dependency.Name] {
+			missing = append(missing, dependency)
+		}
+	}
+	if len(missing) == 0 {
+		return c, nil
+	}
+	versions, err := d.lockedVersions(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, dependency := range missing {
+		var version = dependency.Version
+		if locked, ok := versions[dependency.Name]; ok {
+			version = locked
+		}
+		subChart, err := d.loadDependency(dependency, version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "build dependency %s of chart %s error", dependency.Name, c.Name())
+		}
+		c.AddDependency(subChart)
+	}
+	return c, nil
+}
+
+// lockedVersions verify the Chart.lock is in sync with the dependencies in Chart.yaml
+// and return the locked version of each dependency
+func (d *DependencyBuildOptions) lockedVersions(c *chart.Chart) (map[string]string, error) {
+	var versions = map[string]string{}
+	if c.Lock == nil {
+		return versions, nil
+	}
+	// helm hash the dependencies after the repo names are resolved to urls, the raw dependencies
+	// are checked too so the lock generated with a different repo name still work
+	var resolved []*chart.Dependency
+	for _, dependency := range c.Metadata.Dependencies {
+		item := *dependency
+		if repoName, ok := namedRepo(item.Repository); ok && d.Resolver != nil {
+			if url, ok := d.Resolver.RepoURL(repoName); ok {
+				item.Repository = url
+			}
+		}
+		resolved = append(resolved, &item)
+	}
+	var inSync bool
+	for _, req := range [][]*chart.Dependency{resolved, c.Metadata.Dependencies} {
+		sum, err := hashDependencies(req, c.Lock.Dependencies)
+		if err == nil && sum == c.Lock.Digest {
+			inSync = true
+			break
+		}
+	}
+	if !inSync {
+		return nil, errors.New("the lock file (Chart.lock) is out of sync with the dependencies file (Chart.yaml), please update the dependencies")
+	}
+	for _, dependency := range c.Lock.Dependencies {
+		versions[dependency.Name] = dependency.Version
+	}
+	return versions, nil
+}
+
+// loadDependency load the dependency chart from the local path, the named repo or the repo url
+func (d *DependencyBuildOptions) loadDependency(dependency *chart.Dependency, version string) (*chart.Chart, error) {
+	repository := dependency.Repository
+	if strings.HasPrefix(repository, "file://") {
+		path, err := d.localDependencyPath(strings.TrimPrefix(repository, "file://"))
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return loader.Load(path)
+		}
+		subBuild := *d
+		subBuild.ChartPath = path
+		subBuild.RootPath = d.rootPath()
+		return subBuild.Run()
+	}
+	if cached, ok := d.loadCache(repository, dependency.Name, version); ok {
+		return cached, nil
+	}
+	if repoName, ok := namedRepo(repository); ok {
+		if d.Resolver == nil {
+			return nil, errors.Errorf("named repository %s is not supported", repository)
+		}
+		chartOpts, err := d.Resolver.ResolveChart(repoName, dependency.Name, version)
+		if err != nil {
+			return nil, err
+		}
+		if chartOpts.ChartURL == "" {
+			return chartOpts.LoadChart()
+		}
+		archive, err := utils.DownloadChartArchiveWithTransport(chartOpts.ChartURL, chartOpts.AuthInfo.Username,
+			chartOpts.AuthInfo.Password, chartOpts.transportOptions())
+		if err != nil {
+			return nil, err
+		}
+		return d.saveCache(repository, archive)
+	}
+	if !strings.HasPrefix(repository, "http://") && !strings.HasPrefix(repository, "https://") {
+		return nil, errors.Errorf("repository %s is not supported", repository)
+	}
+	url, err := FindChartInAuthAndTLSRepoURL(repository, "", "", dependency.Name, version, "", "", "",
+		d.InsecureSkipTLSVerify, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		return nil, err
+	}
+	archive, err := utils.DownloadChartArchive(url, "", "", "", "", "", d.InsecureSkipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+	return d.saveCache(repository, archive)
+}
+
+// rootPath the root the local dependencies are confined to
+func (d *DependencyBuildOptions) rootPath() string {
+	if d.RootPath == "" {
+		return d.ChartPath
+	}
+	return d.RootPath
+}
+
+// localDependencyPath resolve the path of the `file://` dependency, the symlinks are resolved and
+// the path must be in the root path, e.g. `file://../../` can not read the files of other repos
+func (d *DependencyBuildOptions) localDependencyPath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(d.ChartPath, path)
+	}
+	root, err := filepath.EvalSymlinks(d.rootPath())
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve the root path %s error", d.rootPath())
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve the local dependency path %s error", path)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("the local dependency path %s is out of the repo", path)
+	}
+	return resolved, nil
+}
+
+// loadCache load the dependency chart from the cache, only the exact version is cached
+func (d *DependencyBuildOptions) loadCache(repository, name, version string) (*chart.Chart, bool) {
+	if d.CacheDir == "" {
+		return nil, false
+	}
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v")); err != nil {
+		return nil, false
+	}
+	c, err := loader.LoadFile(d.cacheFile(repository, name, version))
+	if err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// saveCache load the downloaded chart archive and save it to the cache
+func (d *DependencyBuildOptions) saveCache(repository string, archive *bytes.Buffer) (*chart.Chart, error) {
+	data := archive.Bytes()
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if d.CacheDir == "" {
+		return c, nil
+	}
+	path := d.cacheFile(repository, c.Name(), c.Metadata.Version)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		// the cache is only an optimization, the chart is still usable if it can not be written
+		_ = ioutil.WriteFile(path, data, 0644)
+	}
+	return c, nil
+}
+
+func (d *DependencyBuildOptions) cacheFile(repository, name, version string) string {
+	sum := sha256.Sum256([]byte(repository))
+	return filepath.Join(d.CacheDir, hex.EncodeToString(sum[:8]), fmt.Sprintf("%s-%s.tgz", name, version))
+}
+
+// namedRepo get the repo name of the repository reference like `@name` or `alias:name`
+func namedRepo(repository string) (string, bool) {
+	if strings.HasPrefix(repository, "@") {
+		return strings.TrimPrefix(repository, "@"), true
+	}
+	if strings.HasPrefix(repository, "alias:") {
+		return strings.TrimPrefix(repository, "alias:"), true
+	}
+	return "", false
+}
+
+// hashDependencies is the same as the digest of helm in Chart.lock
+func hashDependencies(req, lock []*chart.Dependency) (string, error) {
+	data, err := json.Marshal([2][]*chart.Dependency{req, lock})
+	if err != nil {
+		return "", err
+	}
+	s, err := provenance.Digest(bytes.NewBuffer(data))
+	return "sha256:" + s, err
+}
@@ -0,0 +1,131 @@
+package actions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+type fakeRepoResolver struct {
+	charts map[string]*chart.Chart
+}
+
+func (f fakeRepoResolver) RepoURL(repoName string) (string, bool) {
+	return "https://charts.example.com/" + repoName, true
+}
+
+func (f fakeRepoResolver) ResolveChart(repoName, chartName, versionConstraint string) (*ChartOpts, error) {
+	return &ChartOpts{Chart: f.charts[chartName]}, nil
+}
+
+func writeChart(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_DependencyBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dependency-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeChart(t, dir, map[string]string{
+		"child/Chart.yaml": "apiVersion: v2\nname: child\nversion: 0.1.0\n",
+		"parent/Chart.yaml": `apiVersion: v2
+name: parent
+version: 1.0.0
+dependencies:
+- name: child
+  version: 0.1.0
+  repository: file://../child
+- name: redis
+  version: ">=1.0.0"
+  repository: "@stable"
+`,
+	})
+	resolver := fakeRepoResolver{charts: map[string]*chart.Chart{
+		"redis": {Metadata: &chart.Metadata{APIVersion: "v2", Name: "redis", Version: "1.2.0"}},
+	}}
+	build := &DependencyBuildOptions{ChartPath: filepath.Join(dir, "parent"), RootPath: dir, Resolver: resolver}
+	c, err := build.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names = map[string]bool{}
+	for _, item := range c.Dependencies() {
+		names[item.Name()] = true
+	}
+	if !names["child"] || !names["redis"] {
+		t.Errorf("expect dependencies child and redis, got %v", names)
+	}
+
+	writeChart(t, dir, map[string]string{
+		"parent/Chart.lock": `dependencies:
+- name: child
+  repository: file://../child
+  version: 0.1.0
+digest: sha256:0000
+`,
+	})
+	if _, err = build.Run(); err == nil {
+		t.Error("expect error for the out of sync Chart.lock")
+	}
+}
+
+func Test_DependencyBuildLocalPathEscape(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dependency-escape")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the repo checkout is dir/repo, dir/secret is the checkout of another repo
+	writeChart(t, dir, map[string]string{
+		"secret/Chart.yaml":      "apiVersion: v2\nname: secret\nversion: 0.1.0\n",
+		"repo/common/Chart.yaml": "apiVersion: v2\nname: common\nversion: 0.1.0\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "secret"), filepath.Join(dir, "repo", "link")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		repository string
+		wantErr    bool
+	}{
+		{name: "in the repo", repository: "file://../common"},
+		{name: "parent of the repo", repository: "file://../../secret", wantErr: true},
+		{name: "absolute path", repository: "file://" + filepath.Join(dir, "secret"), wantErr: true},
+		{name: "symlink out of the repo", repository: "file://../link", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeChart(t, dir, map[string]string{
+				"repo/web/Chart.yaml": "apiVersion: v2\nname: web\nversion: 1.0.0\ndependencies:\n" +
+					"- name: dependency\n  version: 0.1.0\n  repository: " + tt.repository + "\n",
+			})
+			build := &DependencyBuildOptions{ChartPath: filepath.Join(dir, "repo", "web"), RootPath: filepath.Join(dir, "repo")}
+			if _, err := build.Run(); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// the chart directory is the root without the repo root
+	writeChart(t, dir, map[string]string{
+		"repo/web/Chart.yaml": "apiVersion: v2\nname: web\nversion: 1.0.0\ndependencies:\n" +
+			"- name: common\n  version: 0.1.0\n  repository: file://../common\n",
+	})
+	build := &DependencyBuildOptions{ChartPath: filepath.Join(dir, "repo", "web")}
+	if _, err := build.Run(); err == nil {
+		t.Error("expect the local dependency out of the chart directory rejected without the root path")
+	}
+}
@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ChartDigestMismatchErr the digest of the chart archive is not the expected digest
+var ChartDigestMismatchErr = errors.New("the chart digest mismatch")
+
+// NormalizeDigest remove the algorithm prefix of the sha256 digest, the digests in the repo index have no prefix
+func NormalizeDigest(digest string) string {
+	return strings.ToLower(strings.TrimPrefix(digest, "sha256:"))
+}
+
+// CheckDigest compare the digest in the repo index with the expected digest before the chart downloaded
+func (c *ChartOpts) CheckDigest() error {
+	if c.ExpectedDigest == "" || c.Digest == "" || NormalizeDigest(c.Digest) == NormalizeDigest(c.ExpectedDigest) {
+		return nil
+	}
+	return errors.Wrapf(ChartDigestMismatchErr, "chart %s version %s digest in the repo is %s, expect %s",
+		c.ChartName, c.ChartVersion, NormalizeDigest(c.Digest), NormalizeDigest(c.ExpectedDigest))
+}
+
+// verifyArchive check the archive has the expected digest, the digest of the archive is recorded
+// if the repo not provide it
+func (c *ChartOpts) verifyArchive(data []byte) error {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if c.Digest == "" {
+		c.Digest = digest
+	}
+	if c.ExpectedDigest == "" || digest == NormalizeDigest(c.ExpectedDigest) {
+		return nil
+	}
+	return errors.Wrapf(ChartDigestMismatchErr, "chart %s version %s archive digest is %s, expect %s",
+		c.ChartName, c.ChartVersion, digest, NormalizeDigest(c.ExpectedDigest))
+}
+
+// checkDirectoryDigest the chart directory has no archive digest, it can not be pinned
+func (c *ChartOpts) checkDirectoryDigest() error {
+	if c.ExpectedDigest == "" {
+		return nil
+	}
+	return errors.Errorf("chart %s version %s is a chart directory, the digest can not be verified",
+		c.ChartName, c.ChartVersion)
+}
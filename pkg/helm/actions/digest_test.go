@@ -0,0 +1,57 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func Test_ChartDigest(t *testing.T) {
+	dir := t.TempDir()
+	chartPath, err := chartutil.Create("nginx", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := loader.LoadDir(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archivePath, err := chartutil.Save(c, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	opts := &ChartOpts{ChartName: "nginx", ChartVersion: "0.1.0", ChartArchive: bytes.NewBuffer(data), ExpectedDigest: "sha256:" + digest}
+	if _, err = opts.LoadChart(); err != nil {
+		t.Errorf("expect the archive with the pinned digest loaded, got %v", err)
+	}
+	opts = &ChartOpts{ChartName: "nginx", ChartVersion: "0.1.0", LocalPath: archivePath}
+	if _, err = opts.LoadChart(); err != nil || opts.Digest != digest {
+		t.Errorf("expect the digest of the archive recorded, got %s %v", opts.Digest, err)
+	}
+
+	opts = &ChartOpts{ChartName: "nginx", ChartVersion: "0.1.0", LocalPath: archivePath, ExpectedDigest: digest[1:] + "0"}
+	if _, err = opts.LoadChartFiles(); errors.Cause(err) != ChartDigestMismatchErr {
+		t.Errorf("expect digest mismatch of the archive, got %v", err)
+	}
+	opts = &ChartOpts{ChartName: "nginx", ChartVersion: "0.1.0", Digest: digest, ExpectedDigest: digest[1:] + "0"}
+	if err = opts.CheckDigest(); errors.Cause(err) != ChartDigestMismatchErr {
+		t.Errorf("expect digest mismatch of the repo index, got %v", err)
+	}
+	opts = &ChartOpts{ChartName: "nginx", ChartVersion: "0.1.0", LocalPath: chartPath, ExpectedDigest: digest}
+	if _, err = opts.LoadChart(); err == nil {
+		t.Error("expect the digest of the chart directory can not be verified")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	helmactions "helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/release"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
 	diskcached "k8s.io/client-go/discovery/cached/disk"
@@ -20,6 +21,10 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
+const (
+	serverVersionTimeout = 10 * time.Second
+)
+
 var (
 	defaultCacheDir = filepath.Join(homedir.HomeDir(), ".kube", "http-cache")
 	ErrEmptyConfig  = errors.New(`Missing or incomplete configuration info.  Please point to an existing, complete config file:
@@ -37,7 +42,9 @@ type KubernetesClient struct {
 	Namespace      string
 	ClientName     string
 	Config         *rest.Config
-	Log            func(string, ...interface{})
+	// Impersonate the user which the helm actions impersonate, it override the impersonation in the configs
+	Impersonate rest.ImpersonationConfig
+	Log         func(string, ...interface{})
 }
 
 type Option func(k *KubernetesClient)
@@ -72,6 +79,13 @@ func WithClientName(clientName string) Option {
 	}
 }
 
+// WithImpersonate impersonate the user for all the requests of the client
+func WithImpersonate(impersonate rest.ImpersonationConfig) Option {
+	return func(k *KubernetesClient) {
+		k.Impersonate = impersonate
+	}
+}
+
 func NewKubernetesClient(opts ...Option) *KubernetesClient {
 	kubernetesClient := &KubernetesClient{}
 	for _, fn := range opts {
@@ -133,30 +147,41 @@ func (t *KubernetesClient) ToRawKubeConfigLoader() clientcmd.ClientConfig {
 	if t.ConfigString != "" {
 		config, err := clientcmd.Load([]byte(t.ConfigString))
 		if err == nil {
-			result := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: t.Namespace}})
+			result := clientcmd.NewDefaultClientConfig(*config, t.configOverrides())
 			return result
 		}
 	}
 	if t.ConfigFilePath != "" {
 		config, err := clientcmd.LoadFromFile(t.ConfigFilePath)
 		if err == nil {
-			return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: t.Namespace}})
+			return clientcmd.NewDefaultClientConfig(*config, t.configOverrides())
 		}
 	}
 	if t.Config != nil {
 		var apiConfig = t.ConvertRestConfigToAPIConfig(t.Config)
-		return clientcmd.NewDefaultClientConfig(apiConfig, &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: t.Namespace}})
+		return clientcmd.NewDefaultClientConfig(apiConfig, t.configOverrides())
 	}
 	//not test for this
 	config, err := rest.InClusterConfig()
 	if err == nil {
 		var apiConfig = t.ConvertRestConfigToAPIConfig(config)
-		return clientcmd.NewDefaultClientConfig(apiConfig, &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: t.Namespace}})
+		return clientcmd.NewDefaultClientConfig(apiConfig, t.configOverrides())
 	}
 
 	return &clientcmd.DirectClientConfig{}
 }
 
+// configOverrides override the namespace and the impersonation of the kubeconfig
+func (t *KubernetesClient) configOverrides() *clientcmd.ConfigOverrides {
+	overrides := &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: t.Namespace}}
+	if t.Impersonate.UserName != "" {
+		overrides.AuthInfo.Impersonate = t.Impersonate.UserName
+		overrides.AuthInfo.ImpersonateGroups = t.Impersonate.Groups
+		overrides.AuthInfo.ImpersonateUserExtra = t.Impersonate.Extra
+	}
+	return overrides
+}
+
 func (t *KubernetesClient) ConvertRestConfigToAPIConfig(restConfig *rest.Config) clientcmdapi.Config {
 	clusters := make(map[string]*clientcmdapi.Cluster)
 	clusters["default-cluster"] = &clientcmdapi.Cluster{
@@ -236,6 +261,23 @@ func (t *KubernetesClient) GetHelmActionConfiguration(namespace string) (*helmac
 	return cfg, nil
 }
 
+// ServerVersion get the kubernetes server version, use it to check the cluster is reachable
+func (t *KubernetesClient) ServerVersion() (*version.Info, error) {
+	config, err := t.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	if config.Timeout == 0 {
+		config.Timeout = serverVersionTimeout
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return discoveryClient.ServerVersion()
+}
+
 // overlyCautiousIllegalFileCharacters matches characters that *might* not be supported.  Windows is really restrictive, so this is really restrictive
 var overlyCautiousIllegalFileCharacters = regexp.MustCompile(`[^(\w/\.)]`)
 
@@ -289,7 +331,9 @@ func (i *InstallOptions) Run() (*release.Release, error) {
 	installConfig := helmactions.NewInstall(cfg)
 	installConfig.SkipCRDs = i.SkipCRDs
 	installConfig.DryRun = i.DryRun
-	installConfig.RepoURL = i.ChartOpts.RepoOptions.RepoURL
+	if i.ChartOpts.RepoOptions != nil {
+		installConfig.RepoURL = i.ChartOpts.RepoOptions.RepoURL
+	}
 	installConfig.CreateNamespace = i.CreateNamespace
 	installConfig.Timeout = i.Timeout
 	installConfig.DisableHooks = i.NoHook
@@ -0,0 +1,24 @@
+package actions
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func Test_KubernetesClientImpersonate(t *testing.T) {
+	username := "system:serviceaccount:default:deployer"
+	kubeClient := NewKubernetesClient(
+		WithRestConfig(&rest.Config{Host: "https://127.0.0.1:6443", BearerToken: "token"}),
+		WithImpersonate(rest.ImpersonationConfig{UserName: username}))
+	config, err := kubeClient.ToRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Impersonate.UserName != username {
+		t.Errorf("expect impersonate %s, got %s", username, config.Impersonate.UserName)
+	}
+	if config.BearerToken != "token" {
+		t.Errorf("expect the bearer token of the rest config, got %s", config.BearerToken)
+	}
+}
@@ -32,6 +32,13 @@ type RepoOptions struct {
 	CAFile   string
 	// InsecureSkipTLSVerify skip tls certificate checks for the chart download
 	InsecureSkipTLSVerify bool
+	// Transport the tls data and the timeout of the repo, the files and InsecureSkipTLSVerify are merged into it
+	Transport utils.TransportOptions
+}
+
+// transportOptions the transport options of the repo index and the chart download
+func (r *RepoOptions) transportOptions() utils.TransportOptions {
+	return mergeTransportOptions(r.Transport, r.CAFile, r.CertFile, r.KeyFile, r.InsecureSkipTLSVerify)
 }
 
 // ChartOpts helm chart options
@@ -45,17 +52,30 @@ type ChartOpts struct {
 	ChartVersion string
 	// ChartURL install chart url
 	ChartURL string
+	// Digest the digest of the chart archive in the repo index, empty if the repo not provide it
+	Digest string
+	// ExpectedDigest the chart archive must have the sha256 digest if set, the chart directories can not be verified
+	ExpectedDigest string
 
 	// InsecureSkipTLSVerify skip tls certificate checks for the chart download
 	InsecureSkipTLSVerify bool
 
 	//LocalPath chart local path
 	LocalPath string
+	//LocalRootPath the root of the repo checkout the LocalPath in, the local dependencies must be in it
+	LocalRootPath string
 	//AuthInfo chartURL auth info
 	AuthInfo AuthInfo
+	//Transport the tls data and the timeout of the chartURL repo, the files of AuthInfo and InsecureSkipTLSVerify are merged into it
+	Transport utils.TransportOptions
 
 	ChartArchive *bytes.Buffer
 	Chart        *chart.Chart
+
+	//DependencyCacheDir the cache dir of the dependency charts of the LocalPath chart directory
+	DependencyCacheDir string
+	//DependencyResolver resolve the dependency repository referenced by name
+	DependencyResolver NamedRepoResolver
 }
 
 type AuthInfo struct {
@@ -66,6 +86,27 @@ type AuthInfo struct {
 	PrivateKeyPath string
 }
 
+// transportOptions the transport options of the chartURL download
+func (c *ChartOpts) transportOptions() utils.TransportOptions {
+	return mergeTransportOptions(c.Transport, c.AuthInfo.RootCAPath, c.AuthInfo.CertPath, c.AuthInfo.PrivateKeyPath,
+		c.InsecureSkipTLSVerify)
+}
+
+// mergeTransportOptions set the files and the insecure option which not set in the transport options
+func mergeTransportOptions(opts utils.TransportOptions, caFile, certFile, keyFile string, insecureSkipTLSVerify bool) utils.TransportOptions {
+	if opts.CAFile == "" {
+		opts.CAFile = caFile
+	}
+	if opts.CertFile == "" {
+		opts.CertFile = certFile
+	}
+	if opts.KeyFile == "" {
+		opts.KeyFile = keyFile
+	}
+	opts.InsecureSkipVerify = opts.InsecureSkipVerify || insecureSkipTLSVerify
+	return opts
+}
+
 func (c *ChartOpts) LoadChartFiles() ([]*loader.BufferedFile, error) {
 	if c.Chart != nil {
 		var files = c.Chart.Files
@@ -85,6 +126,9 @@ func (c *ChartOpts) LoadChartFiles() ([]*loader.BufferedFile, error) {
 		if err == nil {
 			files := []*loader.BufferedFile{}
 			if pathState.IsDir() {
+				if err = c.checkDirectoryDigest(); err != nil {
+					return nil, err
+				}
 				var localPath = c.LocalPath
 				if !strings.HasSuffix(localPath, "/") {
 					localPath = fmt.Sprintf("%s/", localPath)
@@ -112,20 +156,29 @@ func (c *ChartOpts) LoadChartFiles() ([]*loader.BufferedFile, error) {
 				if err != nil {
 					return nil, err
 				}
+				if err = c.verifyArchive(data); err != nil {
+					return nil, err
+				}
 				return loader.LoadArchiveFiles(bytes.NewReader(data))
 			}
 		}
 	}
 	if c.ChartArchive != nil {
+		if err := c.verifyArchive(c.ChartArchive.Bytes()); err != nil {
+			return nil, err
+		}
 		return loader.LoadArchiveFiles(c.ChartArchive)
 	}
 
 	if c.ChartURL != "" {
-		bytesBuffer, err := utils.DownloadChartArchive(c.ChartURL, c.AuthInfo.Username, c.AuthInfo.Password, c.AuthInfo.RootCAPath,
-			c.AuthInfo.CertPath, c.AuthInfo.PrivateKeyPath, c.InsecureSkipTLSVerify)
+		bytesBuffer, err := utils.DownloadChartArchiveWithTransport(c.ChartURL, c.AuthInfo.Username, c.AuthInfo.Password,
+			c.transportOptions())
 		if err != nil {
 			return nil, err
 		}
+		if err = c.verifyArchive(bytesBuffer.Bytes()); err != nil {
+			return nil, err
+		}
 		return loader.LoadArchiveFiles(bytesBuffer)
 	}
 
@@ -136,11 +189,14 @@ func (c *ChartOpts) LoadChartFiles() ([]*loader.BufferedFile, error) {
 		if err != nil {
 			return nil, err
 		}
-		bytesBuffer, err := utils.DownloadChartArchive(url, c.RepoOptions.Username, c.RepoOptions.Password,
-			c.RepoOptions.CAFile, c.RepoOptions.CertFile, c.RepoOptions.KeyFile, false)
+		bytesBuffer, err := utils.DownloadChartArchiveWithTransport(url, c.RepoOptions.Username, c.RepoOptions.Password,
+			c.RepoOptions.transportOptions())
 		if err != nil {
 			return nil, err
 		}
+		if err = c.verifyArchive(bytesBuffer.Bytes()); err != nil {
+			return nil, err
+		}
 		return loader.LoadArchiveFiles(bytesBuffer)
 	}
 	return nil, errors.New("load chart error ,chart load method not config")
@@ -152,17 +208,34 @@ func (c *ChartOpts) LoadChart() (*chart.Chart, error) {
 		return c.Chart, nil
 	}
 	if c.LocalPath != "" {
-		_, err := os.Stat(c.LocalPath)
+		pathState, err := os.Stat(c.LocalPath)
 		if err == nil {
-			return loader.Load(c.LocalPath)
+			if pathState.IsDir() {
+				if err = c.checkDirectoryDigest(); err != nil {
+					return nil, err
+				}
+				// the charts in git are usually not vendored, build the dependencies like helm dependency build
+				dependencyBuild := &DependencyBuildOptions{
+					ChartPath:             c.LocalPath,
+					RootPath:              c.LocalRootPath,
+					CacheDir:              c.DependencyCacheDir,
+					Resolver:              c.DependencyResolver,
+					InsecureSkipTLSVerify: c.InsecureSkipTLSVerify,
+				}
+				return dependencyBuild.Run()
+			}
+			return c.loadArchive(ioutil.ReadFile(c.LocalPath))
 		}
 	}
 	if c.ChartArchive != nil {
+		if err := c.verifyArchive(c.ChartArchive.Bytes()); err != nil {
+			return nil, err
+		}
 		return loader.LoadArchive(c.ChartArchive)
 	}
 	if c.ChartURL != "" {
-		return utils.DownloadChartWithTLS(c.ChartURL, c.AuthInfo.Username, c.AuthInfo.Password, c.AuthInfo.RootCAPath,
-			c.AuthInfo.CertPath, c.AuthInfo.PrivateKeyPath, c.InsecureSkipTLSVerify)
+		return c.loadDownloadedArchive(utils.DownloadChartArchiveWithTransport(c.ChartURL, c.AuthInfo.Username, c.AuthInfo.Password,
+			c.transportOptions()))
 	}
 	if c.RepoOptions != nil {
 		url, err := FindChartInAuthAndTLSRepoURL(c.RepoOptions.RepoURL, c.RepoOptions.Username, c.RepoOptions.Password,
@@ -171,13 +244,32 @@ func (c *ChartOpts) LoadChart() (*chart.Chart, error) {
 		if err != nil {
 			return nil, err
 		}
-		return utils.DownloadChartWithTLS(url, c.RepoOptions.Username, c.RepoOptions.Password,
-			c.RepoOptions.CAFile, c.RepoOptions.CertFile, c.RepoOptions.KeyFile, false)
+		return c.loadDownloadedArchive(utils.DownloadChartArchiveWithTransport(url, c.RepoOptions.Username, c.RepoOptions.Password,
+			c.RepoOptions.transportOptions()))
 	}
 
 	return nil, errors.New("load chart error ,chart load method not config")
 }
 
+// loadDownloadedArchive verify and load the downloaded chart archive
+func (c *ChartOpts) loadDownloadedArchive(archive *bytes.Buffer, err error) (*chart.Chart, error) {
+	if err != nil {
+		return nil, err
+	}
+	return c.loadArchive(archive.Bytes(), nil)
+}
+
+// loadArchive verify and load the chart archive data
+func (c *ChartOpts) loadArchive(data []byte, err error) (*chart.Chart, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err = c.verifyArchive(data); err != nil {
+		return nil, err
+	}
+	return loader.LoadArchive(bytes.NewReader(data))
+}
+
 func (r *RepoOptions) GetLatestRepoIndex() (*repo.IndexFile, error) {
 	repoIndexURL := fmt.Sprintf("%s/index.yaml", r.RepoURL)
 	var indexFile = &IndexFile{
@@ -187,8 +279,7 @@ func (r *RepoOptions) GetLatestRepoIndex() (*repo.IndexFile, error) {
 
 	err := utils.HttpGetStruct(repoIndexURL, map[string]string{}, indexFile,
 		utils.WithBasicAuth(r.Username, r.Password),
-		utils.WithInsecureSkipVerifyTLS(r.InsecureSkipTLSVerify),
-		utils.WithTLSClientConfig(r.KeyFile, r.CAFile, r.CertFile))
+		utils.WithTransportOptions(r.transportOptions()))
 	if err != nil {
 		return nil, err
 	}
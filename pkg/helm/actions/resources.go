@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ResourcesOptions get the live objects of the resources in the release manifest
+type ResourcesOptions struct {
+	Namespace         string
+	Manifest          string
+	KubernetesOptions *KubernetesClient
+}
+
+// Run return the live objects from the cluster, return the not found error if any resource not exist
+func (i *ResourcesOptions) Run() ([]*unstructured.Unstructured, error) {
+	cfg, err := i.KubernetesOptions.GetHelmActionConfiguration(i.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := cfg.KubeClient.Build(bytes.NewBufferString(i.Manifest), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "build resources from release manifest error")
+	}
+	var result []*unstructured.Unstructured
+	for _, info := range resources {
+		if err = info.Get(); err != nil {
+			return nil, errors.Wrapf(err, "get %s %s/%s error", info.Mapping.GroupVersionKind.Kind, info.Namespace, info.Name)
+		}
+		obj, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		result = append(result, obj)
+	}
+	return result, nil
+}
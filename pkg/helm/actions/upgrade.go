@@ -90,7 +90,9 @@ func (i *UpgradeOptions) Run() (*release.Release, error) {
 	upgradeConfig := helmactions.NewUpgrade(cfg)
 	upgradeConfig.SkipCRDs = i.SkipCRDs
 	upgradeConfig.DryRun = i.DryRun
-	upgradeConfig.RepoURL = i.ChartOpts.RepoOptions.RepoURL
+	if i.ChartOpts.RepoOptions != nil {
+		upgradeConfig.RepoURL = i.ChartOpts.RepoOptions.RepoURL
+	}
 	upgradeConfig.Timeout = i.Timeout
 	upgradeConfig.DisableHooks = i.DisableHooks
 	upgradeConfig.DisableOpenAPIValidation = i.DisableOpenAPIValidation
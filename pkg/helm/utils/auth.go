@@ -0,0 +1,354 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuthTypeBasic the basic auth with the username and the password
+	AuthTypeBasic = "Basic"
+	// AuthTypeBearer the static bearer token
+	AuthTypeBearer = "Bearer"
+	// AuthTypeTokenExchange the bearer token got from the realm of the WWW-Authenticate challenge with
+	// the username and the password, like the docker registry token auth of harbor
+	AuthTypeTokenExchange = "TokenExchange"
+	// AuthTypeClientCredentials the bearer token got by the oauth2 client credentials grant
+	AuthTypeClientCredentials = "ClientCredentials"
+
+	// tokenExpirySkew refresh the token before it expired
+	tokenExpirySkew = 30 * time.Second
+	// defaultTokenExpiry the expiry of the token without expires_in
+	defaultTokenExpiry = 60 * time.Second
+)
+
+// AuthProvider authorize the requests to the repo
+type AuthProvider interface {
+	// Authorize set the credentials of the request
+	Authorize(req *http.Request) error
+	// Challenge handle the 401 response of the request, return true if the credentials renewed
+	// and the request should be sent again
+	Challenge(req *http.Request, resp *http.Response) (bool, error)
+}
+
+// AuthConfig the config of the auth provider, the fields used by the auth type must be set
+type AuthConfig struct {
+	// Type Basic, Bearer, TokenExchange or ClientCredentials
+	Type         string
+	Username     string
+	Password     string
+	Token        string
+	ClientID     string
+	ClientSecret string
+	// TokenURL the token endpoint of the client credentials grant
+	TokenURL string
+	// Scopes the scopes requested by the client credentials grant
+	Scopes []string
+}
+
+// NewAuthProvider create the auth provider of the config, the tokens are requested with the client
+func NewAuthProvider(config AuthConfig, client *http.Client) (AuthProvider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch config.Type {
+	case AuthTypeBasic:
+		return &BasicAuth{Username: config.Username, Password: config.Password}, nil
+	case AuthTypeBearer:
+		if config.Token == "" {
+			return nil, errors.New("the token of the bearer auth is empty")
+		}
+		return &BearerAuth{Token: config.Token}, nil
+	case AuthTypeTokenExchange:
+		return &TokenExchangeAuth{Username: config.Username, Password: config.Password, client: client,
+			tokens: map[string]*cachedToken{}, now: time.Now}, nil
+	case AuthTypeClientCredentials:
+		if config.TokenURL == "" || config.ClientID == "" {
+			return nil, errors.New("the token url and the client id of the client credentials auth must be set")
+		}
+		return &ClientCredentialsAuth{TokenURL: config.TokenURL, ClientID: config.ClientID,
+			ClientSecret: config.ClientSecret, Scopes: config.Scopes, client: client, now: time.Now}, nil
+	}
+	return nil, errors.Errorf("auth type %s not support", config.Type)
+}
+
+// BasicAuth set the basic auth of the requests
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuth) Authorize(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+func (a *BasicAuth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	return false, nil
+}
+
+// BearerAuth set the static bearer token of the requests
+type BearerAuth struct {
+	Token string
+}
+
+func (a *BearerAuth) Authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+func (a *BearerAuth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	return false, nil
+}
+
+// cachedToken the token and the time it expired
+type cachedToken struct {
+	token  string
+	expiry time.Time
+}
+
+func (t *cachedToken) valid(now time.Time) bool {
+	return t != nil && t.token != "" && now.Add(tokenExpirySkew).Before(t.expiry)
+}
+
+// tokenResponse the token response of the oauth2 token endpoint and the docker registry token server
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// requestToken send the token request and parse the token response
+func requestToken(client *http.Client, req *http.Request, now time.Time) (*cachedToken, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request token error")
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("request token from %s return %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var token tokenResponse
+	if err = json.Unmarshal(data, &token); err != nil {
+		return nil, errors.Wrap(err, "parse token response error")
+	}
+	result := &cachedToken{token: token.AccessToken, expiry: now.Add(defaultTokenExpiry)}
+	if result.token == "" {
+		result.token = token.Token
+	}
+	if result.token == "" {
+		return nil, errors.Errorf("the token response from %s has no token", req.URL.Host)
+	}
+	if token.ExpiresIn > 0 {
+		result.expiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return result, nil
+}
+
+// TokenExchangeAuth get the bearer token from the realm of the WWW-Authenticate challenge,
+// the tokens are cached by the realm, the service and the scope of the challenge
+type TokenExchangeAuth struct {
+	Username string
+	Password string
+	client   *http.Client
+	now      func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]*cachedToken
+	// hosts the challenge key of the host, the cached token is sent without the challenge
+	hosts map[string]string
+}
+
+func (a *TokenExchangeAuth) Authorize(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if token := a.tokens[a.hosts[req.URL.Host]]; token.valid(a.now()) {
+		req.Header.Set("Authorization", "Bearer "+token.token)
+	} else if a.Username != "" {
+		// the repo may accept the basic auth without the challenge
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	return nil
+}
+
+func (a *TokenExchangeAuth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	if !strings.EqualFold(scheme, "Bearer") || params["realm"] == "" {
+		return false, nil
+	}
+	key := strings.Join([]string{params["realm"], params["service"], params["scope"]}, "|")
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return false, errors.Wrapf(err, "the realm %s of the challenge is invalid", params["realm"])
+	}
+	query := tokenURL.Query()
+	for _, name := range []string{"service", "scope"} {
+		if params[name] != "" {
+			query.Set(name, params[name])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	if a.Username != "" {
+		tokenReq.SetBasicAuth(a.Username, a.Password)
+	}
+	token, err := requestToken(a.client, tokenReq, a.now())
+	if err != nil {
+		return false, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.hosts == nil {
+		a.hosts = map[string]string{}
+	}
+	a.tokens[key] = token
+	a.hosts[req.URL.Host] = key
+	return true, nil
+}
+
+// parseChallenge parse the WWW-Authenticate header like `Bearer realm="https://auth",service="registry"`
+func parseChallenge(header string) (string, map[string]string) {
+	var params = map[string]string{}
+	header = strings.TrimSpace(header)
+	index := strings.IndexByte(header, ' ')
+	if index < 0 {
+		return header, params
+	}
+	scheme, rest := header[:index], header[index+1:]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		index = strings.IndexByte(rest, '=')
+		if index < 0 {
+			break
+		}
+		name := strings.ToLower(strings.TrimSpace(rest[:index]))
+		rest = rest[index+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		params[name] = value
+	}
+	return scheme, params
+}
+
+// ClientCredentialsAuth get the bearer token with the oauth2 client credentials grant, the token is
+// cached until it is about to expire or rejected by the repo
+type ClientCredentialsAuth struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	client       *http.Client
+	now          func() time.Time
+
+	mu    sync.Mutex
+	token *cachedToken
+}
+
+// fetchToken request a new token from the token endpoint
+func (a *ClientCredentialsAuth) fetchToken(req *http.Request) (*cachedToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.SetBasicAuth(url.QueryEscape(a.ClientID), url.QueryEscape(a.ClientSecret))
+	return requestToken(a.client, tokenReq, a.now())
+}
+
+func (a *ClientCredentialsAuth) Authorize(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.token.valid(a.now()) {
+		token, err := a.fetchToken(req)
+		if err != nil {
+			return err
+		}
+		a.token = token
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token.token)
+	return nil
+}
+
+// Challenge drop the rejected token, the request is sent again with a new token
+func (a *ClientCredentialsAuth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// the token may already be renewed by the other request
+	if a.token != nil && req.Header.Get("Authorization") == "Bearer "+a.token.token {
+		a.token = nil
+	}
+	return true, nil
+}
+
+// authTransport authorize the requests with the auth provider, the request is sent again once
+// if the provider renewed the credentials for the 401 response
+type authTransport struct {
+	base http.RoundTripper
+	auth AuthProvider
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		request := req.Clone(req.Context())
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, errors.New("the request body can not be sent again for the auth challenge")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+		if err := t.auth.Authorize(request); err != nil {
+			return nil, errors.Wrap(err, "authorize request error")
+		}
+		resp, err := t.base.RoundTrip(request)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, err
+		}
+		renewed, err := t.auth.Challenge(request, resp)
+		if err != nil {
+			resp.Body.Close()
+			return nil, errors.Wrapf(err, "auth challenge of %s error", req.URL.Host)
+		}
+		if !renewed {
+			return resp, nil
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_TokenExchangeAuth(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("service") != "charts" || r.URL.Query().Get("scope") != "repository:library:pull" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(&tokenRequests, 1)
+		fmt.Fprint(w, `{"token":"exchanged","expires_in":300}`)
+	}))
+	defer tokenServer.Close()
+	var repoRequests int32
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&repoRequests, 1)
+		if r.Header.Get("Authorization") != "Bearer exchanged" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/service/token",service="charts",scope="repository:library:pull"`, tokenServer.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer repo.Close()
+
+	auth, err := NewAuthProvider(AuthConfig{Type: AuthTypeTokenExchange, Username: "admin", Password: "secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewHTTPClient(TransportOptions{Auth: auth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(repo.URL + "/index.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expect ok with the exchanged token, got %d", resp.StatusCode)
+		}
+	}
+	// the first request is challenged, the cached token is sent by the others
+	if tokenRequests != 1 || repoRequests != 4 {
+		t.Errorf("expect 1 token request and 4 repo requests, got %d and %d", tokenRequests, repoRequests)
+	}
+}
+
+func Test_ClientCredentialsAuth(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "helmops" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" ||
+			r.FormValue("scope") != "charts.read" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":120}`, atomic.AddInt32(&tokenRequests, 1))
+	}))
+	defer tokenServer.Close()
+	// the repo only accept the current token
+	var accepted atomic.Value
+	accepted.Store("Bearer token-1")
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != accepted.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer repo.Close()
+
+	provider, err := NewAuthProvider(AuthConfig{Type: AuthTypeClientCredentials, ClientID: "helmops",
+		ClientSecret: "secret", TokenURL: tokenServer.URL, Scopes: []string{"charts.read"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	provider.(*ClientCredentialsAuth).now = func() time.Time { return now }
+	client, err := NewHTTPClient(TransportOptions{Auth: provider})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(expect int32) {
+		t.Helper()
+		resp, err := client.Get(repo.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || tokenRequests != expect {
+			t.Fatalf("expect ok after %d token requests, got status %d after %d", expect, resp.StatusCode, tokenRequests)
+		}
+	}
+	get(1)
+	get(1)
+	// the token is refreshed before it expired
+	now = now.Add(100 * time.Second)
+	accepted.Store("Bearer token-2")
+	get(2)
+	// the revoked token is dropped and requested again
+	accepted.Store("Bearer token-3")
+	get(3)
+}
+
+func Test_BearerAuth(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer static" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer repo.Close()
+	if _, err := NewAuthProvider(AuthConfig{Type: AuthTypeBearer}, nil); err == nil {
+		t.Error("expect error for the empty bearer token")
+	}
+	auth, err := NewAuthProvider(AuthConfig{Type: AuthTypeBearer, Token: "static"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewHTTPClient(TransportOptions{Auth: auth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(repo.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expect ok with the static token, got %d", resp.StatusCode)
+	}
+}
+
+func Test_ParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service=registry,scope="repository:a/b:pull,push"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.example.com/token" || params["service"] != "registry" ||
+		params["scope"] != "repository:a/b:pull,push" {
+		t.Errorf("unexpected challenge %s %v", scheme, params)
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BreakerState the state of the circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed the requests are sent
+	BreakerClosed BreakerState = "Closed"
+	// BreakerOpen the requests are rejected without sending until the cooldown passed
+	BreakerOpen BreakerState = "Open"
+	// BreakerHalfOpen one request is sent to probe the repo, the breaker is closed if it succeeded
+	BreakerHalfOpen BreakerState = "HalfOpen"
+)
+
+var (
+	CircuitOpenErr = errors.New("circuit breaker is open")
+)
+
+// CircuitBreaker stop sending the requests to a failing repo, so the reconciles fail fast
+// instead of waiting for the timeouts and the retries
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu             sync.Mutex
+	state          BreakerState
+	failures       int
+	lastError      string
+	transitionTime time.Time
+	probing        bool
+	now            func() time.Time
+}
+
+// BreakerSnapshot the state of the circuit breaker at a time
+type BreakerSnapshot struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	LastError           string
+	TransitionTime      time.Time
+}
+
+// NewCircuitBreaker create the breaker which opened after the threshold consecutive failures,
+// and probe the repo after the cooldown
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: BreakerClosed, now: time.Now}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state != state {
+		b.state = state
+		b.transitionTime = b.now()
+	}
+}
+
+// Allow check the request can be sent, only one request is allowed when the breaker is half open
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.transitionTime) < b.cooldown {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Success record the request succeeded and close the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	b.setState(BreakerClosed)
+}
+
+// Failure record the request failed, the breaker is opened if the probe failed or the failures reach the threshold
+func (b *CircuitBreaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.probing = false
+		// the cooldown restart from the last failure
+		b.state = BreakerOpen
+		b.transitionTime = b.now()
+	}
+}
+
+// Abort release the probe of the request which not completed, e.g. the request cancelled,
+// the result is not recorded and the next request probe the repo again
+func (b *CircuitBreaker) Abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Snapshot return the current state of the breaker
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerSnapshot{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+		TransitionTime:      b.transitionTime,
+	}
+}
@@ -2,7 +2,7 @@ package utils
 
 import (
 	"bytes"
-	"errors"
+	"fmt"
 
 	"github.com/Masterminds/semver/v3"
 	"helm.sh/helm/v3/pkg/chart"
@@ -27,23 +27,26 @@ func DownloadChartWithTLS(chartUrl, username, password string,
 }
 
 func DownloadChartArchive(chartUrl, username, password string, caPath, certPath, privateKeyPath string, insecureSkipTLSVerify bool) (*bytes.Buffer, error) {
-	var opts []HttpRequestOptions
+	return DownloadChartArchiveWithTransport(chartUrl, username, password, TransportOptions{
+		CAFile:             caPath,
+		CertFile:           certPath,
+		KeyFile:            privateKeyPath,
+		InsecureSkipVerify: insecureSkipTLSVerify,
+	})
+}
+
+// DownloadChartArchiveWithTransport download the chart archive with the tls and the timeout options of the repo
+func DownloadChartArchiveWithTransport(chartUrl, username, password string, transport TransportOptions) (*bytes.Buffer, error) {
+	var opts = []HttpRequestOptions{WithTransportOptions(transport)}
 	if username != "" && password != "" {
 		opts = append(opts, WithBasicAuth(username, password))
 	}
-	if caPath != "" && certPath != "" && privateKeyPath != "" {
-		opts = append(opts, WithTLSClientConfig(privateKeyPath, caPath, certPath))
-	}
-	if insecureSkipTLSVerify {
-		opts = append(opts, WithInsecureSkipVerifyTLS(insecureSkipTLSVerify))
-	}
 	data, stateCode, _, err := HttpGet(chartUrl, nil, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if stateCode > 400 {
-		//todo if 401 return add token support
-		return nil, errors.New("download chart return 401 from repo")
+	if stateCode >= 400 {
+		return nil, fmt.Errorf("download chart %s return %d from repo", chartUrl, stateCode)
 	}
 	return bytes.NewBuffer(data), nil
 }
@@ -1,8 +1,6 @@
 package utils
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,13 +17,10 @@ type ContextType string
 
 type HttpUtil struct {
 	*http.Client
-	Username              string
-	Password              string
-	Header                http.Header
-	RootCAPath            string
-	PrivateKeyPath        string
-	CertPath              string
-	InsecureSkipVerifyTLS bool
+	Username  string
+	Password  string
+	Header    http.Header
+	Transport TransportOptions
 }
 
 type HttpRequestOptions func(r *HttpUtil)
@@ -41,15 +36,23 @@ func WithBasicAuth(username, password string) HttpRequestOptions {
 
 func WithInsecureSkipVerifyTLS(insecureSkipVerifyTLS bool) HttpRequestOptions {
 	return func(r *HttpUtil) {
-		r.InsecureSkipVerifyTLS = insecureSkipVerifyTLS
+		r.Transport.InsecureSkipVerify = insecureSkipVerifyTLS
 	}
 }
 
+// WithTLSClientConfig set the files of the ca bundle and the client certificate, the empty files are ignored
 func WithTLSClientConfig(privateKey, rootCA, certPath string) HttpRequestOptions {
 	return func(r *HttpUtil) {
-		r.PrivateKeyPath = privateKey
-		r.RootCAPath = rootCA
-		r.CertPath = certPath
+		r.Transport.KeyFile = privateKey
+		r.Transport.CAFile = rootCA
+		r.Transport.CertFile = certPath
+	}
+}
+
+// WithTransportOptions set the tls and the timeout options of the request
+func WithTransportOptions(opts TransportOptions) HttpRequestOptions {
+	return func(r *HttpUtil) {
+		r.Transport = opts
 	}
 }
 
@@ -62,7 +65,7 @@ func WithContentType(contextType ContextType) HttpRequestOptions {
 func WithTimeout(timeout int) HttpRequestOptions {
 	return func(r *HttpUtil) {
 		if timeout > 0 {
-			r.Timeout = time.Duration(timeout) * time.Second
+			r.Transport.Timeout = time.Duration(timeout) * time.Second
 		}
 	}
 }
@@ -71,46 +74,14 @@ func NewHttpRequest() *HttpUtil {
 	return &HttpUtil{Header: map[string][]string{}}
 }
 
+// Do send the request with the shared transport of the tls options, the tls errors are returned
+// instead of falling back to skip the verify
 func (h *HttpUtil) Do(req *http.Request) (*http.Response, error) {
-	var tr *http.Transport
-	if strings.ToLower(req.URL.Scheme) == "https" {
-		if h.InsecureSkipVerifyTLS {
-			tr = &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			}
-		} else if h.RootCAPath != "" && h.PrivateKeyPath != "" {
-			certs, err := tls.LoadX509KeyPair(h.RootCAPath, h.PrivateKeyPath)
-			if err == nil {
-				ca, err := x509.ParseCertificate(certs.Certificate[0])
-				if err == nil {
-					pool := x509.NewCertPool()
-					pool.AddCert(ca)
-
-					tr = &http.Transport{
-						TLSClientConfig: &tls.Config{RootCAs: pool},
-					}
-				}
-			}
-		} else if h.CertPath != "" && h.PrivateKeyPath != "" {
-			cert, err := tls.LoadX509KeyPair(h.CertPath, h.PrivateKeyPath)
-			if err == nil {
-				tr = &http.Transport{
-					TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
-				}
-			}
-
-		}
-		if tr == nil {
-			tr = &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			}
-		}
-	}
-	if tr == nil {
-		h.Client = http.DefaultClient
-	} else {
-		h.Client = &http.Client{Transport: tr}
+	client, err := NewHTTPClient(h.Transport)
+	if err != nil {
+		return nil, err
 	}
+	h.Client = client
 	return h.Client.Do(req)
 }
 
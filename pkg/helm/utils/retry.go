@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+	// maxRetryAfter the longest Retry-After waited, the response is returned if the repo ask to wait longer
+	maxRetryAfter = time.Minute
+)
+
+// RetryPolicy the retries of the failed requests, the zero value use the defaults
+type RetryPolicy struct {
+	// MaxRetries the retries after the first request, the negative value disable the retry
+	MaxRetries int
+	// InitialBackoff the wait before the first retry, it is doubled for each retry
+	InitialBackoff time.Duration
+	// MaxBackoff the longest wait between the retries
+	MaxBackoff time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = defaultMaxRetries
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	return p
+}
+
+// backoff the exponential wait with jitter before the retry
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff << uint(attempt)
+	if wait <= 0 || wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+var (
+	hostLimit      = rate.Limit(10)
+	hostBurst      = 20
+	hostLimiters   = map[string]*rate.Limiter{}
+	hostLimitersMu sync.Mutex
+)
+
+// SetHostRateLimit set the requests per second and the burst of each repo host
+func SetHostRateLimit(limit float64, burst int) {
+	hostLimitersMu.Lock()
+	defer hostLimitersMu.Unlock()
+	hostLimit = rate.Limit(limit)
+	hostBurst = burst
+	for _, limiter := range hostLimiters {
+		limiter.SetLimit(hostLimit)
+		limiter.SetBurst(hostBurst)
+	}
+}
+
+// hostLimiter the limiter shared by the requests to the host
+func hostLimiter(host string) *rate.Limiter {
+	hostLimitersMu.Lock()
+	defer hostLimitersMu.Unlock()
+	limiter, ok := hostLimiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(hostLimit, hostBurst)
+		hostLimiters[host] = limiter
+	}
+	return limiter
+}
+
+// retryTransport retry the requests failed with the network errors, 5xx and 429,
+// the requests are limited by the host and rejected when the circuit breaker is open
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+}
+
+// retryAfter parse the Retry-After header in seconds or http date
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// failed check the request failed by the repo, it is counted by the circuit breaker
+func failed(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryable check the error may be resolved by retry, the certificate errors are not
+func retryable(err error) bool {
+	if err == nil {
+		return true
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCertificate x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+	return !(stderrors.As(err, &unknownAuthority) || stderrors.As(err, &invalidCertificate) ||
+		stderrors.As(err, &hostname) || stderrors.As(err, &recordHeader))
+}
+
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var recorded bool
+	if t.breaker != nil {
+		if !t.breaker.Allow() {
+			return nil, errors.Wrapf(CircuitOpenErr, "request to %s rejected", req.URL.Host)
+		}
+		// the probe of the half open breaker must be released when the request cancelled
+		defer func() {
+			if !recorded {
+				t.breaker.Abort()
+			}
+		}()
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := hostLimiter(req.URL.Host).Wait(ctx); err != nil {
+			return nil, err
+		}
+		request := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request = req.Clone(ctx)
+			request.Body = body
+		}
+		resp, err := t.base.RoundTrip(request)
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		var wait = t.policy.backoff(attempt)
+		var retry = failed(resp, err) && retryable(err) && attempt < t.policy.MaxRetries && (req.Body == nil || req.GetBody != nil)
+		if retry && err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			if after, ok := retryAfter(resp, time.Now()); ok {
+				wait = after
+				retry = after <= maxRetryAfter
+			}
+		}
+		if !retry {
+			t.record(req, resp, err)
+			recorded = true
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// record the result of the request to the circuit breaker
+func (t *retryTransport) record(req *http.Request, resp *http.Response, err error) {
+	if t.breaker == nil {
+		return
+	}
+	if !failed(resp, err) {
+		t.breaker.Success()
+		return
+	}
+	if err == nil {
+		err = fmt.Errorf("request to %s return %d", req.URL.Host, resp.StatusCode)
+	}
+	t.breaker.Failure(err)
+}
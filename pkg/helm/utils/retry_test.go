@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_RetryTransport(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+	client, err := NewHTTPClient(TransportOptions{Retry: RetryPolicy{InitialBackoff: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Errorf("expect ok after 3 requests, got status %d after %d requests", resp.StatusCode, requests)
+	}
+
+	// the client errors are not retried
+	atomic.StoreInt32(&requests, 0)
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFound.Close()
+	resp, err = client.Get(notFound.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if requests != 1 {
+		t.Errorf("expect 404 not retried, got %d requests", requests)
+	}
+}
+
+func Test_RetryTransportCircuitBreaker(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	breaker := NewCircuitBreaker(2, time.Minute)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	client, err := NewHTTPClient(TransportOptions{Retry: RetryPolicy{MaxRetries: -1}, Breaker: breaker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if state := breaker.Snapshot().State; state != BreakerOpen {
+		t.Fatalf("expect the breaker open, got %s", state)
+	}
+	if _, err = client.Get(server.URL); errors.Cause(errors.Unwrap(err)) != CircuitOpenErr {
+		t.Errorf("expect the circuit open error, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expect the request rejected without sending, got %d requests", requests)
+	}
+
+	// probe the repo after the cooldown, the breaker is open again if the probe failed
+	now = now.Add(time.Minute)
+	if !breaker.Allow() || breaker.Allow() {
+		t.Error("expect only one probe allowed when the breaker is half open")
+	}
+	breaker.Failure(errors.New("probe failed"))
+	if snapshot := breaker.Snapshot(); snapshot.State != BreakerOpen || snapshot.LastError != "probe failed" {
+		t.Errorf("unexpected breaker snapshot %+v", snapshot)
+	}
+	now = now.Add(time.Minute)
+	if !breaker.Allow() {
+		t.Fatal("expect the probe allowed after the cooldown")
+	}
+	breaker.Success()
+	if snapshot := breaker.Snapshot(); snapshot.State != BreakerClosed || snapshot.ConsecutiveFailures != 0 {
+		t.Errorf("unexpected breaker snapshot %+v", snapshot)
+	}
+}
+
+func Test_RetryTransportCancelProbe(t *testing.T) {
+	var fail int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	breaker := NewCircuitBreaker(1, time.Minute)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	client, err := NewHTTPClient(TransportOptions{Retry: RetryPolicy{MaxRetries: -1}, Breaker: breaker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if state := breaker.Snapshot().State; state != BreakerOpen {
+		t.Fatalf("expect the breaker open, got %s", state)
+	}
+
+	// the probe after the cooldown is cancelled by the caller
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Do(req); err == nil {
+		t.Fatal("expect the probe cancelled")
+	}
+
+	// the cancelled probe is released, the next request probe the repo
+	atomic.StoreInt32(&fail, 0)
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expect the request allowed after the cancelled probe, got %v", err)
+	}
+	resp.Body.Close()
+	if state := breaker.Snapshot().State; state != BreakerClosed {
+		t.Errorf("expect the breaker closed after the probe succeeded, got %s", state)
+	}
+}
@@ -37,3 +37,16 @@ func GetVersionGreaterThan(v1, v2 string) bool {
 	}
 	return version1.GreaterThan(version2)
 }
+
+// CheckVersionConstraint check the version match the semver constraint, like `>=1.2.0 <2.0.0`
+func CheckVersionConstraint(version, constraint string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, errors.Wrapf(err, "parse version constraint %s error", constraint)
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, errors.Wrapf(err, "parse version %s error", version)
+	}
+	return c.Check(v), nil
+}
@@ -10,3 +10,28 @@ func Test_GetLatestSemver(t *testing.T) {
 	version, _ := GetLatestSemver(versions)
 	fmt.Println(version)
 }
+
+func Test_CheckVersionConstraint(t *testing.T) {
+	var cases = []struct {
+		version    string
+		constraint string
+		match      bool
+	}{
+		{"1.2.3", ">=1.2.0", true},
+		{"1.2.3", ">=1.2.0 <1.2.3", false},
+		{"2.0.0", "~1.2", false},
+		{"1.2.9", "~1.2", true},
+	}
+	for _, item := range cases {
+		match, err := CheckVersionConstraint(item.version, item.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match != item.match {
+			t.Errorf("version %s constraint %s expect %v, got %v", item.version, item.constraint, item.match, match)
+		}
+	}
+	if _, err := CheckVersionConstraint("1.0.0", "not a constraint"); err == nil {
+		t.Error("expect error for invalid constraint")
+	}
+}
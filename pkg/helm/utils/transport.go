@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TransportOptions the tls and the timeout options of the requests to a repo,
+// the pem data is used if both the data and the file are set
+type TransportOptions struct {
+	// CAFile the file of the ca bundle which added to the system roots
+	CAFile string
+	// CertFile the file of the client certificate for mTLS
+	CertFile string
+	// KeyFile the file of the private key of the client certificate
+	KeyFile string
+	// CAData the pem data of the ca bundle
+	CAData []byte
+	// CertData the pem data of the client certificate
+	CertData []byte
+	// KeyData the pem data of the private key of the client certificate
+	KeyData []byte
+	// InsecureSkipVerify skip the verify of the server certificate
+	InsecureSkipVerify bool
+	// Timeout the timeout of each request, no timeout if it is zero
+	Timeout time.Duration
+	// Retry the retries of the failed requests
+	Retry RetryPolicy
+	// Breaker the circuit breaker of the repo, the requests are not broken if nil
+	Breaker *CircuitBreaker
+	// Auth the auth provider of the repo, the requests are not authorized by the transport if nil
+	Auth AuthProvider
+}
+
+var (
+	// transports the transports shared by the requests with the same tls options, so the connections are reused
+	transports sync.Map
+)
+
+// readPEM return the data, or the content of the file if the data is empty
+func readPEM(data []byte, file string) ([]byte, error) {
+	if len(data) > 0 || file == "" {
+		return data, nil
+	}
+	return ioutil.ReadFile(file)
+}
+
+// Resolve read the pem files into the data, the transport is created from the data
+func (o TransportOptions) Resolve() (TransportOptions, error) {
+	var err error
+	if o.CAData, err = readPEM(o.CAData, o.CAFile); err != nil {
+		return o, errors.Wrap(err, "read ca file error")
+	}
+	if o.CertData, err = readPEM(o.CertData, o.CertFile); err != nil {
+		return o, errors.Wrap(err, "read client certificate file error")
+	}
+	if o.KeyData, err = readPEM(o.KeyData, o.KeyFile); err != nil {
+		return o, errors.Wrap(err, "read client key file error")
+	}
+	o.CAFile, o.CertFile, o.KeyFile = "", "", ""
+	return o, nil
+}
+
+// key the key of the shared transport, the rotated certificates get a new transport
+func (o TransportOptions) key() string {
+	h := sha256.New()
+	for _, data := range [][]byte{o.CAData, o.CertData, o.KeyData} {
+		sum := sha256.Sum256(data)
+		h.Write(sum[:])
+	}
+	if o.InsecureSkipVerify {
+		h.Write([]byte{1})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// tlsConfig create the tls config, the ca bundle is added to the system roots
+func (o TransportOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if len(o.CAData) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(o.CAData) {
+			return nil, errors.New("no certificate found in the ca bundle")
+		}
+		config.RootCAs = pool
+	}
+	if len(o.CertData) > 0 || len(o.KeyData) > 0 {
+		if len(o.CertData) == 0 || len(o.KeyData) == 0 {
+			return nil, errors.New("both the client certificate and the key must be set for mTLS")
+		}
+		cert, err := tls.X509KeyPair(o.CertData, o.KeyData)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate error")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewTransport return the transport of the options, the transport is shared by the same tls options,
+// the proxy is read from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	opts, err := opts.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	key := opts.key()
+	if tr, ok := transports.Load(key); ok {
+		return tr.(*http.Transport), nil
+	}
+	config, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       config,
+	}
+	actual, _ := transports.LoadOrStore(key, tr)
+	return actual.(*http.Transport), nil
+}
+
+// NewHTTPClient return the client of the shared transport with the timeout, the auth, the retries and the circuit breaker
+// of the options, the timeout is the limit of each attempt
+func NewHTTPClient(opts TransportOptions) (*http.Client, error) {
+	tr, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	var roundTripper http.RoundTripper = tr
+	if opts.Timeout > 0 {
+		roundTripper = &timeoutTransport{base: tr, timeout: opts.Timeout}
+	}
+	if opts.Auth != nil {
+		roundTripper = &authTransport{base: roundTripper, auth: opts.Auth}
+	}
+	return &http.Client{Transport: &retryTransport{
+		base:    roundTripper,
+		policy:  opts.Retry.withDefaults(),
+		breaker: opts.Breaker,
+	}}, nil
+}
+
+// timeoutTransport limit each attempt of the request, the body is read in the timeout too
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody cancel the context of the attempt when the body closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
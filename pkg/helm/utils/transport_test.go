@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// clientCertificate create a self signed client certificate
+func clientCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "helmops"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func Test_NewHTTPClient(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	certPEM, keyPEM := clientCertificate(t)
+
+	// the server certificate is not trusted without the ca bundle, the request must not fall back to skip the verify
+	client, err := NewHTTPClient(TransportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Get(server.URL); err == nil {
+		t.Error("expect the unknown authority error")
+	}
+
+	var opts = TransportOptions{CAData: caPEM, CertData: certPEM, KeyData: keyPEM, Timeout: 5 * time.Second}
+	client, err = NewHTTPClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expect the client certificate sent, got status %d", resp.StatusCode)
+	}
+
+	first, _ := NewTransport(opts)
+	second, _ := NewTransport(opts)
+	if first != second {
+		t.Error("expect the transport shared by the same options")
+	}
+
+	if _, err = NewHTTPClient(TransportOptions{CertData: certPEM}); err == nil {
+		t.Error("expect error for the client certificate without the key")
+	}
+	if _, err = NewHTTPClient(TransportOptions{CAData: []byte("invalid")}); err == nil {
+		t.Error("expect error for the invalid ca bundle")
+	}
+	if _, err = NewHTTPClient(TransportOptions{CAFile: "/not/exist/ca.crt"}); err == nil {
+		t.Error("expect error for the missing ca file")
+	}
+}
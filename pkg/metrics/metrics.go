@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"helm.sh/helm/v3/pkg/release"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+// the workqueue depth and retries of the repo job queue are exported by the controller-runtime workqueue provider
+// as workqueue_depth{name="repo-job-queue"} and workqueue_retries_total{name="repo-job-queue"}
+
+const (
+	namespace = "helmops"
+
+	ActionInstall   = "install"
+	ActionUpgrade   = "upgrade"
+	ActionRollback  = "rollback"
+	ActionUninstall = "uninstall"
+
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// releaseStatuses all the helm release status, the status gauge set 1 for the current status and 0 for others
+var releaseStatuses = []release.Status{
+	release.StatusUnknown,
+	release.StatusDeployed,
+	release.StatusUninstalled,
+	release.StatusSuperseded,
+	release.StatusFailed,
+	release.StatusUninstalling,
+	release.StatusPendingInstall,
+	release.StatusPendingUpgrade,
+	release.StatusPendingRollback,
+}
+
+var (
+	releaseActionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "release_actions_total",
+		Help:      "Total number of helm release actions by action and result",
+	}, []string{"action", "result"})
+
+	releaseActionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "release_action_duration_seconds",
+		Help:      "Duration of helm release actions by action and result",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"action", "result"})
+
+	releaseStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "release_status",
+		Help:      "The helm release status of the helm operation, 1 for the current status",
+	}, []string{"namespace", "name", "status"})
+
+	repoLastSyncTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "repo_last_sync_timestamp_seconds",
+		Help:      "The unix timestamp of the last successful sync of the helm repo",
+	}, []string{"repo"})
+
+	repoSyncErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "repo_sync_errors_total",
+		Help:      "Total number of helm repo sync errors",
+	}, []string{"repo"})
+
+	repoCharts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "repo_charts",
+		Help:      "The number of charts discovered in the helm repo",
+	}, []string{"repo"})
+
+	repoChartVersions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "repo_chart_versions",
+		Help:      "The number of chart versions discovered in the helm repo",
+	}, []string{"repo"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		releaseActionTotal,
+		releaseActionDuration,
+		releaseStatus,
+		repoLastSyncTimestamp,
+		repoSyncErrorsTotal,
+		repoCharts,
+		repoChartVersions,
+	)
+}
+
+// ObserveReleaseAction record the count and duration of the helm release action which started at start time
+func ObserveReleaseAction(action string, start time.Time, err error) {
+	var result = ResultSuccess
+	if err != nil {
+		result = ResultError
+	}
+	releaseActionTotal.WithLabelValues(action, result).Inc()
+	releaseActionDuration.WithLabelValues(action, result).Observe(time.Since(start).Seconds())
+}
+
+// SetReleaseStatus set the release status gauge of the helm operation
+func SetReleaseStatus(operationNamespace, operationName, status string) {
+	for _, item := range releaseStatuses {
+		var value float64
+		if string(item) == status {
+			value = 1
+		}
+		releaseStatus.WithLabelValues(operationNamespace, operationName, string(item)).Set(value)
+	}
+}
+
+// DeleteReleaseStatus remove the release status gauge of the deleted helm operation
+func DeleteReleaseStatus(operationNamespace, operationName string) {
+	for _, item := range releaseStatuses {
+		releaseStatus.DeleteLabelValues(operationNamespace, operationName, string(item))
+	}
+}
+
+// ObserveRepoSync record the result of a helm repo sync
+func ObserveRepoSync(repo string, chartVersions map[string]utils.CommonChartVersions, err error) {
+	if err != nil {
+		repoSyncErrorsTotal.WithLabelValues(repo).Inc()
+		return
+	}
+	var versions int
+	for _, item := range chartVersions {
+		versions += len(item)
+	}
+	repoLastSyncTimestamp.WithLabelValues(repo).SetToCurrentTime()
+	repoCharts.WithLabelValues(repo).Set(float64(len(chartVersions)))
+	repoChartVersions.WithLabelValues(repo).Set(float64(versions))
+}
+
+// DeleteRepo remove the metrics of the deleted helm repo
+func DeleteRepo(repo string) {
+	repoLastSyncTimestamp.DeleteLabelValues(repo)
+	repoSyncErrorsTotal.DeleteLabelValues(repo)
+	repoCharts.DeleteLabelValues(repo)
+	repoChartVersions.DeleteLabelValues(repo)
+}
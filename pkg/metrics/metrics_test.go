@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/shijunLee/helmops/pkg/helm/utils"
+)
+
+func Test_SetReleaseStatus(t *testing.T) {
+	SetReleaseStatus("default", "nginx", "deployed")
+	SetReleaseStatus("default", "nginx", "failed")
+	if value := testutil.ToFloat64(releaseStatus.WithLabelValues("default", "nginx", "failed")); value != 1 {
+		t.Errorf("expect failed status 1, got %v", value)
+	}
+	if value := testutil.ToFloat64(releaseStatus.WithLabelValues("default", "nginx", "deployed")); value != 0 {
+		t.Errorf("expect deployed status 0, got %v", value)
+	}
+	DeleteReleaseStatus("default", "nginx")
+	if count := testutil.CollectAndCount(releaseStatus); count != 0 {
+		t.Errorf("expect no release status series, got %d", count)
+	}
+}
+
+func Test_ObserveRepoSync(t *testing.T) {
+	ObserveRepoSync("stable", map[string]utils.CommonChartVersions{
+		"nginx": {{Name: "nginx", Version: "1.0.0"}, {Name: "nginx", Version: "1.1.0"}},
+		"redis": {{Name: "redis", Version: "6.0.0"}},
+	}, nil)
+	ObserveRepoSync("stable", nil, errors.New("sync error"))
+	if value := testutil.ToFloat64(repoCharts.WithLabelValues("stable")); value != 2 {
+		t.Errorf("expect 2 charts, got %v", value)
+	}
+	if value := testutil.ToFloat64(repoChartVersions.WithLabelValues("stable")); value != 3 {
+		t.Errorf("expect 3 chart versions, got %v", value)
+	}
+	if value := testutil.ToFloat64(repoSyncErrorsTotal.WithLabelValues("stable")); value != 1 {
+		t.Errorf("expect 1 sync error, got %v", value)
+	}
+	DeleteRepo("stable")
+}
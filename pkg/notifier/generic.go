@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// SignatureHeader the header of the hmac signature of the generic webhook body, the value is `sha256=<hex digest>`
+const SignatureHeader = "X-Helmops-Signature"
+
+// generic post the event as json to a webhook
+type generic struct {
+	opts Options
+}
+
+type genericPayload struct {
+	Event
+	// Text the message rendered with the template of the alert
+	Text string `json:"text"`
+}
+
+func (g *generic) Post(ctx context.Context, event Event, message string) error {
+	data, err := json.Marshal(genericPayload{Event: event, Text: message})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if len(g.opts.HMACKey) > 0 {
+		header.Set(SignatureHeader, "sha256="+Sign(g.opts.HMACKey, data))
+	}
+	return post(ctx, g.opts.Client, g.opts.Address, data, header)
+}
+
+// Sign the hex encoded hmac sha256 of the data, the receiver can verify the body with it
+func Sign(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
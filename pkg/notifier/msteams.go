@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// msTeams post the message card to a microsoft teams incoming webhook
+type msTeams struct {
+	opts Options
+}
+
+type msTeamsPayload struct {
+	Type       string           `json:"@type"`
+	Context    string           `json:"@context"`
+	ThemeColor string           `json:"themeColor"`
+	Summary    string           `json:"summary"`
+	Sections   []msTeamsSection `json:"sections"`
+}
+
+type msTeamsSection struct {
+	ActivityTitle    string        `json:"activityTitle"`
+	ActivitySubtitle string        `json:"activitySubtitle"`
+	Facts            []msTeamsFact `json:"facts"`
+}
+
+type msTeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (m *msTeams) Post(ctx context.Context, event Event, message string) error {
+	payload := msTeamsPayload{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: strings.TrimPrefix(eventColor(event), "#"),
+		Summary:    fmt.Sprintf("%s/%s %s", event.Namespace, event.Name, event.Type),
+		Sections: []msTeamsSection{{
+			ActivityTitle:    message,
+			ActivitySubtitle: fmt.Sprintf("%s/%s", event.Namespace, event.Name),
+			Facts: []msTeamsFact{
+				{Name: "Chart", Value: event.ChartName},
+				{Name: "Version", Value: event.ChartVersion},
+			},
+		}},
+	}
+	return postJSON(ctx, m.opts.Client, m.opts.Address, payload, nil)
+}
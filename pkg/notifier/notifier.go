@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Provider types, the same as the types of the NotificationProvider
+const (
+	ProviderSlack   = "Slack"
+	ProviderMSTeams = "MSTeams"
+	ProviderGeneric = "Generic"
+)
+
+const postTimeout = 15 * time.Second
+
+// Event the release lifecycle event of a helm operation
+type Event struct {
+	Type         string    `json:"type"`
+	Namespace    string    `json:"namespace"`
+	Name         string    `json:"name"`
+	ChartName    string    `json:"chartName,omitempty"`
+	ChartVersion string    `json:"chartVersion,omitempty"`
+	Revision     int       `json:"revision,omitempty"`
+	Message      string    `json:"message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Notifier post the message of the event to the provider
+type Notifier interface {
+	Post(ctx context.Context, event Event, message string) error
+}
+
+// Options the options of the notifier
+type Options struct {
+	// Address the webhook address
+	Address string
+	// Channel the slack channel
+	Channel string
+	// Username the slack user name
+	Username string
+	// HMACKey sign the body of the generic webhook if set
+	HMACKey []byte
+	// Client the http client, the default client with a timeout is used if not set
+	Client *http.Client
+}
+
+// New create the notifier of the provider type
+func New(providerType string, opts Options) (Notifier, error) {
+	if opts.Address == "" {
+		return nil, errors.New("the address of the notifier is empty")
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: postTimeout}
+	}
+	switch providerType {
+	case ProviderSlack:
+		return &slack{opts: opts}, nil
+	case ProviderMSTeams:
+		return &msTeams{opts: opts}, nil
+	case ProviderGeneric:
+		return &generic{opts: opts}, nil
+	}
+	return nil, errors.Errorf("notification provider type %s is not supported", providerType)
+}
+
+// postJSON post the payload as json, the response status must be 2xx
+func postJSON(ctx context.Context, client *http.Client, address string, payload interface{}, header http.Header) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return post(ctx, client, address, data, header)
+}
+
+func post(ctx context.Context, client *http.Client, address string, data []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post to %s failed with status %d: %s", req.URL.Host, resp.StatusCode, string(body))
+	}
+	return nil
+}
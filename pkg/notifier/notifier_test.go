@@ -0,0 +1,133 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receiver the local stand-in of the webhooks, record the last request
+type receiver struct {
+	body   []byte
+	header http.Header
+	status int
+}
+
+func (r *receiver) start(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.body, r.header = body, req.Header
+		if r.status != 0 {
+			w.WriteHeader(r.status)
+		}
+	}))
+}
+
+var testEvent = Event{
+	Type:         "AutoUpdated",
+	Namespace:    "default",
+	Name:         "redis",
+	ChartName:    "redis",
+	ChartVersion: "1.2.0",
+	Timestamp:    time.Unix(0, 0).UTC(),
+}
+
+func Test_Slack(t *testing.T) {
+	r := &receiver{}
+	server := r.start(t)
+	defer server.Close()
+	n, err := New(ProviderSlack, Options{Address: server.URL, Channel: "releases"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = n.Post(context.Background(), testEvent, "redis updated"); err != nil {
+		t.Fatal(err)
+	}
+	var payload slackPayload
+	if err = json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Channel != "releases" || payload.Username != defaultSlackUsername ||
+		len(payload.Attachments) != 1 || payload.Attachments[0].Text != "redis updated" {
+		t.Errorf("unexpected slack payload %s", string(r.body))
+	}
+}
+
+func Test_MSTeams(t *testing.T) {
+	r := &receiver{}
+	server := r.start(t)
+	defer server.Close()
+	n, err := New(ProviderMSTeams, Options{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = n.Post(context.Background(), testEvent, "redis updated"); err != nil {
+		t.Fatal(err)
+	}
+	var payload msTeamsPayload
+	if err = json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != "MessageCard" || len(payload.Sections) != 1 || payload.Sections[0].ActivityTitle != "redis updated" {
+		t.Errorf("unexpected teams payload %s", string(r.body))
+	}
+}
+
+func Test_GenericSignature(t *testing.T) {
+	r := &receiver{}
+	server := r.start(t)
+	defer server.Close()
+	key := []byte("secret")
+	n, err := New(ProviderGeneric, Options{Address: server.URL, HMACKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = n.Post(context.Background(), testEvent, "redis updated"); err != nil {
+		t.Fatal(err)
+	}
+	if r.header.Get(SignatureHeader) != "sha256="+Sign(key, r.body) {
+		t.Errorf("signature %s not match the body", r.header.Get(SignatureHeader))
+	}
+	var payload genericPayload
+	if err = json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != testEvent.Type || payload.Text != "redis updated" {
+		t.Errorf("unexpected generic payload %s", string(r.body))
+	}
+}
+
+func Test_PostError(t *testing.T) {
+	r := &receiver{status: http.StatusBadRequest}
+	server := r.start(t)
+	defer server.Close()
+	n, err := New(ProviderGeneric, Options{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Post(context.Background(), testEvent, "redis updated")
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expect the status error, got %v", err)
+	}
+}
+
+func Test_RateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(time.Minute)
+	limiter.now = func() time.Time { return now }
+	if !limiter.Allow("a") || limiter.Allow("a") || !limiter.Allow("b") {
+		t.Error("expect the same key limited in the interval")
+	}
+	now = now.Add(time.Minute)
+	if !limiter.Allow("a") {
+		t.Error("expect the key allowed after the interval")
+	}
+}
@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter drop the same message in the interval, e.g. a failed upgrade retried every few seconds
+type RateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	seen     map[string]time.Time
+	now      func() time.Time
+}
+
+// NewRateLimiter create the rate limiter, every message is allowed if the interval is not positive
+func NewRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{interval: interval, seen: map[string]time.Time{}, now: time.Now}
+}
+
+// Allow check the message of the key can be posted, the key is recorded if allowed
+func (r *RateLimiter) Allow(key string) bool {
+	if r.interval <= 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for item, last := range r.seen {
+		if now.Sub(last) >= r.interval {
+			delete(r.seen, item)
+		}
+	}
+	if _, ok := r.seen[key]; ok {
+		return false
+	}
+	r.seen[key] = now
+	return true
+}
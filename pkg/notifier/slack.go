@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"context"
+)
+
+const defaultSlackUsername = "helmops"
+
+// slack post the message to a slack incoming webhook
+type slack struct {
+	opts Options
+}
+
+type slackPayload struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color,omitempty"`
+	Text   string       `json:"text,omitempty"`
+	Fields []slackField `json:"fields,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *slack) Post(ctx context.Context, event Event, message string) error {
+	username := s.opts.Username
+	if username == "" {
+		username = defaultSlackUsername
+	}
+	payload := slackPayload{
+		Channel:  s.opts.Channel,
+		Username: username,
+		Attachments: []slackAttachment{{
+			Color: eventColor(event),
+			Text:  message,
+			Fields: []slackField{
+				{Title: "Chart", Value: event.ChartName, Short: true},
+				{Title: "Version", Value: event.ChartVersion, Short: true},
+			},
+		}},
+	}
+	return postJSON(ctx, s.opts.Client, s.opts.Address, payload, nil)
+}
+
+// eventColor the failed events are red, the others are green
+func eventColor(event Event) string {
+	if event.Type == "Failed" {
+		return "#d50000"
+	}
+	return "#2eb886"
+}